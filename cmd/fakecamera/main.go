@@ -0,0 +1,56 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"ip-camera-viewer/internal/infrastructure/fakecamera"
+)
+
+func main() {
+	opts := fakecamera.Options{
+		Streams: []fakecamera.Stream{
+			{Path: "/main", Width: 640, Height: 480, FPS: 15},
+			{Path: "/sub", Width: 320, Height: 240, FPS: 15},
+		},
+	}
+
+	authName := flag.String("auth", "digest", "авторизация: none, basic или digest")
+	flag.StringVar(&opts.Address, "addr", "127.0.0.1:8554", "адрес RTSP сервера")
+	flag.StringVar(&opts.Login, "login", "admin", "логин")
+	flag.StringVar(&opts.Password, "password", "admin", "пароль")
+	flag.DurationVar(&opts.Latency, "latency", 0, "задержка ответов на запросы RTSP")
+	flag.Float64Var(&opts.PacketLoss, "loss", 0, "доля потерянных RTP пакетов, от 0 до 1")
+	flag.DurationVar(&opts.DisconnectAfter, "disconnect", 0, "разрывать сессию через заданное время после PLAY")
+	flag.Parse()
+
+	switch *authName {
+	case "none":
+		opts.Auth = fakecamera.AuthNone
+	case "basic":
+		opts.Auth = fakecamera.AuthBasic
+	case "digest":
+		opts.Auth = fakecamera.AuthDigest
+	default:
+		log.Fatalf("неизвестный способ авторизации: %s", *authName)
+	}
+
+	camera, err := fakecamera.Start(opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer camera.Close()
+
+	fmt.Println("Камера запущена:")
+	for _, stream := range opts.Streams {
+		fmt.Println("  " + camera.URL(stream.Path))
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	<-signals
+}
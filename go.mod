@@ -6,6 +6,7 @@ require (
 	fyne.io/fyne/v2 v2.7.0
 	github.com/bluenviron/gortsplib/v5 v5.1.1
 	github.com/bluenviron/mediacommon/v2 v2.5.1
+	github.com/gen2brain/malgo v0.11.24
 	github.com/pion/rtp v1.8.23
 )
 
@@ -13,11 +13,26 @@ type ConfigurationService struct {
 }
 
 type SavedConfig struct {
-	IP       string `json:"ip"`
-	Port     int    `json:"port"`
-	Login    string `json:"login"`
-	RTSPURI1 string `json:"rtsp_uri_1"`
-	RTSPURI2 string `json:"rtsp_uri_2"`
+	IP          string `json:"ip"`
+	Port        int    `json:"port"`
+	Login       string `json:"login"`
+	Password    string `json:"password,omitempty"`
+	RTSPURI1    string `json:"rtsp_uri_1"`
+	RTSPURI2    string `json:"rtsp_uri_2"`
+	Audio       bool   `json:"audio"`
+	Backchannel bool   `json:"backchannel"`
+
+	CAFile          string `json:"ca_file,omitempty"`
+	CertFingerprint string `json:"cert_fingerprint,omitempty"`
+
+	RememberPassword bool `json:"remember_password,omitempty"`
+
+	Motion    *model.MotionConfig    `json:"motion,omitempty"`
+	Recording *model.RecordingConfig `json:"recording,omitempty"`
+	Health    *model.HealthConfig    `json:"health,omitempty"`
+	Watchdog  *model.WatchdogConfig  `json:"watchdog,omitempty"`
+
+	Variables map[string]string `json:"variables,omitempty"`
 }
 
 func NewConfigurationService(logger *LoggerService) *ConfigurationService {
@@ -25,18 +40,36 @@ func NewConfigurationService(logger *LoggerService) *ConfigurationService {
 	configPath := filepath.Join(currentDir, "config.json")
 
 	return &ConfigurationService{
-		logger:     logger,
+		logger:     logger.Component("config"),
 		configPath: configPath,
 	}
 }
 
 func (cs *ConfigurationService) SaveConfig(config *model.ConnectionConfig) error {
 	saved := &SavedConfig{
-		IP:       config.IP,
-		Port:     config.Port,
-		Login:    config.Login,
-		RTSPURI1: config.RTSPURI1,
-		RTSPURI2: config.RTSPURI2,
+		IP:          config.IP,
+		Port:        config.Port,
+		Login:       config.Login,
+		RTSPURI1:    config.RTSPURI1,
+		RTSPURI2:    config.RTSPURI2,
+		Audio:       config.Audio,
+		Backchannel: config.Backchannel,
+
+		CAFile:          config.CAFile,
+		CertFingerprint: config.CertFingerprint,
+
+		RememberPassword: config.RememberPassword,
+
+		Motion:    &config.Motion,
+		Recording: &config.Recording,
+		Health:    config.Health,
+		Watchdog:  config.Watchdog,
+
+		Variables: config.Variables,
+	}
+
+	if config.RememberPassword {
+		saved.Password = config.Password
 	}
 
 	dir := filepath.Dir(cs.configPath)
@@ -4,6 +4,10 @@ import (
 	"context"
 	"ip-camera-viewer/internal/domain"
 	"ip-camera-viewer/internal/domain/model"
+	"net"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type ConnectionService struct {
@@ -17,7 +21,7 @@ func NewConnectionService(logger *LoggerService, streamManager *StreamManager) *
 	return &ConnectionService{
 		validationService: NewValidationService(),
 		streamManager:     streamManager,
-		logger:            logger,
+		logger:            logger.Component("connection"),
 		templateResolver:  domain.NewTemplateResolver(),
 	}
 }
@@ -26,14 +30,20 @@ func (cs *ConnectionService) ValidateConfig(config *model.ConnectionConfig) (*mo
 	return cs.validationService.ValidateAndResolve(config)
 }
 
+func (cs *ConnectionService) ResolveHost(ctx context.Context, host string) <-chan *model.ValidationResult {
+	return cs.validationService.ResolveHostAsync(ctx, host)
+}
+
 func (cs *ConnectionService) Connect(ctx context.Context, config *model.ConnectionConfig) (
 	highFrames <-chan *model.FrameData,
 	lowFrames <-chan *model.FrameData,
 	err error,
 ) {
+	cs.logger.RegisterCredentials(config.Login, config.Password)
+
 	resolved, result := cs.validationService.ValidateAndResolve(config)
 	if !result.Valid {
-		cs.logger.Error("Ошибка валидации конфигурации", nil)
+		cs.logger.Error("Ошибка валидации конфигурации", nil, "errors", len(result.Errors))
 		return nil, nil, &model.AppError{
 			Type:        model.ErrorTypeValidation,
 			Message:     "Валидация не пройдена",
@@ -41,20 +51,41 @@ func (cs *ConnectionService) Connect(ctx context.Context, config *model.Connecti
 		}
 	}
 
-	cs.logger.Info("Начало подключения к камере %s:%d", config.IP, config.Port)
+	cs.logger.Info("Начало подключения к камере", "host", net.JoinHostPort(strings.Trim(config.IP, "[]"), strconv.Itoa(config.Port)))
 
 	highConfig := model.NewStreamConfig("High", resolved.URI1, config.Login, config.Password)
 	lowConfig := model.NewStreamConfig("Low", resolved.URI2, config.Login, config.Password)
+	highConfig.Audio = config.Audio
+	lowConfig.Audio = config.Audio
+	highConfig.Backchannel = config.Backchannel
+	motionConfig := config.Motion
+	lowConfig.Motion = &motionConfig
+	recordingConfig := config.Recording
+	highConfig.Recording = &recordingConfig
+	for _, streamConfig := range []*model.StreamConfig{highConfig, lowConfig} {
+		healthConfig := model.DefaultHealthConfig()
+		if config.Health != nil {
+			healthConfig = *config.Health
+		}
+		streamConfig.Health = &healthConfig
+		watchdogConfig := model.DefaultWatchdogConfig()
+		if config.Watchdog != nil {
+			watchdogConfig = *config.Watchdog
+		}
+		streamConfig.Watchdog = &watchdogConfig
+		streamConfig.CAFile = config.CAFile
+		streamConfig.CertFingerprint = config.CertFingerprint
+	}
 
 	highChan, err := cs.streamManager.StartStream(ctx, highConfig)
 	if err != nil {
-		cs.logger.Error("Ошибка запуска High потока", err)
+		cs.logger.Error("Ошибка запуска потока", err, "stream", "High")
 		return nil, nil, err
 	}
 
 	lowChan, err := cs.streamManager.StartStream(ctx, lowConfig)
 	if err != nil {
-		cs.logger.Error("Ошибка запуска Low потока", err)
+		cs.logger.Error("Ошибка запуска потока", err, "stream", "Low")
 		cs.streamManager.StopStream("High")
 		return nil, nil, err
 	}
@@ -69,6 +100,89 @@ func (cs *ConnectionService) Disconnect() {
 	cs.streamManager.StopAllStreams()
 }
 
+func (cs *ConnectionService) SetPreviewSize(streamName string, width, height int) {
+	cs.streamManager.SetOutputSize(streamName, width, height)
+}
+
+func (cs *ConnectionService) SetMotionConfig(streamName string, config model.MotionConfig) {
+	cs.streamManager.SetMotionConfig(streamName, config)
+}
+
+// TriggerRecording сохраняет клип потока по внешнему событию: кнопке в
+// интерфейсе или вызову API. Пустое имя — все потоки с буфером записи.
+func (cs *ConnectionService) TriggerRecording(streamName, reason string) error {
+	return cs.streamManager.TriggerRecording(streamName, reason)
+}
+
+func (cs *ConnectionService) SetHealthConfig(config model.HealthConfig) {
+	cs.streamManager.SetHealthConfig(config)
+}
+
+func (cs *ConnectionService) SetRecordOnMotion(enabled bool) {
+	cs.streamManager.SetRecordOnMotion(enabled)
+}
+
+func (cs *ConnectionService) SetMuted(streamName string, muted bool) {
+	cs.streamManager.SetMuted(streamName, muted)
+}
+
+func (cs *ConnectionService) StartTalk(ctx context.Context, streamName string) error {
+	err := cs.streamManager.StartTalk(ctx, streamName)
+	if err != nil {
+		cs.logger.Error("Ошибка запуска передачи звука", err, "stream", streamName)
+		return err
+	}
+
+	cs.logger.Info("Передача звука", "stream", streamName)
+	return nil
+}
+
+func (cs *ConnectionService) StopTalk(streamName string) {
+	cs.streamManager.StopTalk(streamName)
+}
+
+// SeekPlayback запрашивает у NVR запись потока начиная с момента start.
+func (cs *ConnectionService) SeekPlayback(streamName string, start time.Time) error {
+	if err := cs.streamManager.SeekPlayback(streamName, start); err != nil {
+		cs.logger.Error("Ошибка перехода по архиву", err, "stream", streamName)
+		return err
+	}
+
+	cs.logger.Info("Переход по архиву", "stream", streamName, "position", start.Format(time.RFC3339))
+	return nil
+}
+
+func (cs *ConnectionService) PausePlayback(streamName string) error {
+	return cs.streamManager.PausePlayback(streamName)
+}
+
+func (cs *ConnectionService) ResumePlayback(streamName string) error {
+	return cs.streamManager.ResumePlayback(streamName)
+}
+
+func (cs *ConnectionService) SetPlaybackScale(streamName string, scale float64) error {
+	return cs.streamManager.SetPlaybackScale(streamName, scale)
+}
+
+func (cs *ConnectionService) GoLive(streamName string) error {
+	if err := cs.streamManager.GoLive(streamName); err != nil {
+		cs.logger.Error("Ошибка возврата в эфир", err, "stream", streamName)
+		return err
+	}
+
+	cs.logger.Info("Возврат в эфир", "stream", streamName)
+	return nil
+}
+
+// StreamInfo возвращает статистику потока и положение воспроизведения архива.
+func (cs *ConnectionService) StreamInfo(streamName string) (*model.StreamInfo, bool) {
+	return cs.streamManager.StreamInfo(streamName)
+}
+
+func (cs *ConnectionService) GetAudioLevelChannel() <-chan *model.AudioLevel {
+	return cs.streamManager.GetAudioLevelChannel()
+}
+
 func (cs *ConnectionService) GetStatusChannel() <-chan *model.StreamStatusUpdate {
 	return cs.streamManager.GetStatusChannel()
 }
@@ -0,0 +1,318 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ip-camera-viewer/internal/domain/model"
+	"ip-camera-viewer/internal/infrastructure/audio"
+	"ip-camera-viewer/internal/infrastructure/fakecamera"
+)
+
+const (
+	cameraPassword = "p@ssword"
+	frameTimeout   = 10 * time.Second
+)
+
+func startFakeCamera(t *testing.T, opts fakecamera.Options) *fakecamera.Camera {
+	t.Helper()
+
+	if opts.Streams == nil {
+		opts.Streams = []fakecamera.Stream{
+			{Path: "/main", Width: 320, Height: 240, FPS: 10},
+			{Path: "/sub", Width: 160, Height: 112, FPS: 10},
+		}
+	}
+	if opts.Auth == fakecamera.AuthNone {
+		opts.Auth = fakecamera.AuthDigest
+	}
+	opts.Login = testLogin
+	opts.Password = cameraPassword
+
+	camera, err := fakecamera.Start(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(camera.Close)
+	return camera
+}
+
+func newTestConnection(t *testing.T) *ConnectionService {
+	t.Helper()
+
+	logger := NewLoggerService()
+	cs := NewConnectionService(logger, NewStreamManager(logger))
+	t.Cleanup(cs.Disconnect)
+	return cs
+}
+
+// cameraConfig собирает конфигурацию так же, как форма подключения:
+// учётные данные и адрес подставляются через плейсхолдеры. Сторож
+// остаётся по умолчанию; тесты, которым нужен другой, задают его сами.
+func cameraConfig(t *testing.T, camera *fakecamera.Camera, path1, path2 string) *model.ConnectionConfig {
+	return &model.ConnectionConfig{
+		IP:        camera.Host(),
+		Port:      camera.Port(),
+		Login:     testLogin,
+		Password:  cameraPassword,
+		RTSPURI1:  "rtsp://{login}:{password}@{ip}:{port}" + path1,
+		RTSPURI2:  "rtsp://{login}:{password}@{ip}:{port}" + path2,
+		Recording: model.RecordingConfig{Dir: t.TempDir(), PostEvent: 1},
+	}
+}
+
+func connect(t *testing.T, cs *ConnectionService, config *model.ConnectionConfig) (high, low <-chan *model.FrameData) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	high, low, err := cs.Connect(ctx, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return high, low
+}
+
+func waitFrame(t *testing.T, name string, frames <-chan *model.FrameData) *model.FrameData {
+	t.Helper()
+
+	select {
+	case frame, ok := <-frames:
+		if !ok {
+			t.Fatalf("поток %s закрылся без кадров", name)
+		}
+		return frame
+	case <-time.After(frameTimeout):
+		t.Fatalf("поток %s не прислал кадр за %s", name, frameTimeout)
+		return nil
+	}
+}
+
+// waitStatus пропускает обновления других потоков и событий, пока поток
+// name не перейдёт в status.
+func waitStatus(t *testing.T, cs *ConnectionService, name string, status model.StreamStatus) *model.StreamStatusUpdate {
+	t.Helper()
+
+	timeout := time.After(frameTimeout)
+	for {
+		select {
+		case update := <-cs.GetStatusChannel():
+			if update.StreamName == name && update.Status == status {
+				return update
+			}
+		case <-timeout:
+			t.Fatalf("поток %s не перешёл в состояние %s", name, status)
+			return nil
+		}
+	}
+}
+
+// drain освобождает кадры, чтобы буферы пула не кончились, пока тест ждёт
+// смены состояния.
+func drain(frames <-chan *model.FrameData) {
+	go func() {
+		for frame := range frames {
+			frame.Release()
+		}
+	}()
+}
+
+func TestConnectReceivesFramesOnBothStreams(t *testing.T) {
+	camera := startFakeCamera(t, fakecamera.Options{})
+	cs := newTestConnection(t)
+
+	high, low := connect(t, cs, cameraConfig(t, camera, "/main", "/sub"))
+
+	for name, frames := range map[string]<-chan *model.FrameData{"High": high, "Low": low} {
+		frame := waitFrame(t, name, frames)
+		if frame.Image.Bounds().Empty() {
+			t.Fatalf("поток %s прислал пустой кадр", name)
+		}
+		frame.Release()
+	}
+}
+
+func TestConnectWithBasicAuth(t *testing.T) {
+	camera := startFakeCamera(t, fakecamera.Options{Auth: fakecamera.AuthBasic})
+	cs := newTestConnection(t)
+
+	high, _ := connect(t, cs, cameraConfig(t, camera, "/main", "/sub"))
+	waitFrame(t, "High", high).Release()
+}
+
+func TestConnectWrongPasswordReportsError(t *testing.T) {
+	camera := startFakeCamera(t, fakecamera.Options{})
+	cs := newTestConnection(t)
+
+	config := cameraConfig(t, camera, "/main", "/sub")
+	config.Password = "wrong"
+	connect(t, cs, config)
+
+	update := waitStatus(t, cs, "High", model.StatusError)
+	if update.Error == nil || !strings.Contains(update.Error.Error(), "401") {
+		t.Fatalf("ожидалась ошибка 401, получено %v", update.Error)
+	}
+}
+
+func TestConnectRejectsIdenticalStreams(t *testing.T) {
+	camera := startFakeCamera(t, fakecamera.Options{})
+	cs := newTestConnection(t)
+
+	_, _, err := cs.Connect(context.Background(), cameraConfig(t, camera, "/main", "//main/./"))
+
+	var appErr *model.AppError
+	if !errors.As(err, &appErr) || appErr.Type != model.ErrorTypeValidation {
+		t.Fatalf("ожидалась ошибка валидации, получено %v", err)
+	}
+}
+
+func TestConnectAcceptsAliasOfSameStream(t *testing.T) {
+	// разные URL одного потока не отличить по строке — подключение идёт
+	camera := startFakeCamera(t, fakecamera.Options{
+		Streams: []fakecamera.Stream{
+			{Path: "/main", Width: 160, Height: 112, FPS: 10, Aliases: []string{"/Streaming/Channels/101"}},
+		},
+	})
+	cs := newTestConnection(t)
+
+	high, low := connect(t, cs, cameraConfig(t, camera, "/main", "/Streaming/Channels/101"))
+	waitFrame(t, "High", high).Release()
+	waitFrame(t, "Low", low).Release()
+}
+
+func TestConnectToleratesLatencyAndPacketLoss(t *testing.T) {
+	camera := startFakeCamera(t, fakecamera.Options{
+		Streams: []fakecamera.Stream{
+			{Path: "/main", Width: 160, Height: 112, FPS: 25},
+			{Path: "/sub", Width: 64, Height: 48, FPS: 25},
+		},
+		Latency:    300 * time.Millisecond,
+		PacketLoss: 0.02,
+	})
+	cs := newTestConnection(t)
+
+	high, low := connect(t, cs, cameraConfig(t, camera, "/main", "/sub"))
+	waitFrame(t, "High", high).Release()
+	waitFrame(t, "Low", low).Release()
+}
+
+func TestConnectReconnectsAfterCameraDisconnects(t *testing.T) {
+	camera := startFakeCamera(t, fakecamera.Options{DisconnectAfter: time.Second})
+	cs := newTestConnection(t)
+
+	high, low := connect(t, cs, cameraConfig(t, camera, "/main", "/sub"))
+	waitStatus(t, cs, "High", model.StatusPlaying)
+	drain(high)
+	drain(low)
+
+	waitStatus(t, cs, "High", model.StatusReconnecting)
+	waitStatus(t, cs, "High", model.StatusPlaying)
+}
+
+func TestConnectWatchdogWaitsForSlowHandshake(t *testing.T) {
+	// DESCRIBE, SETUP и PLAY вместе дольше таймаута сторожа, но отсчёт
+	// идёт с ответа на PLAY, поэтому поток не переподключается
+	camera := startFakeCamera(t, fakecamera.Options{Latency: 1200 * time.Millisecond})
+	cs := newTestConnection(t)
+
+	config := cameraConfig(t, camera, "/main", "/sub")
+	config.Watchdog = &model.WatchdogConfig{Timeout: 1, Reconnect: true}
+	high, low := connect(t, cs, config)
+	waitFrame(t, "High", high).Release()
+	waitFrame(t, "Low", low).Release()
+
+	for {
+		select {
+		case update := <-cs.GetStatusChannel():
+			if update.Status == model.StatusReconnecting {
+				t.Fatalf("поток %s переподключался во время рукопожатия", update.StreamName)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func TestConnectReconnectsAfterTCPDisconnect(t *testing.T) {
+	// у камеры нет UDP портов, поэтому сессия идёт по TCP; таймаут сторожа
+	// выключен, и переподключает только ошибка оборванного соединения
+	camera := startFakeCamera(t, fakecamera.Options{DisconnectAfter: time.Second})
+	cs := newTestConnection(t)
+
+	config := cameraConfig(t, camera, "/main", "/sub")
+	config.Watchdog = &model.WatchdogConfig{Reconnect: true}
+	high, low := connect(t, cs, config)
+	waitStatus(t, cs, "High", model.StatusPlaying)
+	drain(high)
+	drain(low)
+
+	waitStatus(t, cs, "High", model.StatusReconnecting)
+	waitStatus(t, cs, "High", model.StatusPlaying)
+}
+
+func TestTalkSendsWAVToCameraBackchannel(t *testing.T) {
+	camera := startFakeCamera(t, fakecamera.Options{
+		Streams: []fakecamera.Stream{
+			{Path: "/main", Width: 160, Height: 112, FPS: 10, Backchannel: true},
+			{Path: "/sub", Width: 64, Height: 48, FPS: 10},
+		},
+	})
+
+	// полсекунды тона в формате обратного канала: talkLoop не пересчитывает
+	// частоту, и камера должна принять ровно EncodeG711 от этих отсчётов
+	format := audio.Format{SampleRate: 8000, Channels: 1}
+	samples := make([]int16, format.SampleRate/2)
+	for i := range samples {
+		samples[i] = int16(12000 * math.Sin(2*math.Pi*float64(i)/20))
+	}
+	path := filepath.Join(t.TempDir(), "talk.wav")
+	sink, err := audio.NewWAVSink(path, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(samples); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	logger := NewLoggerService()
+	sm := NewStreamManager(logger)
+	sm.SetAudioSourceFactory(audio.WAVSourceFactory(path))
+	cs := NewConnectionService(logger, sm)
+	t.Cleanup(cs.Disconnect)
+
+	config := cameraConfig(t, camera, "/main", "/sub")
+	config.Backchannel = true
+	high, low := connect(t, cs, config)
+	waitFrame(t, "High", high).Release()
+	drain(high)
+	drain(low)
+
+	if err := cs.StartTalk(context.Background(), "High"); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := audio.EncodeG711(samples, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(frameTimeout)
+	for len(camera.Backchannel("/main")) < len(want) {
+		if time.Now().After(deadline) {
+			t.Fatalf("камера приняла %d байт G.711 из %d", len(camera.Backchannel("/main")), len(want))
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if got := camera.Backchannel("/main"); !bytes.Equal(got, want) {
+		t.Fatalf("камера приняла %d байт, отличающихся от переданного звука", len(got))
+	}
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"ip-camera-viewer/internal/domain"
+	"ip-camera-viewer/internal/infrastructure/pcap"
+	videodecoder "ip-camera-viewer/internal/infrastructure/video"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultCaptureDuration = 5 * time.Second
+
+type DiagnosticsService struct {
+	logger        *LoggerService
+	streamManager *StreamManager
+	configService *ConfigurationService
+}
+
+// DiagnosticsInput — то, что известно только интерфейсу: последние
+// показанные кадры потоков.
+type DiagnosticsInput struct {
+	Snapshots       map[string]image.Image
+	CaptureDuration time.Duration
+}
+
+func NewDiagnosticsService(logger *LoggerService, streamManager *StreamManager, configService *ConfigurationService) *DiagnosticsService {
+	return &DiagnosticsService{
+		logger:        logger.Component("diagnostics"),
+		streamManager: streamManager,
+		configService: configService,
+	}
+}
+
+// Export собирает zip архив для службы поддержки: журналы, конфигурацию без
+// пароля, SDP, статистику и снимок каждого потока, несколько секунд RTP в
+// формате pcap и сведения об окружении. Все тексты проходят маскирование.
+func (ds *DiagnosticsService) Export(ctx context.Context, path string, input DiagnosticsInput) error {
+	ds.logger.Info("Экспорт диагностики", "path", path)
+
+	if input.CaptureDuration <= 0 {
+		input.CaptureDuration = DefaultCaptureDuration
+	}
+
+	streams := ds.streamManager.StreamNames()
+	captures := ds.captureRTP(ctx, streams, input.CaptureDuration)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	archive := zip.NewWriter(file)
+
+	var errs []string
+	addErr := func(name string, err error) {
+		if err != nil {
+			errs = append(errs, name+": "+ds.logger.MaskSensitiveData(err.Error()))
+		}
+	}
+
+	addErr("environment.txt", writeZipFile(archive, "environment.txt", []byte(environmentInfo())))
+	addErr("config.json", ds.writeConfig(archive))
+	addErr("logs", ds.writeLogs(archive))
+
+	for _, name := range streams {
+		dir := "streams/" + name + "/"
+
+		if info, ok := ds.streamManager.StreamInfo(name); ok {
+			data, err := json.MarshalIndent(info, "", "  ")
+			if err == nil {
+				err = writeZipFile(archive, dir+"info.json", data)
+			}
+			addErr(dir+"info.json", err)
+		}
+
+		if sdp := ds.streamManager.StreamSDP(name); len(sdp) > 0 {
+			addErr(dir+"describe.sdp", writeZipFile(archive, dir+"describe.sdp", []byte(ds.logger.MaskSensitiveData(string(sdp)))))
+		}
+
+		if snapshot := input.Snapshots[name]; snapshot != nil {
+			var buf bytes.Buffer
+			err := png.Encode(&buf, snapshot)
+			if err == nil {
+				err = writeZipFile(archive, dir+"snapshot.png", buf.Bytes())
+			}
+			addErr(dir+"snapshot.png", err)
+		}
+
+		if capture := captures[name]; capture.err != nil {
+			addErr(dir+"rtp.pcap", capture.err)
+		} else {
+			addErr(dir+"rtp.pcap", writePcap(archive, dir+"rtp.pcap", capture.packets))
+		}
+	}
+
+	if len(errs) > 0 {
+		writeZipFile(archive, "errors.txt", []byte(strings.Join(errs, "\n")+"\n"))
+	}
+
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	ds.logger.Info("Диагностика сохранена", "path", path, "streams", len(streams), "errors", len(errs))
+	return file.Close()
+}
+
+type rtpCaptureResult struct {
+	packets []pcap.Packet
+	err     error
+}
+
+// captureRTP записывает RTP всех потоков одновременно.
+func (ds *DiagnosticsService) captureRTP(ctx context.Context, streams []string, duration time.Duration) map[string]rtpCaptureResult {
+	results := make(map[string]rtpCaptureResult, len(streams))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, name := range streams {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			packets, err := ds.streamManager.CaptureRTP(ctx, name, duration)
+
+			mu.Lock()
+			results[name] = rtpCaptureResult{packets: packets, err: err}
+			mu.Unlock()
+		}()
+	}
+
+	wg.Wait()
+	return results
+}
+
+func (ds *DiagnosticsService) writeConfig(archive *zip.Writer) error {
+	saved, err := ds.configService.LoadConfig()
+	if err != nil || saved == nil {
+		return err
+	}
+
+	saved.Password = ""
+	resolver := domain.NewTemplateResolver()
+	saved.RTSPURI1 = resolver.MaskPassword(saved.RTSPURI1)
+	saved.RTSPURI2 = resolver.MaskPassword(saved.RTSPURI2)
+
+	data, err := json.MarshalIndent(saved, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeZipFile(archive, "config.json", []byte(ds.logger.MaskSensitiveData(string(data))))
+}
+
+// writeLogs копирует файлы журнала. Они уже замаскированы при записи,
+// но маскирование повторяется на случай файлов старых версий и секретов,
+// зарегистрированных позже. Сжатые архивы ротации распаковываются, иначе
+// маскирование их не коснётся.
+func (ds *DiagnosticsService) writeLogs(archive *zip.Writer) error {
+	for _, path := range ds.logger.LogFiles() {
+		data, err := readLogFile(path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return err
+		}
+
+		name := strings.TrimSuffix(filepath.Base(path), ".gz")
+		if err := writeZipFile(archive, "logs/"+name, []byte(ds.logger.MaskSensitiveData(string(data)))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readLogFile читает файл журнала, распаковывая архивы .gz.
+func readLogFile(path string) ([]byte, error) {
+	if !strings.HasSuffix(path, ".gz") {
+		return os.ReadFile(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	defer gz.Close()
+
+	return io.ReadAll(gz)
+}
+
+func writeZipFile(archive *zip.Writer, name string, data []byte) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(data)
+	return err
+}
+
+func writePcap(archive *zip.Writer, name string, packets []pcap.Packet) error {
+	w, err := archive.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	pw, err := pcap.NewWriter(w)
+	if err != nil {
+		return err
+	}
+
+	for _, pkt := range packets {
+		if err := pw.WritePacket(pkt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func environmentInfo() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "time: %s\n", time.Now().Format(time.RFC3339))
+	fmt.Fprintf(&b, "os: %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	fmt.Fprintf(&b, "cpus: %d\n", runtime.NumCPU())
+	fmt.Fprintf(&b, "go: %s\n", runtime.Version())
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		fmt.Fprintf(&b, "module: %s %s\n", build.Main.Path, build.Main.Version)
+		for _, setting := range build.Settings {
+			if strings.HasPrefix(setting.Key, "vcs.") {
+				fmt.Fprintf(&b, "%s: %s\n", setting.Key, setting.Value)
+			}
+		}
+	}
+
+	versions := videodecoder.LibraryVersions()
+	names := make([]string, 0, len(versions))
+	for name := range versions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(&b, "%s: %s\n", name, versions[name])
+	}
+
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiagnosticsMasksCompressedLogBackups(t *testing.T) {
+	stdout := captureStdout(t)
+	dir := t.TempDir()
+
+	// копия прежней версии, записанная до регистрации пароля
+	backup := filepath.Join(dir, "ip-camera-viewer-20250101T000000.000.log.gz")
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte("Подключение rtsp://" + testLogin + ":" + testSecret + "@192.168.1.10/stream1\n"))
+	gz.Close()
+	if err := os.WriteFile(backup, compressed.Bytes(), 0600); err != nil {
+		stdout()
+		t.Fatal(err)
+	}
+
+	logger, err := NewFileLoggerService(LogOptions{Dir: dir, Format: LogFormatText, Level: slog.LevelDebug})
+	if err != nil {
+		stdout()
+		t.Fatal(err)
+	}
+	logger.RegisterCredentials(testLogin, testSecret)
+	stdout()
+	defer logger.Close()
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	ds := &DiagnosticsService{logger: logger}
+	if err := ds.writeLogs(archive); err != nil {
+		t.Fatal(err)
+	}
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := false
+	for _, file := range reader.File {
+		if strings.HasSuffix(file.Name, ".gz") {
+			t.Errorf("архив ротации не распакован: %s", file.Name)
+		}
+
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if file.Name == "logs/ip-camera-viewer-20250101T000000.000.log" {
+			found = true
+			if !strings.Contains(string(data), "***") {
+				t.Errorf("копия не замаскирована: %s", data)
+			}
+		}
+		for _, form := range secretForms() {
+			if strings.Contains(string(data), form) {
+				t.Errorf("%s содержит секрет %q:\n%s", file.Name, form, data)
+			}
+		}
+	}
+	if !found {
+		t.Error("распакованная копия журнала не попала в архив")
+	}
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"ip-camera-viewer/internal/domain/model"
+	"log/slog"
+	"sync"
+)
+
+// LogSink получает каждую запись журнала после маскирования. Вызывается из
+// горутины, которая пишет в журнал.
+type LogSink func(*model.LogEntry)
+
+type sinkHub struct {
+	mu    sync.RWMutex
+	sinks []LogSink
+}
+
+func (h *sinkHub) add(sink LogSink) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.sinks = append(h.sinks, sink)
+}
+
+func (h *sinkHub) empty() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.sinks) == 0
+}
+
+func (h *sinkHub) publish(entry *model.LogEntry) {
+	h.mu.RLock()
+	sinks := h.sinks
+	h.mu.RUnlock()
+
+	for _, sink := range sinks {
+		sink(entry)
+	}
+}
+
+// sinkHandler превращает записи slog в model.LogEntry для подписчиков.
+type sinkHandler struct {
+	hub       *sinkHub
+	attrs     []model.LogAttr
+	component string
+	group     string
+}
+
+func (h *sinkHandler) Enabled(context.Context, slog.Level) bool {
+	return !h.hub.empty()
+}
+
+func (h *sinkHandler) Handle(_ context.Context, r slog.Record) error {
+	entry := &model.LogEntry{
+		Time:      r.Time,
+		Level:     r.Level,
+		Component: h.component,
+		Message:   r.Message,
+		Attrs:     append([]model.LogAttr(nil), h.attrs...),
+	}
+
+	r.Attrs(func(attr slog.Attr) bool {
+		entry.Attrs = appendLogAttr(entry.Attrs, h.group, attr)
+		return true
+	})
+
+	h.hub.publish(entry)
+	return nil
+}
+
+func (h *sinkHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append([]model.LogAttr(nil), h.attrs...)
+
+	for _, attr := range attrs {
+		if attr.Key == "component" && h.group == "" {
+			clone.component = attr.Value.String()
+			continue
+		}
+		clone.attrs = appendLogAttr(clone.attrs, h.group, attr)
+	}
+
+	return &clone
+}
+
+func (h *sinkHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.group = joinLogKey(h.group, name)
+	return &clone
+}
+
+func appendLogAttr(attrs []model.LogAttr, group string, attr slog.Attr) []model.LogAttr {
+	value := attr.Value.Resolve()
+	if value.Kind() == slog.KindGroup {
+		for _, member := range value.Group() {
+			attrs = appendLogAttr(attrs, joinLogKey(group, attr.Key), member)
+		}
+		return attrs
+	}
+
+	return append(attrs, model.LogAttr{Key: joinLogKey(group, attr.Key), Value: value.String()})
+}
+
+func joinLogKey(group, key string) string {
+	if group == "" {
+		return key
+	}
+	if key == "" {
+		return group
+	}
+	return group + "." + key
+}
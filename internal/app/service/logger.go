@@ -1,66 +1,526 @@
 package service
 
 import (
+	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
+	"ip-camera-viewer/internal/domain/model"
+	"ip-camera-viewer/internal/infrastructure/logfile"
 	"log/slog"
+	"net/url"
 	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
+type LogFormat string
+
+const (
+	LogFormatText LogFormat = "text"
+	LogFormatJSON LogFormat = "json"
+)
+
+const (
+	logFileName = "ip-camera-viewer.log"
+
+	// LogLevelEnv задаёт уровни журнала: "info" или "info,rtsp=debug,preset=warn".
+	LogLevelEnv  = "IPCAM_LOG_LEVEL"
+	LogFormatEnv = "IPCAM_LOG_FORMAT"
+)
+
+var (
+	rtspPasswordPattern = regexp.MustCompile(`(rtsps?://[^:/@\s]*:)([^@\s]+)(@)`)
+	passwordPattern     = regexp.MustCompile(`(?i)(password|pwd|pass)\s*[:=]\s*["']?([^"'\s,&]+)["']?`)
+	jsonPasswordPattern = regexp.MustCompile(`(?i)"(password|pwd|pass)"\s*:\s*"([^"]+)"`)
+)
+
+type LogOptions struct {
+	// Dir — каталог файлов журнала. Пустая строка — без записи в файл.
+	Dir    string
+	Format LogFormat
+
+	// Level — уровень по умолчанию, Levels — уровни отдельных компонентов.
+	Level  slog.Level
+	Levels map[string]slog.Level
+
+	MaxSize    int64
+	MaxAge     time.Duration
+	MaxBackups int
+	Compress   bool
+}
+
+// DefaultLogOptions пишет журнал в каталог состояния пользователя и читает
+// формат и уровни из переменных окружения.
+func DefaultLogOptions() LogOptions {
+	opts := LogOptions{
+		Format:     LogFormatText,
+		Level:      slog.LevelInfo,
+		MaxSize:    10 << 20,
+		MaxAge:     24 * time.Hour,
+		MaxBackups: 7,
+		Compress:   true,
+	}
+
+	if dir, err := logfile.UserStateDir(); err == nil {
+		opts.Dir = filepath.Join(dir, "ip-camera-viewer", "logs")
+	}
+
+	if LogFormat(os.Getenv(LogFormatEnv)) == LogFormatJSON {
+		opts.Format = LogFormatJSON
+	}
+
+	if spec := os.Getenv(LogLevelEnv); spec != "" {
+		if level, levels, err := ParseLogLevels(spec); err == nil {
+			opts.Level = level
+			opts.Levels = levels
+		}
+	}
+
+	return opts
+}
+
+// ParseLogLevels разбирает строку вида "info,rtsp=debug": элемент без имени
+// задаёт уровень по умолчанию.
+func ParseLogLevels(spec string) (slog.Level, map[string]slog.Level, error) {
+	def := slog.LevelInfo
+	levels := make(map[string]slog.Level)
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		component, value, found := strings.Cut(item, "=")
+		if !found {
+			component, value = "", item
+		}
+
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(strings.TrimSpace(value))); err != nil {
+			return def, nil, fmt.Errorf("некорректный уровень журнала %q: %w", item, err)
+		}
+
+		if component = strings.TrimSpace(component); component == "" {
+			def = level
+		} else {
+			levels[component] = level
+		}
+	}
+
+	return def, levels, nil
+}
+
 type LoggerService struct {
 	logger *slog.Logger
+	// root — журнал без атрибутов With, от него строятся компоненты.
+	root    *slog.Logger
+	levels  *logLevels
+	file    *logfile.RotatingWriter
+	sinks   *sinkHub
+	secrets *secretSet
 }
 
 func NewLoggerService() *LoggerService {
-	handler := slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
-		Level: slog.LevelInfo,
-	})
+	levels := newLogLevels(slog.LevelInfo, nil)
+	secrets := &secretSet{}
+	sinks := &sinkHub{}
+	handler := fanoutHandler{
+		slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		&sinkHandler{hub: sinks},
+	}
 
+	logger := slog.New(&maskingHandler{next: handler, levels: levels, secrets: secrets})
 	return &LoggerService{
-		logger: slog.New(handler),
+		logger:  logger,
+		root:    logger,
+		levels:  levels,
+		sinks:   sinks,
+		secrets: secrets,
 	}
 }
 
-func (ls *LoggerService) MaskSensitiveData(message string) string {
-	masked := message
+// NewFileLoggerService пишет журнал в stdout и в ротируемый файл opts.Dir.
+func NewFileLoggerService(opts LogOptions) (*LoggerService, error) {
+	levels := newLogLevels(opts.Level, opts.Levels)
+	secrets := &secretSet{}
+	handlerOpts := &slog.HandlerOptions{Level: slog.LevelDebug}
 
-	rtspPattern := regexp.MustCompile(`(rtsp://[^:]+:)([^@]+)(@)`)
-	masked = rtspPattern.ReplaceAllString(masked, "${1}***${3}")
+	sinks := &sinkHub{}
+	handlers := fanoutHandler{
+		slog.NewTextHandler(os.Stdout, handlerOpts),
+		&sinkHandler{hub: sinks},
+	}
 
-	passwordPattern := regexp.MustCompile(`(?i)(password|pwd|pass)\s*[:=]\s*["']?([^"'\s,&]+)["']?`)
-	masked = passwordPattern.ReplaceAllString(masked, "${1}=***")
+	var file *logfile.RotatingWriter
+	if opts.Dir != "" {
+		var err error
+		file, err = logfile.NewRotatingWriter(logfile.Options{
+			Path:       filepath.Join(opts.Dir, logFileName),
+			MaxSize:    opts.MaxSize,
+			MaxAge:     opts.MaxAge,
+			MaxBackups: opts.MaxBackups,
+			Compress:   opts.Compress,
+		})
+		if err != nil {
+			return nil, err
+		}
 
-	jsonPasswordPattern := regexp.MustCompile(`(?i)"(password|pwd|pass)"\s*:\s*"([^"]+)"`)
-	masked = jsonPasswordPattern.ReplaceAllString(masked, `"${1}":"***"`)
+		handlers = append(handlers, newFormatHandler(file, opts.Format, handlerOpts))
+	}
+
+	logger := slog.New(&maskingHandler{next: handlers, levels: levels, secrets: secrets})
+	return &LoggerService{
+		logger:  logger,
+		root:    logger,
+		levels:  levels,
+		file:    file,
+		sinks:   sinks,
+		secrets: secrets,
+	}, nil
+}
+
+func newFormatHandler(w io.Writer, format LogFormat, opts *slog.HandlerOptions) slog.Handler {
+	if format == LogFormatJSON {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// With возвращает журнал, добавляющий атрибуты ко всем записям.
+func (ls *LoggerService) With(args ...any) *LoggerService {
+	return &LoggerService{
+		logger:  ls.logger.With(args...),
+		root:    ls.root,
+		levels:  ls.levels,
+		file:    ls.file,
+		sinks:   ls.sinks,
+		secrets: ls.secrets,
+	}
+}
+
+// Component возвращает журнал компонента: его уровень настраивается
+// отдельно через SetLevel. Журнал строится от корневого, поэтому атрибуты
+// With и прежний компонент не наследуются и component в записи один.
+func (ls *LoggerService) Component(name string) *LoggerService {
+	return (&LoggerService{
+		logger:  ls.root,
+		root:    ls.root,
+		levels:  ls.levels,
+		file:    ls.file,
+		sinks:   ls.sinks,
+		secrets: ls.secrets,
+	}).With("component", name)
+}
+
+// SetLevel меняет уровень компонента во время работы. Пустое имя —
+// уровень по умолчанию.
+func (ls *LoggerService) SetLevel(component string, level slog.Level) {
+	ls.levels.set(component, level)
+}
+
+func (ls *LoggerService) Level(component string) slog.Level {
+	return ls.levels.level(component)
+}
+
+// AddSink подписывает sink на все записи журнала, включая записи
+// компонентов, созданных через With и Component.
+func (ls *LoggerService) AddSink(sink LogSink) {
+	ls.sinks.add(sink)
+}
+
+// CaptureStandardLog направляет вывод пакета log (например, log.Printf в
+// rtsp.Client) в этот журнал с компонентом "stdlog".
+func (ls *LoggerService) CaptureStandardLog() {
+	slog.SetDefault(ls.Component("stdlog").logger)
+}
+
+// Slog возвращает *slog.Logger с тем же маскированием и уровнями — для
+// пакетов, которые не зависят от сервисов (rtsp.Client).
+func (ls *LoggerService) Slog() *slog.Logger {
+	return ls.logger
+}
+
+// RegisterSecret добавляет строку, которая заменяется на *** во всех записях
+// журнала, в том числе в URL-кодированном виде.
+func (ls *LoggerService) RegisterSecret(secret string) {
+	ls.secrets.add(secret)
+}
+
+// RegisterCredentials регистрирует пароль и заголовок Basic-авторизации,
+// построенный из пары логин/пароль.
+func (ls *LoggerService) RegisterCredentials(login, password string) {
+	if password == "" {
+		return
+	}
+	ls.secrets.add(password)
+	ls.secrets.add(base64.StdEncoding.EncodeToString([]byte(login + ":" + password)))
+}
+
+// LogFiles возвращает текущий файл журнала и его ротированные копии.
+func (ls *LoggerService) LogFiles() []string {
+	if ls.file == nil {
+		return nil
+	}
+	return ls.file.Files()
+}
+
+func (ls *LoggerService) Close() error {
+	if ls.file == nil {
+		return nil
+	}
+	return ls.file.Close()
+}
+
+func (ls *LoggerService) MaskSensitiveData(message string) string {
+	return maskSensitiveData(ls.secrets.redact(message))
+}
 
+func maskSensitiveData(message string) string {
+	masked := rtspPasswordPattern.ReplaceAllString(message, "${1}***${3}")
+	masked = passwordPattern.ReplaceAllString(masked, "${1}=***")
+	masked = jsonPasswordPattern.ReplaceAllString(masked, `"${1}":"***"`)
 	return masked
 }
 
 func (ls *LoggerService) Info(message string, args ...any) {
-	masked := ls.MaskSensitiveData(fmt.Sprintf(message, args...))
-	ls.logger.Info(masked)
+	ls.logger.Info(message, args...)
 }
 
 func (ls *LoggerService) Error(message string, err error, args ...any) {
-	masked := ls.MaskSensitiveData(fmt.Sprintf(message, args...))
 	if err != nil {
-		maskedErr := ls.MaskSensitiveData(err.Error())
-		ls.logger.Error(masked, "error", maskedErr)
-	} else {
-		ls.logger.Error(masked)
+		args = append(args, "error", err, "error_type", errorType(err))
 	}
+	ls.logger.Error(message, args...)
 }
 
 func (ls *LoggerService) Warn(message string, args ...any) {
-	masked := ls.MaskSensitiveData(fmt.Sprintf(message, args...))
-	ls.logger.Warn(masked)
+	ls.logger.Warn(message, args...)
 }
 
 func (ls *LoggerService) Debug(message string, args ...any) {
-	masked := ls.MaskSensitiveData(fmt.Sprintf(message, args...))
-	ls.logger.Debug(masked)
+	ls.logger.Debug(message, args...)
 }
 
 func (ls *LoggerService) FormatConnectionAttempt(streamName, uri string) string {
 	return fmt.Sprintf("Попытка подключения к потоку %s: %s", streamName, ls.MaskSensitiveData(uri))
 }
+
+func errorType(err error) string {
+	var appErr *model.AppError
+	if errors.As(err, &appErr) {
+		return appErr.Type.String()
+	}
+	return fmt.Sprintf("%T", err)
+}
+
+type logLevels struct {
+	mu         sync.RWMutex
+	def        slog.Level
+	components map[string]slog.Level
+}
+
+func newLogLevels(def slog.Level, components map[string]slog.Level) *logLevels {
+	levels := &logLevels{def: def, components: make(map[string]slog.Level)}
+	for name, level := range components {
+		levels.components[name] = level
+	}
+	return levels
+}
+
+func (l *logLevels) level(component string) slog.Level {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	if level, ok := l.components[component]; ok {
+		return level
+	}
+	return l.def
+}
+
+func (l *logLevels) set(component string, level slog.Level) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if component == "" {
+		l.def = level
+		return
+	}
+	l.components[component] = level
+}
+
+// maskingHandler фильтрует записи по уровню компонента и маскирует пароли
+// в сообщении и во всех атрибутах до того, как запись попадёт в вывод.
+type maskingHandler struct {
+	next      slog.Handler
+	levels    *logLevels
+	secrets   *secretSet
+	component string
+}
+
+func (h *maskingHandler) mask(text string) string {
+	return maskSensitiveData(h.secrets.redact(text))
+}
+
+func (h *maskingHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.levels.level(h.component)
+}
+
+func (h *maskingHandler) Handle(ctx context.Context, r slog.Record) error {
+	masked := slog.NewRecord(r.Time, r.Level, h.mask(r.Message), r.PC)
+	r.Attrs(func(attr slog.Attr) bool {
+		masked.AddAttrs(h.maskAttr(attr))
+		return true
+	})
+	return h.next.Handle(ctx, masked)
+}
+
+func (h *maskingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	component := h.component
+	masked := make([]slog.Attr, 0, len(attrs))
+	for _, attr := range attrs {
+		if attr.Key == "component" {
+			component = attr.Value.String()
+		}
+		masked = append(masked, h.maskAttr(attr))
+	}
+
+	return &maskingHandler{
+		next:      h.next.WithAttrs(masked),
+		levels:    h.levels,
+		secrets:   h.secrets,
+		component: component,
+	}
+}
+
+func (h *maskingHandler) WithGroup(name string) slog.Handler {
+	return &maskingHandler{
+		next:      h.next.WithGroup(name),
+		levels:    h.levels,
+		secrets:   h.secrets,
+		component: h.component,
+	}
+}
+
+func (h *maskingHandler) maskAttr(attr slog.Attr) slog.Attr {
+	value := attr.Value.Resolve()
+
+	switch strings.ToLower(attr.Key) {
+	case "password", "pwd", "pass", "passwd":
+		return slog.String(attr.Key, "***")
+	}
+
+	switch value.Kind() {
+	case slog.KindString:
+		return slog.String(attr.Key, h.mask(value.String()))
+
+	case slog.KindGroup:
+		group := value.Group()
+		masked := make([]any, 0, len(group))
+		for _, member := range group {
+			masked = append(masked, h.maskAttr(member))
+		}
+		return slog.Group(attr.Key, masked...)
+
+	case slog.KindAny:
+		switch v := value.Any().(type) {
+		case error:
+			return slog.String(attr.Key, h.mask(v.Error()))
+		default:
+			return slog.String(attr.Key, h.mask(fmt.Sprint(v)))
+		}
+	}
+
+	return slog.Attr{Key: attr.Key, Value: value}
+}
+
+// secretSet хранит зарегистрированные секреты вместе с их URL-кодированными
+// формами, длинные — первыми, чтобы замена не оставляла хвостов. Длина
+// секрета не ограничена: пароль из одного-двух символов заменяется везде,
+// даже внутри слов, — журнал становится менее читаемым, но пароль не утекает.
+type secretSet struct {
+	mu     sync.RWMutex
+	values []string
+}
+
+func (s *secretSet) add(secret string) {
+	if secret == "" {
+		return
+	}
+
+	variants := []string{
+		secret,
+		url.QueryEscape(secret),
+		url.PathEscape(secret),
+		strings.TrimPrefix(url.UserPassword("", secret).String(), ":"),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, variant := range variants {
+		if !slices.Contains(s.values, variant) {
+			s.values = append(s.values, variant)
+		}
+	}
+	sort.Slice(s.values, func(i, j int) bool {
+		return len(s.values[i]) > len(s.values[j])
+	})
+}
+
+func (s *secretSet) redact(text string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, secret := range s.values {
+		if strings.Contains(text, secret) {
+			text = strings.ReplaceAll(text, secret, "***")
+		}
+	}
+	return text
+}
+
+// fanoutHandler передаёт запись нескольким обработчикам.
+type fanoutHandler []slog.Handler
+
+func (f fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range f {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range f {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (f fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanoutHandler, len(f))
+	for i, h := range f {
+		handlers[i] = h.WithAttrs(attrs)
+	}
+	return handlers
+}
+
+func (f fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make(fanoutHandler, len(f))
+	for i, h := range f {
+		handlers[i] = h.WithGroup(name)
+	}
+	return handlers
+}
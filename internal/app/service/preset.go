@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"ip-camera-viewer/internal/domain"
+	"ip-camera-viewer/internal/domain/model"
+	"ip-camera-viewer/internal/infrastructure/rtsp"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v5/pkg/base"
+)
+
+const (
+	presetProbeTimeout     = 5 * time.Second
+	presetProbeConcurrency = 4
+)
+
+type PresetService struct {
+	logger           *LoggerService
+	templateResolver *domain.TemplateResolver
+	presets          []domain.VendorPreset
+}
+
+type PresetMatch struct {
+	Preset domain.VendorPreset
+	MainOK bool
+	SubOK  bool
+}
+
+func NewPresetService(logger *LoggerService) *PresetService {
+	presets, err := domain.LoadVendorPresets()
+	if err != nil {
+		logger.Error("Ошибка загрузки пресетов", err)
+	}
+
+	return &PresetService{
+		logger:           logger.Component("preset"),
+		templateResolver: domain.NewTemplateResolver(),
+		presets:          presets,
+	}
+}
+
+func (ps *PresetService) Presets() []domain.VendorPreset {
+	return ps.presets
+}
+
+func (ps *PresetService) Find(vendor string) (domain.VendorPreset, bool) {
+	return domain.FindVendorPreset(ps.presets, vendor)
+}
+
+// AutoDetect выполняет DESCRIBE для шаблонов всех производителей и
+// возвращает пресеты, основной поток которых ответил 200 OK.
+func (ps *PresetService) AutoDetect(ctx context.Context, config *model.ConnectionConfig) []PresetMatch {
+	ps.logger.RegisterCredentials(config.Login, config.Password)
+
+	type probeKey struct {
+		preset int
+		sub    bool
+	}
+
+	uris := make(map[probeKey]string)
+	unique := make(map[string]bool)
+	for i, preset := range ps.presets {
+		main := ps.templateResolver.ResolveContext(preset.Main, &domain.PlaceholderContext{Config: config, StreamIndex: 0})
+		sub := ps.templateResolver.ResolveContext(preset.Sub, &domain.PlaceholderContext{Config: config, StreamIndex: 1})
+		uris[probeKey{i, false}] = main
+		uris[probeKey{i, true}] = sub
+		unique[main] = false
+		unique[sub] = false
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, presetProbeConcurrency)
+
+	for uri := range unique {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			defer func() { <-sem }()
+
+			ok := ps.probe(ctx, uri, config)
+
+			mu.Lock()
+			unique[uri] = ok
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	var matches []PresetMatch
+	for i, preset := range ps.presets {
+		match := PresetMatch{
+			Preset: preset,
+			MainOK: unique[uris[probeKey{i, false}]],
+			SubOK:  unique[uris[probeKey{i, true}]],
+		}
+		if match.MainOK {
+			matches = append(matches, match)
+		}
+	}
+
+	return matches
+}
+
+func (ps *PresetService) probe(ctx context.Context, uri string, config *model.ConnectionConfig) bool {
+	probeCtx, cancel := context.WithTimeout(ctx, presetProbeTimeout)
+	defer cancel()
+
+	streamConfig := model.NewStreamConfig("Probe", uri, config.Login, config.Password)
+	streamConfig.CAFile = config.CAFile
+	streamConfig.CertFingerprint = config.CertFingerprint
+
+	client := rtsp.NewClient(streamConfig)
+	client.SetLogger(ps.logger.Component("rtsp").With("stream", "Probe").Slog())
+
+	code, err := client.Probe(probeCtx)
+	if err != nil {
+		ps.logger.Debug("DESCRIBE не выполнен", "uri", uri, "error", err)
+		return false
+	}
+
+	ps.logger.Debug("DESCRIBE выполнен", "uri", uri, "status", int(code))
+	return code == base.StatusOK
+}
@@ -2,34 +2,78 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"ip-camera-viewer/internal/domain/model"
-	"ip-camera-viewer/internal/infrastructure/rtsp"
+	"ip-camera-viewer/internal/infrastructure/audio"
+	"ip-camera-viewer/internal/infrastructure/health"
+	"ip-camera-viewer/internal/infrastructure/motion"
+	"ip-camera-viewer/internal/infrastructure/pcap"
+	"ip-camera-viewer/internal/infrastructure/record"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type StreamManager struct {
-	logger        *LoggerService
-	streams       map[string]*StreamController
-	statusChannel chan *model.StreamStatusUpdate
-	mu            sync.RWMutex
-	cancelFuncs   map[string]context.CancelFunc
+	logger            *LoggerService
+	streams           map[string]*StreamController
+	statusChannel     chan *model.StreamStatusUpdate
+	audioLevelChannel chan *model.AudioLevel
+	audioSinkFactory  audio.SinkFactory
+	audioSrcFactory   audio.SourceFactory
+	mu                sync.RWMutex
+	cancelFuncs       map[string]context.CancelFunc
 }
 
 type StreamController struct {
 	Config       *model.StreamConfig
-	Client       *rtsp.Client
+	Source       StreamSource
 	FrameChannel chan *model.FrameData
 	Status       model.StreamStatus
 	Info         *model.StreamInfo
+	Motion       *motion.Detector
+	Recorder     *record.Recorder
+	Health       *health.Analyzer
+
+	warningsMu sync.Mutex
+	warnings   []model.VideoWarning
+	stalled    atomic.Bool
+}
+
+func (c *StreamController) Stalled() bool {
+	return c.stalled.Load()
+}
+
+func (c *StreamController) setStalled(stalled bool) {
+	c.stalled.Store(stalled)
+}
+
+func (c *StreamController) Warnings() []model.VideoWarning {
+	c.warningsMu.Lock()
+	defer c.warningsMu.Unlock()
+
+	return c.warnings
+}
+
+func (c *StreamController) setWarnings(warnings []model.VideoWarning) {
+	c.warningsMu.Lock()
+	defer c.warningsMu.Unlock()
+
+	c.warnings = warnings
 }
 
 func NewStreamManager(logger *LoggerService) *StreamManager {
 	return &StreamManager{
-		logger:        logger,
-		streams:       make(map[string]*StreamController),
-		statusChannel: make(chan *model.StreamStatusUpdate, 100),
-		cancelFuncs:   make(map[string]context.CancelFunc),
+		logger:            logger.Component("streams"),
+		streams:           make(map[string]*StreamController),
+		statusChannel:     make(chan *model.StreamStatusUpdate, 100),
+		audioLevelChannel: make(chan *model.AudioLevel, 100),
+		audioSinkFactory:  audio.NewDeviceSink,
+		audioSrcFactory:   audio.NewDeviceSource,
+		cancelFuncs:       make(map[string]context.CancelFunc),
 	}
 }
 
@@ -42,11 +86,18 @@ func (sm *StreamManager) StartStream(ctx context.Context, config *model.StreamCo
 
 	frameChannel := make(chan *model.FrameData, 30)
 
-	client := rtsp.NewClient(config)
+	source := newStreamSource(config)
+	if s, ok := source.(loggingSource); ok {
+		s.SetLogger(sm.logger.Component("rtsp").With("stream", config.Name).Slog())
+	}
+	if s, ok := source.(audioSource); ok {
+		s.SetAudioSinkFactory(sm.audioSinkFactory)
+		s.SetAudioLevelHandler(sm.sendAudioLevel)
+	}
 
 	controller := &StreamController{
 		Config:       config,
-		Client:       client,
+		Source:       source,
 		FrameChannel: frameChannel,
 		Status:       model.StatusConnecting,
 		Info: &model.StreamInfo{
@@ -55,42 +106,431 @@ func (sm *StreamManager) StartStream(ctx context.Context, config *model.StreamCo
 		},
 	}
 
+	if config.Recording != nil {
+		controller.Recorder = sm.newRecorder(config.Name, *config.Recording)
+		if s, ok := source.(recordingSource); ok {
+			s.SetRecorder(controller.Recorder)
+		}
+	}
+
 	sm.streams[config.Name] = controller
 
 	sm.sendStatus(config.Name, model.StatusConnecting, nil)
 
 	go sm.handleStream(streamCtx, controller)
 
-	return frameChannel, nil
+	if config.Motion == nil && config.Health == nil {
+		return frameChannel, nil
+	}
+
+	if config.Motion != nil {
+		controller.Motion = motion.NewDetector(*config.Motion)
+	}
+	if config.Health != nil {
+		controller.Health = health.NewAnalyzer(*config.Health)
+	}
+
+	output := make(chan *model.FrameData, 30)
+	go sm.runFrameAnalysis(streamCtx, controller, output)
+
+	return output, nil
+}
+
+// runFrameAnalysis пропускает кадры потока через детектор движения и
+// анализ изображения и передаёт их дальше. Анализ читает кадр до отправки,
+// поэтому буфер из пула не может быть переиспользован во время анализа.
+func (sm *StreamManager) runFrameAnalysis(ctx context.Context, controller *StreamController, output chan<- *model.FrameData) {
+	defer close(output)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case frame, ok := <-controller.FrameChannel:
+			if !ok {
+				return
+			}
+
+			if controller.Motion != nil {
+				if event := controller.Motion.Process(frame.Image, frame.Timestamp); event != nil {
+					event.StreamName = controller.Config.Name
+					sm.sendMotion(controller, event)
+					sm.triggerOnMotion()
+				}
+			}
+
+			if controller.Health != nil {
+				if warnings, changed := controller.Health.Process(frame.Image, frame.Timestamp); changed {
+					controller.setWarnings(warnings)
+					sm.sendWarnings(controller, warnings)
+				}
+			}
+
+			select {
+			case output <- frame:
+			default:
+				frame.Release()
+			}
+		}
+	}
+}
+
+func (sm *StreamManager) sendWarnings(controller *StreamController, warnings []model.VideoWarning) {
+	name := controller.Config.Name
+	if len(warnings) == 0 {
+		sm.logger.Info("Изображение в норме", "stream", name)
+	} else {
+		texts := make([]string, len(warnings))
+		for i, w := range warnings {
+			texts[i] = w.String()
+		}
+		sm.logger.Warn("Проблема изображения", "stream", name, "warnings", strings.Join(texts, ", "))
+	}
+
+	update := &model.StreamStatusUpdate{
+		StreamName: name,
+		Status:     controller.Status,
+		Info: &model.StreamInfo{
+			Name:     name,
+			Status:   controller.Status,
+			Warnings: append([]model.VideoWarning{}, warnings...),
+			Stalled:  controller.Stalled(),
+		},
+	}
+
+	select {
+	case sm.statusChannel <- update:
+	default:
+	}
+}
+
+// SetHealthConfig меняет пороги анализа изображения без переподключения.
+func (sm *StreamManager) SetHealthConfig(config model.HealthConfig) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	for _, controller := range sm.streams {
+		if controller.Health != nil {
+			controller.Health.SetConfig(config)
+		}
+	}
+}
+
+// SetMotionConfig меняет настройки детектора движения без переподключения.
+func (sm *StreamManager) SetMotionConfig(streamName string, config model.MotionConfig) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	controller, exists := sm.streams[streamName]
+	if !exists || controller.Motion == nil {
+		return
+	}
+
+	controller.Motion.SetConfig(config)
+}
+
+func (sm *StreamManager) newRecorder(streamName string, config model.RecordingConfig) *record.Recorder {
+	opts := record.Options{
+		Name:      streamName,
+		Dir:       config.Dir,
+		PreEvent:  time.Duration(config.PreEvent) * time.Second,
+		PostEvent: time.Duration(config.PostEvent) * time.Second,
+	}
+
+	return record.NewRecorder(opts, func(clip record.Clip) {
+		if clip.Err != nil {
+			sm.logger.Error("Ошибка записи клипа", clip.Err, "stream", streamName, "path", clip.Path)
+			return
+		}
+		sm.logger.Info("Клип сохранён", "stream", streamName, "reason", clip.Reason,
+			"path", clip.Path, "duration", clip.Duration.Round(time.Second).String())
+	})
+}
+
+// TriggerRecording сохраняет буфер потока и следующие секунды в клип.
+// Пустое имя потока запускает запись на всех потоках с буфером. reason
+// попадает в имя файла: motion, manual, api.
+func (sm *StreamManager) TriggerRecording(streamName, reason string) error {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	triggered := false
+	for name, controller := range sm.streams {
+		if controller.Recorder == nil || (streamName != "" && name != streamName) {
+			continue
+		}
+
+		if err := controller.Recorder.Trigger(reason); err != nil {
+			return fmt.Errorf("поток %s: %w", name, err)
+		}
+		triggered = true
+		sm.logger.Info("Запись клипа", "stream", name, "reason", reason)
+	}
+
+	if !triggered {
+		return fmt.Errorf("нет потоков с буфером записи")
+	}
+	return nil
+}
+
+// triggerOnMotion запускает или продлевает запись потоков, у которых
+// включена запись по движению. Срабатывает и на начало, и на конец
+// эпизода, поэтому клип захватывает PostEvent секунд после движения.
+func (sm *StreamManager) triggerOnMotion() {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	for name, controller := range sm.streams {
+		if controller.Recorder == nil || !controller.Config.Recording.OnMotion {
+			continue
+		}
+
+		if err := controller.Recorder.Trigger("motion"); err != nil {
+			sm.logger.Warn("Запись по движению не началась", "stream", name, "error", err)
+		}
+	}
+}
+
+// SetRecordOnMotion включает и выключает запись по движению без
+// переподключения.
+func (sm *StreamManager) SetRecordOnMotion(enabled bool) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	for _, controller := range sm.streams {
+		if controller.Config.Recording != nil {
+			controller.Config.Recording.OnMotion = enabled
+		}
+	}
+}
+
+func (sm *StreamManager) sendMotion(controller *StreamController, event *model.MotionEvent) {
+	sm.logger.Info("Детектор движения", "stream", event.StreamName, "event", event.Type.String(),
+		"area", fmt.Sprintf("%.1f%%", event.Area))
+
+	update := &model.StreamStatusUpdate{
+		StreamName: event.StreamName,
+		Status:     controller.Status,
+		Info: &model.StreamInfo{
+			Name:   event.StreamName,
+			Status: controller.Status,
+		},
+		Motion: event,
+	}
+
+	select {
+	case sm.statusChannel <- update:
+	default:
+	}
 }
 
 func (sm *StreamManager) handleStream(ctx context.Context, controller *StreamController) {
 	defer close(controller.FrameChannel)
+	if controller.Recorder != nil {
+		defer controller.Recorder.Close()
+	}
+	defer controller.Config.ClearCredentials()
 
-	err := controller.Client.Connect(ctx)
-	if err != nil {
-		sm.logger.Error("Ошибка подключения к потоку %s", err, controller.Config.Name)
-		sm.sendStatus(controller.Config.Name, model.StatusError, err)
-		return
+	name := controller.Config.Name
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if !sm.waitReconnect(ctx, controller, attempt) {
+				break
+			}
+		}
+
+		err := controller.Source.Connect(ctx)
+		if err != nil {
+			sm.logger.Error("Ошибка подключения к потоку", err, "stream", name)
+			if attempt > 0 {
+				continue
+			}
+			sm.sendStatus(name, model.StatusError, err)
+			return
+		}
+
+		sm.logger.Info("Успешно подключен к потоку", "stream", name)
+		sm.sendStatus(name, model.StatusPlaying, nil)
+
+		controller.Status = model.StatusPlaying
+
+		sessionCtx, stopWatchdog := context.WithCancel(ctx)
+		reconnect := sm.watchStream(sessionCtx, controller)
+
+		err = controller.Source.StartStreaming(ctx, controller.FrameChannel)
+		if err != nil && ctx.Err() == nil {
+			sm.logger.Error("Ошибка стриминга потока", err, "stream", name)
+			if !retryable(controller, err, reconnect) {
+				stopWatchdog()
+				sm.sendStatus(name, model.StatusError, err)
+				return
+			}
+		}
+
+		// поток мог закончиться сам или быть закрыт сторожем; ждём
+		// либо отключения, либо решения сторожа переподключиться
+		if err == nil {
+			select {
+			case <-ctx.Done():
+			case <-reconnect:
+			}
+		}
+		stopWatchdog()
+
+		if ctx.Err() != nil {
+			break
+		}
+		controller.Source.Close()
 	}
 
-	sm.logger.Info("Успешно подключен к потоку %s", controller.Config.Name)
-	sm.sendStatus(controller.Config.Name, model.StatusPlaying, nil)
+	controller.Source.Close()
+	sm.sendStatus(name, model.StatusDisconnected, nil)
+	sm.logger.Info("Поток отключен", "stream", name)
+}
 
-	controller.Status = model.StatusPlaying
+// waitReconnect ждёт перед очередной попыткой: 2 с, 4 с, ... до 30 с.
+func (sm *StreamManager) waitReconnect(ctx context.Context, controller *StreamController, attempt int) bool {
+	name := controller.Config.Name
+	delay := min(time.Duration(attempt)*2*time.Second, 30*time.Second)
 
-	err = controller.Client.StartStreaming(ctx, controller.FrameChannel)
-	if err != nil {
-		sm.logger.Error("Ошибка стриминга потока %s", err, controller.Config.Name)
-		sm.sendStatus(controller.Config.Name, model.StatusError, err)
-		return
+	controller.Status = model.StatusReconnecting
+	controller.Info.ReconnectAttempt = attempt
+	sm.logger.Info("Переподключение потока", "stream", name, "attempt", attempt, "delay", delay.String())
+	sm.sendStatus(name, model.StatusReconnecting, nil)
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// watchStream раз в секунду сравнивает время последнего RTP пакета и
+// последнего кадра с таймаутом, отсчитывая его от ответа на PLAY. Зависший поток отмечается в StreamInfo;
+// если включено переподключение, клиент закрывается, а возвращённый канал
+// закрывается, сообщая handleStream о новой попытке.
+func (sm *StreamManager) watchStream(ctx context.Context, controller *StreamController) <-chan struct{} {
+	reconnect := make(chan struct{})
+	config := controller.Config.Watchdog
+	if config == nil || config.Timeout <= 0 {
+		return reconnect
 	}
 
-	<-ctx.Done()
+	timeout := time.Duration(config.Timeout * float64(time.Second))
+	name := controller.Config.Name
+
+	go func() {
+		defer controller.setStalled(false)
+
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+
+		// на паузе архива данных нет намеренно, отсчёт идёт с её конца
+		var resumed time.Time
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				stats := controller.Source.Stats()
+				if stats.Playback != nil && stats.Playback.Paused {
+					resumed = now
+					continue
+				}
+				// до ответа на PLAY отсчёт не идёт: медленное рукопожатие
+				// ограничивает таймаут чтения клиента, а не сторож
+				if stats.PlayingSince.IsZero() {
+					continue
+				}
+				start := latest(stats.PlayingSince, resumed)
+				lastPacket := latest(stats.LastPacketTime, start)
+				lastFrame := latest(stats.LastFrameTime, start)
+				stalled := now.Sub(lastPacket) > timeout || now.Sub(lastFrame) > timeout
+
+				if stalled == controller.Stalled() {
+					continue
+				}
+				controller.setStalled(stalled)
+
+				if !stalled {
+					sm.logger.Info("Данные потока снова поступают", "stream", name)
+					sm.sendStalled(controller, stats)
+					continue
+				}
+
+				sm.logger.Warn("Поток завис", "stream", name,
+					"since_packet", now.Sub(lastPacket).Round(time.Second).String(),
+					"since_frame", now.Sub(lastFrame).Round(time.Second).String())
+				sm.sendStalled(controller, stats)
 
-	controller.Client.Close()
-	sm.sendStatus(controller.Config.Name, model.StatusDisconnected, nil)
-	sm.logger.Info("Поток %s отключен", controller.Config.Name)
+				if config.Reconnect {
+					controller.Source.Close()
+					close(reconnect)
+					return
+				}
+			}
+		}
+	}()
+
+	return reconnect
+}
+
+func (sm *StreamManager) sendStalled(controller *StreamController, stats model.StreamInfo) {
+	stats.Name = controller.Config.Name
+	stats.Status = controller.Status
+	stats.Stalled = controller.Stalled()
+	stats.Warnings = append([]model.VideoWarning{}, controller.Warnings()...)
+
+	update := &model.StreamStatusUpdate{
+		StreamName: stats.Name,
+		Status:     stats.Status,
+		Info:       &stats,
+	}
+
+	select {
+	case sm.statusChannel <- update:
+	default:
+	}
+}
+
+// retryable сообщает, нужна ли новая попытка после ошибки сессии: сторож
+// уже закрыл поток или переподключение включено. Отказ в доступе не
+// повторяется — пароль от этого не станет верным, а камеры блокируют
+// адрес после нескольких неудачных входов.
+func retryable(controller *StreamController, err error, reconnect <-chan struct{}) bool {
+	if closed(reconnect) {
+		return true
+	}
+
+	config := controller.Config.Watchdog
+	if config == nil || !config.Reconnect {
+		return false
+	}
+
+	var appErr *model.AppError
+	return !errors.As(err, &appErr) || appErr.Type != model.ErrorTypeAuthentication
+}
+
+func closed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
+
+func latest(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
 }
 
 func (sm *StreamManager) StopStream(streamName string) error {
@@ -106,7 +546,7 @@ func (sm *StreamManager) StopStream(streamName string) error {
 	delete(sm.cancelFuncs, streamName)
 	delete(sm.streams, streamName)
 
-	sm.logger.Info("Остановка потока %s", streamName)
+	sm.logger.Info("Остановка потока", "stream", streamName)
 	return nil
 }
 
@@ -116,13 +556,224 @@ func (sm *StreamManager) StopAllStreams() {
 
 	for name, cancel := range sm.cancelFuncs {
 		cancel()
-		sm.logger.Info("Остановка потока %s", name)
+		sm.logger.Info("Остановка потока", "stream", name)
 	}
 
 	sm.cancelFuncs = make(map[string]context.CancelFunc)
 	sm.streams = make(map[string]*StreamController)
 }
 
+func (sm *StreamManager) SetOutputSize(streamName string, width, height int) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	controller, exists := sm.streams[streamName]
+	if !exists {
+		return
+	}
+
+	controller.Source.SetOutputSize(width, height)
+}
+
+// SetAudioSinkFactory заменяет звуковую карту, например записью в WAV.
+// Действует на потоки, запущенные после вызова.
+func (sm *StreamManager) SetAudioSinkFactory(factory audio.SinkFactory) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.audioSinkFactory = factory
+}
+
+func (sm *StreamManager) SetMuted(streamName string, muted bool) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	controller, exists := sm.streams[streamName]
+	if !exists {
+		return
+	}
+
+	if s, ok := controller.Source.(audioSource); ok {
+		s.SetMuted(muted)
+	}
+}
+
+// SetAudioSourceFactory заменяет микрофон, например файлом WAV.
+func (sm *StreamManager) SetAudioSourceFactory(factory audio.SourceFactory) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+
+	sm.audioSrcFactory = factory
+}
+
+func (sm *StreamManager) StartTalk(ctx context.Context, streamName string) error {
+	sm.mu.RLock()
+	controller, exists := sm.streams[streamName]
+	factory := sm.audioSrcFactory
+	sm.mu.RUnlock()
+
+	if !exists {
+		return fmt.Errorf("поток %s не найден", streamName)
+	}
+
+	talk, ok := controller.Source.(talkSource)
+	if !ok {
+		return fmt.Errorf("поток %s не поддерживает передачу звука", streamName)
+	}
+
+	source, err := factory()
+	if err != nil {
+		return fmt.Errorf("ошибка открытия источника звука: %w", err)
+	}
+
+	return talk.StartTalk(ctx, source)
+}
+
+func (sm *StreamManager) StopTalk(streamName string) {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	controller, exists := sm.streams[streamName]
+	if !exists {
+		return
+	}
+
+	if s, ok := controller.Source.(talkSource); ok {
+		s.StopTalk()
+	}
+}
+
+// SeekPlayback переводит поток в режим архива с момента start.
+func (sm *StreamManager) SeekPlayback(streamName string, start time.Time) error {
+	source, err := sm.playbackSource(streamName)
+	if err != nil {
+		return err
+	}
+	return source.Seek(start)
+}
+
+func (sm *StreamManager) PausePlayback(streamName string) error {
+	source, err := sm.playbackSource(streamName)
+	if err != nil {
+		return err
+	}
+	return source.Pause()
+}
+
+func (sm *StreamManager) ResumePlayback(streamName string) error {
+	source, err := sm.playbackSource(streamName)
+	if err != nil {
+		return err
+	}
+	return source.Resume()
+}
+
+func (sm *StreamManager) SetPlaybackScale(streamName string, scale float64) error {
+	source, err := sm.playbackSource(streamName)
+	if err != nil {
+		return err
+	}
+	return source.SetScale(scale)
+}
+
+// GoLive возвращает поток из архива в эфир.
+func (sm *StreamManager) GoLive(streamName string) error {
+	source, err := sm.playbackSource(streamName)
+	if err != nil {
+		return err
+	}
+	return source.GoLive()
+}
+
+func (sm *StreamManager) playbackSource(streamName string) (playbackSource, error) {
+	sm.mu.RLock()
+	controller, exists := sm.streams[streamName]
+	sm.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("поток %s не найден", streamName)
+	}
+	source, ok := controller.Source.(playbackSource)
+	if !ok {
+		return nil, fmt.Errorf("поток %s не поддерживает воспроизведение архива", streamName)
+	}
+	return source, nil
+}
+
+// StreamNames возвращает имена активных потоков по алфавиту.
+func (sm *StreamManager) StreamNames() []string {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+
+	names := make([]string, 0, len(sm.streams))
+	for name := range sm.streams {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// StreamInfo возвращает статус и текущую статистику потока.
+func (sm *StreamManager) StreamInfo(streamName string) (*model.StreamInfo, bool) {
+	sm.mu.RLock()
+	controller, exists := sm.streams[streamName]
+	sm.mu.RUnlock()
+
+	if !exists {
+		return nil, false
+	}
+
+	info := controller.Source.Stats()
+	info.Name = streamName
+	info.Status = controller.Status
+	info.ErrorMessage = controller.Info.ErrorMessage
+	info.ReconnectAttempt = controller.Info.ReconnectAttempt
+	info.Warnings = controller.Warnings()
+	info.Stalled = controller.Stalled()
+	return &info, true
+}
+
+// StreamSDP возвращает SDP из ответа DESCRIBE.
+func (sm *StreamManager) StreamSDP(streamName string) []byte {
+	sm.mu.RLock()
+	controller, exists := sm.streams[streamName]
+	sm.mu.RUnlock()
+
+	if !exists {
+		return nil
+	}
+	if s, ok := controller.Source.(diagnosticSource); ok {
+		return s.SDP()
+	}
+	return nil
+}
+
+func (sm *StreamManager) CaptureRTP(ctx context.Context, streamName string, duration time.Duration) ([]pcap.Packet, error) {
+	sm.mu.RLock()
+	controller, exists := sm.streams[streamName]
+	sm.mu.RUnlock()
+
+	if !exists {
+		return nil, fmt.Errorf("поток %s не найден", streamName)
+	}
+	s, ok := controller.Source.(diagnosticSource)
+	if !ok {
+		return nil, fmt.Errorf("поток %s не поддерживает захват RTP", streamName)
+	}
+	return s.CaptureRTP(ctx, duration)
+}
+
+func (sm *StreamManager) GetAudioLevelChannel() <-chan *model.AudioLevel {
+	return sm.audioLevelChannel
+}
+
+func (sm *StreamManager) sendAudioLevel(level *model.AudioLevel) {
+	select {
+	case sm.audioLevelChannel <- level:
+	default:
+	}
+}
+
 func (sm *StreamManager) GetStatusChannel() <-chan *model.StreamStatusUpdate {
 	return sm.statusChannel
 }
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"ip-camera-viewer/internal/domain/model"
+	"ip-camera-viewer/internal/infrastructure/audio"
+	"ip-camera-viewer/internal/infrastructure/filesource"
+	"ip-camera-viewer/internal/infrastructure/pcap"
+	"ip-camera-viewer/internal/infrastructure/record"
+	"ip-camera-viewer/internal/infrastructure/rtsp"
+	"log/slog"
+	"time"
+)
+
+// StreamSource — источник кадров потока: камера по RTSP или локальный файл.
+// Остальные возможности (звук, обратный канал, диагностика) источник
+// может не поддерживать; они проверяются приведением к интерфейсам ниже.
+type StreamSource interface {
+	Connect(ctx context.Context) error
+	StartStreaming(ctx context.Context, frameChannel chan<- *model.FrameData) error
+	SetOutputSize(width, height int)
+	Stats() model.StreamInfo
+	Close() error
+}
+
+type loggingSource interface {
+	SetLogger(logger *slog.Logger)
+}
+
+type recordingSource interface {
+	SetRecorder(recorder *record.Recorder)
+}
+
+type audioSource interface {
+	SetAudioSinkFactory(factory audio.SinkFactory)
+	SetAudioLevelHandler(handler func(*model.AudioLevel))
+	SetMuted(muted bool)
+}
+
+type talkSource interface {
+	StartTalk(ctx context.Context, source audio.Source) error
+	StopTalk()
+}
+
+type diagnosticSource interface {
+	SDP() []byte
+	CaptureRTP(ctx context.Context, duration time.Duration) ([]pcap.Packet, error)
+}
+
+// playbackSource — источник, умеющий воспроизводить архив NVR.
+type playbackSource interface {
+	Seek(start time.Time) error
+	Pause() error
+	Resume() error
+	SetScale(scale float64) error
+	GoLive() error
+}
+
+func newStreamSource(config *model.StreamConfig) StreamSource {
+	if filesource.IsFileURI(config.RTSPURI) {
+		return filesource.NewSource(config)
+	}
+	return rtsp.NewClient(config)
+}
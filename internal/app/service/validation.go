@@ -1,74 +1,243 @@
 package service
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"ip-camera-viewer/internal/domain"
 	"ip-camera-viewer/internal/domain/model"
+	"ip-camera-viewer/internal/infrastructure/filesource"
 	"net"
+	"net/netip"
 	"net/url"
+	"os"
 	"strings"
+	"time"
+)
+
+const hostLookupTimeout = 3 * time.Second
+
+// Пределы буфера записи клипов: буфер до события хранится в памяти.
+const (
+	maxPreEventSeconds  = 60
+	maxPostEventSeconds = 600
 )
 
 type ValidationService struct {
 	normalizer       *domain.StreamNormalizer
 	templateResolver *domain.TemplateResolver
+	resolver         *net.Resolver
 }
 
 func NewValidationService() *ValidationService {
 	return &ValidationService{
 		normalizer:       domain.NewStreamNormalizer(),
 		templateResolver: domain.NewTemplateResolver(),
+		resolver:         net.DefaultResolver,
 	}
 }
 
 func (vs *ValidationService) ValidateConnectionConfig(config *model.ConnectionConfig) *model.ValidationResult {
 	result := model.NewValidationResult()
 
-	if !IsValidIPv4(config.IP) {
-		result.AddError("IP", "Некорректный IPv4-адрес")
+	for _, field := range []string{
+		model.FieldHost,
+		model.FieldPort,
+		model.FieldLogin,
+		model.FieldPassword,
+		model.FieldVariables,
+		model.FieldRTSPURI1,
+		model.FieldRTSPURI2,
+		model.FieldCAFile,
+		model.FieldFingerprint,
+		model.FieldMotionArea,
+		model.FieldRecording,
+	} {
+		vs.validateField(field, config, result)
 	}
 
-	if !IsValidPort(config.Port) {
-		result.AddError("Port", "Порт должен быть числом 1–65535")
+	if result.Valid {
+		vs.checkStreamUniqueness(config, result)
 	}
 
-	if strings.TrimSpace(config.Login) == "" {
-		result.AddError("Login", "Логин не должен быть пустым")
+	return result
+}
+
+// ValidateField проверяет одно поле формы. Шаблоны RTSP URI зависят от
+// остальных полей, поэтому на вход подаётся вся конфигурация.
+func (vs *ValidationService) ValidateField(field string, config *model.ConnectionConfig) *model.ValidationResult {
+	result := model.NewValidationResult()
+	vs.validateField(field, config, result)
+	return result
+}
+
+func (vs *ValidationService) validateField(field string, config *model.ConnectionConfig, result *model.ValidationResult) {
+	switch field {
+	case model.FieldHost, model.FieldPort, model.FieldLogin, model.FieldPassword:
+		if !usesCamera(config) {
+			return
+		}
 	}
 
-	if strings.TrimSpace(config.Password) == "" {
-		result.AddError("Password", "Пароль не должен быть пустым")
+	switch field {
+	case model.FieldHost:
+		if !IsValidHost(config.IP) {
+			result.AddError(field, "Некорректный адрес: ожидается IPv4, IPv6 или имя хоста")
+		}
+
+	case model.FieldPort:
+		if !IsValidPort(config.Port) {
+			result.AddError(field, "Порт должен быть числом 1–65535")
+		}
+
+	case model.FieldLogin:
+		if strings.TrimSpace(config.Login) == "" {
+			result.AddError(field, "Логин не должен быть пустым")
+		}
+
+	case model.FieldPassword:
+		if strings.TrimSpace(config.Password) == "" {
+			result.AddError(field, "Пароль не должен быть пустым")
+		}
+
+	case model.FieldVariables:
+		vs.validateVariables(config, result)
+
+	case model.FieldRTSPURI1:
+		vs.validateRTSPURI(field, config.RTSPURI1, 0, config, result)
+
+	case model.FieldRTSPURI2:
+		vs.validateRTSPURI(field, config.RTSPURI2, 1, config, result)
+
+	case model.FieldCAFile:
+		if config.CAFile != "" {
+			if info, err := os.Stat(config.CAFile); err != nil || info.IsDir() {
+				result.AddError(field, "Файл сертификата CA не найден")
+			}
+		}
+
+	case model.FieldFingerprint:
+		if config.CertFingerprint != "" {
+			if _, ok := model.NormalizeFingerprint(config.CertFingerprint); !ok {
+				result.AddError(field, "Отпечаток сертификата должен содержать 64 шестнадцатеричных символа")
+			}
+		}
+
+	case model.FieldMotionArea:
+		if config.Motion.MinArea < 0 || config.Motion.MinArea > 100 {
+			result.AddError(field, "Минимальная площадь движения задаётся в процентах от 0 до 100")
+		}
+
+	case model.FieldRecording:
+		if config.Recording.PreEvent < 0 || config.Recording.PreEvent > maxPreEventSeconds {
+			result.AddError(field, fmt.Sprintf("Запись до события: от 0 до %d секунд", maxPreEventSeconds))
+		}
+		if config.Recording.PostEvent < 1 || config.Recording.PostEvent > maxPostEventSeconds {
+			result.AddError(field, fmt.Sprintf("Запись после события: от 1 до %d секунд", maxPostEventSeconds))
+		}
 	}
+}
+
+// usesCamera сообщает, что хотя бы один поток идёт с камеры. Если оба
+// URI — локальные файлы, адрес, порт и учётные данные не нужны.
+func usesCamera(config *model.ConnectionConfig) bool {
+	return !filesource.IsFileURI(config.RTSPURI1) || !filesource.IsFileURI(config.RTSPURI2)
+}
 
-	if !IsValidRTSPURIWithPlaceholders(config.RTSPURI1) {
-		result.AddError("RTSP URI #1", "RTSP-URI должен начинаться с rtsp:// и быть корректным URL")
+func (vs *ValidationService) validateRTSPURI(field, uri string, index int, config *model.ConnectionConfig, result *model.ValidationResult) {
+	ctx := &domain.PlaceholderContext{Config: config, StreamIndex: index}
+
+	if filesource.IsFileURI(uri) {
+		vs.validateFileURI(field, vs.templateResolver.ResolveContext(uri, ctx), result)
+	} else if !IsValidRTSPURIWithPlaceholders(uri) {
+		result.AddError(field, "RTSP-URI должен начинаться с rtsp://, rtsps:// или file:// и быть корректным URL")
 	}
 
-	if !IsValidRTSPURIWithPlaceholders(config.RTSPURI2) {
-		result.AddError("RTSP URI #2", "RTSP-URI должен начинаться с rtsp:// и быть корректным URL")
+	for _, err := range vs.templateResolver.Validate(uri, ctx) {
+		result.AddError(field, err.Error())
 	}
+}
 
-	if result.Valid {
-		vs.checkStreamUniqueness(config, result)
+// validateFileURI проверяет URI локального файла для воспроизведения
+// записи вместо камеры.
+func (vs *ValidationService) validateFileURI(field, uri string, result *model.ValidationResult) {
+	opts, err := filesource.ParseURI(uri)
+	if err != nil {
+		result.AddError(field, "Некорректный URI файла: "+err.Error())
+		return
 	}
 
-	return result
+	if info, err := os.Stat(opts.Path); err != nil || info.IsDir() {
+		result.AddError(field, "Файл для воспроизведения не найден: "+opts.Path)
+	}
+}
+
+func (vs *ValidationService) validateVariables(config *model.ConnectionConfig, result *model.ValidationResult) {
+	for name := range config.Variables {
+		if !domain.IsValidPlaceholderName(name) {
+			result.AddError(model.FieldVariables, "Некорректное имя переменной: "+name)
+			continue
+		}
+
+		switch name {
+		case "login", "password", "ip", "port", "date":
+			result.AddWarning("Переменная " + name + " совпадает со встроенным плейсхолдером и не будет использована")
+		}
+	}
 }
 
 func (vs *ValidationService) checkStreamUniqueness(config *model.ConnectionConfig, result *model.ValidationResult) {
-	uri1 := vs.templateResolver.Resolve(config.RTSPURI1, config)
-	uri2 := vs.templateResolver.Resolve(config.RTSPURI2, config)
+	resolved := vs.templateResolver.ResolveAll(config)
 
-	identical, err := vs.normalizer.AreIdentical(uri1, uri2)
+	comparison, err := vs.normalizer.Compare(resolved.URI1, resolved.URI2)
 	if err != nil {
 		result.AddWarning("Не удалось проверить уникальность RTSP URI: " + err.Error())
 		return
 	}
 
-	if identical {
-		result.AddError("RTSP URIs", "Оба RTSP-URI указывают на один и тот же поток после нормализации. Измените один из URI, чтобы избежать двойной загрузки.")
+	if comparison.Identical {
+		message := "Оба RTSP-URI указывают на один и тот же поток после нормализации"
+		if explanation := comparison.Explain(); explanation != "" {
+			message += " (" + explanation + ")"
+		}
+		result.AddError(model.FieldRTSPURIs, message+". Измените один из URI, чтобы избежать двойной загрузки.")
 	}
 }
 
+// ResolveHostAsync проверяет, что имя хоста резолвится. Результат содержит
+// только предупреждения: недоступный DNS не должен мешать подключению.
+func (vs *ValidationService) ResolveHostAsync(ctx context.Context, host string) <-chan *model.ValidationResult {
+	resultChan := make(chan *model.ValidationResult, 1)
+
+	go func() {
+		defer close(resultChan)
+
+		result := model.NewValidationResult()
+		host = strings.TrimSpace(host)
+		if IsValidIPv4(host) || IsValidIPv6(host) || !IsValidHostname(host) {
+			resultChan <- result
+			return
+		}
+
+		lookupCtx, cancel := context.WithTimeout(ctx, hostLookupTimeout)
+		defer cancel()
+
+		_, err := vs.resolver.LookupHost(lookupCtx, host)
+		var dnsErr *net.DNSError
+		switch {
+		case err == nil:
+		case errors.As(err, &dnsErr) && dnsErr.IsNotFound:
+			result.AddWarning("Имя хоста " + host + " не найдено в DNS (NXDOMAIN)")
+		default:
+			result.AddWarning("Не удалось разрешить имя хоста " + host + ": " + err.Error())
+		}
+
+		resultChan <- result
+	}()
+
+	return resultChan
+}
+
 func (vs *ValidationService) ValidateAndResolve(config *model.ConnectionConfig) (*model.ResolvedURIs, *model.ValidationResult) {
 	result := vs.ValidateConnectionConfig(config)
 
@@ -98,6 +267,51 @@ func IsValidIPv4(ip string) bool {
 	return parsedIP.To4() != nil
 }
 
+func IsValidIPv6(ip string) bool {
+	ip = strings.TrimSpace(ip)
+	if strings.HasPrefix(ip, "[") && strings.HasSuffix(ip, "]") {
+		ip = ip[1 : len(ip)-1]
+	}
+
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+
+	return addr.Is6() && !addr.Is4In6()
+}
+
+// IsValidHostname проверяет имя хоста по RFC 1123.
+func IsValidHostname(host string) bool {
+	host = strings.TrimSuffix(strings.TrimSpace(host), ".")
+	if host == "" || len(host) > 253 {
+		return false
+	}
+
+	labels := strings.Split(host, ".")
+	for _, label := range labels {
+		if len(label) == 0 || len(label) > 63 {
+			return false
+		}
+		if label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-') {
+				return false
+			}
+		}
+	}
+
+	// имя из одних цифр и точек — это некорректный IPv4, а не хост
+	last := labels[len(labels)-1]
+	return strings.Trim(last, "0123456789") != ""
+}
+
+func IsValidHost(host string) bool {
+	return IsValidIPv4(host) || IsValidIPv6(host) || IsValidHostname(host)
+}
+
 func IsValidPort(port int) bool {
 	return port >= 1 && port <= 65535
 }
@@ -108,7 +322,7 @@ func IsValidRTSPURI(uri string) bool {
 		return false
 	}
 
-	if !strings.HasPrefix(strings.ToLower(uri), "rtsp://") {
+	if !hasRTSPScheme(uri) {
 		return false
 	}
 
@@ -117,7 +331,8 @@ func IsValidRTSPURI(uri string) bool {
 		return false
 	}
 
-	if strings.ToLower(parsedURL.Scheme) != "rtsp" {
+	scheme := strings.ToLower(parsedURL.Scheme)
+	if scheme != "rtsp" && scheme != "rtsps" {
 		return false
 	}
 
@@ -134,15 +349,14 @@ func IsValidRTSPURIWithPlaceholders(uri string) bool {
 		return false
 	}
 
-	if !strings.HasPrefix(strings.ToLower(uri), "rtsp://") {
+	if !hasRTSPScheme(uri) {
 		return false
 	}
 
-	temp := uri
-	temp = strings.ReplaceAll(temp, "{login}", "user")
-	temp = strings.ReplaceAll(temp, "{password}", "pass")
-	temp = strings.ReplaceAll(temp, "{ip}", "192.168.1.1")
-	temp = strings.ReplaceAll(temp, "{port}", "554")
+	return IsValidRTSPURI(domain.NewTemplateResolver().ResolveSample(uri))
+}
 
-	return IsValidRTSPURI(temp)
+func hasRTSPScheme(uri string) bool {
+	lower := strings.ToLower(uri)
+	return strings.HasPrefix(lower, "rtsp://") || strings.HasPrefix(lower, "rtsps://")
 }
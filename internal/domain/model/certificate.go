@@ -0,0 +1,67 @@
+package model
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+type CertificateInfo struct {
+	Subject     string
+	Issuer      string
+	DNSNames    []string
+	NotBefore   time.Time
+	NotAfter    time.Time
+	Fingerprint string
+}
+
+func NewCertificateInfo(cert *x509.Certificate) CertificateInfo {
+	sum := sha256.Sum256(cert.Raw)
+
+	return CertificateInfo{
+		Subject:     cert.Subject.String(),
+		Issuer:      cert.Issuer.String(),
+		DNSNames:    cert.DNSNames,
+		NotBefore:   cert.NotBefore,
+		NotAfter:    cert.NotAfter,
+		Fingerprint: FormatFingerprint(sum[:]),
+	}
+}
+
+// FormatFingerprint возвращает отпечаток в виде AB:CD:EF:...
+func FormatFingerprint(sum []byte) string {
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = strings.ToUpper(hex.EncodeToString([]byte{b}))
+	}
+	return strings.Join(parts, ":")
+}
+
+// NormalizeFingerprint приводит отпечаток SHA-256 к виду AB:CD:EF:...,
+// допуская ввод без разделителей и в нижнем регистре.
+func NormalizeFingerprint(fingerprint string) (string, bool) {
+	cleaned := strings.NewReplacer(":", "", " ", "", "-", "").Replace(strings.TrimSpace(fingerprint))
+
+	sum, err := hex.DecodeString(cleaned)
+	if err != nil || len(sum) != sha256.Size {
+		return "", false
+	}
+
+	return FormatFingerprint(sum), true
+}
+
+type UntrustedCertificateError struct {
+	Info   CertificateInfo
+	Pinned string
+	Reason string
+}
+
+func (e *UntrustedCertificateError) Error() string {
+	return "недоверенный сертификат " + e.Info.Subject + ": " + e.Reason
+}
+
+func (e *UntrustedCertificateError) IsPinMismatch() bool {
+	return e.Pinned != ""
+}
@@ -3,12 +3,31 @@ package model
 import "time"
 
 type ConnectionConfig struct {
-	IP       string
-	Port     int
-	Login    string
-	Password string
-	RTSPURI1 string
-	RTSPURI2 string
+	IP          string
+	Port        int
+	Login       string
+	Password    string
+	RTSPURI1    string
+	RTSPURI2    string
+	Audio       bool
+	Backchannel bool
+
+	CAFile          string
+	CertFingerprint string
+
+	Motion    MotionConfig
+	Recording RecordingConfig
+	// Health и Watchdog: nil — не настроено, берутся значения по умолчанию.
+	// Заданная конфигурация используется как есть, нулевые пороги отключают
+	// соответствующие проверки.
+	Health   *HealthConfig
+	Watchdog *WatchdogConfig
+
+	// RememberPassword разрешает сохранять пароль в файл конфигурации.
+	RememberPassword bool
+
+	// Variables — пользовательские переменные профиля для шаблонов RTSP URI.
+	Variables map[string]string
 }
 
 type ResolvedURIs struct {
@@ -20,11 +39,25 @@ type ResolvedURIs struct {
 }
 
 type StreamConfig struct {
-	Name     string
-	RTSPURI  string
-	Login    string
-	Password string
-	Timeout  time.Duration
+	Name        string
+	RTSPURI     string
+	Login       string
+	Password    string
+	Timeout     time.Duration
+	Audio       bool
+	Backchannel bool
+
+	CAFile          string
+	CertFingerprint string
+
+	// Motion включает детектор движения на этом потоке.
+	Motion *MotionConfig
+	// Recording включает буфер записи клипов по событию.
+	Recording *RecordingConfig
+	// Health включает анализ изображения: застывание, чёрный кадр и т.п.
+	Health *HealthConfig
+	// Watchdog следит за тем, что данные продолжают приходить.
+	Watchdog *WatchdogConfig
 }
 
 func NewStreamConfig(name, rtspURI, login, password string) *StreamConfig {
@@ -36,3 +69,9 @@ func NewStreamConfig(name, rtspURI, login, password string) *StreamConfig {
 		Timeout:  10 * time.Second,
 	}
 }
+
+// ClearCredentials забывает пароль и разрешённый URI, в который он подставлен.
+func (sc *StreamConfig) ClearCredentials() {
+	sc.Password = ""
+	sc.RTSPURI = ""
+}
@@ -0,0 +1,65 @@
+package model
+
+// VideoWarning — признак того, что поток идёт, но изображение непригодно.
+type VideoWarning int
+
+const (
+	WarningFrozen VideoWarning = iota
+	WarningBlack
+	WarningCovered
+	WarningDefocused
+	WarningMoved
+)
+
+func (w VideoWarning) String() string {
+	switch w {
+	case WarningFrozen:
+		return "изображение застыло"
+	case WarningBlack:
+		return "чёрный кадр"
+	case WarningCovered:
+		return "камера закрыта"
+	case WarningDefocused:
+		return "изображение расфокусировано"
+	case WarningMoved:
+		return "камера сдвинута"
+	default:
+		return "неизвестно"
+	}
+}
+
+// HealthConfig — пороги анализа изображения.
+type HealthConfig struct {
+	// FrozenAfter — через сколько секунд одинаковых кадров изображение
+	// считается застывшим. 0 отключает проверку.
+	FrozenAfter float64 `json:"frozen_after"`
+	// BlackLuma — средняя яркость 0..255, ниже которой кадр считается чёрным.
+	BlackLuma float64 `json:"black_luma"`
+	// CoveredContrast — стандартное отклонение яркости, ниже которого
+	// однотонный кадр считается закрытым объективом.
+	CoveredContrast float64 `json:"covered_contrast"`
+	// DefocusRatio — во сколько раз резкость должна упасть относительно
+	// обычной для этой камеры, чтобы считаться расфокусировкой (0..1).
+	DefocusRatio float64 `json:"defocus_ratio"`
+	// MovedChange — доля кадра в процентах, изменившаяся разом, при которой
+	// камера считается сдвинутой. 0 отключает проверку.
+	MovedChange float64 `json:"moved_change"`
+	// Persist — сколько секунд признак должен держаться, прежде чем
+	// превратиться в предупреждение.
+	Persist float64 `json:"persist"`
+}
+
+func DefaultHealthConfig() HealthConfig {
+	return HealthConfig{
+		FrozenAfter:     5,
+		BlackLuma:       16,
+		CoveredContrast: 6,
+		DefocusRatio:    0.35,
+		MovedChange:     70,
+		Persist:         3,
+	}
+}
+
+func (w VideoWarning) MarshalText() ([]byte, error) {
+	return []byte(w.String()), nil
+}
@@ -0,0 +1,50 @@
+package model
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LogEntry — запись журнала, уже прошедшая маскирование паролей.
+type LogEntry struct {
+	Time      time.Time
+	Level     slog.Level
+	Component string
+	Message   string
+	Attrs     []LogAttr
+}
+
+type LogAttr struct {
+	Key   string
+	Value string
+}
+
+func (e *LogEntry) String() string {
+	var b strings.Builder
+
+	b.WriteString(e.Time.Format("15:04:05.000"))
+	b.WriteByte(' ')
+	b.WriteString(e.Level.String())
+	if e.Component != "" {
+		b.WriteString(" [")
+		b.WriteString(e.Component)
+		b.WriteByte(']')
+	}
+	b.WriteByte(' ')
+	b.WriteString(e.Message)
+
+	for _, attr := range e.Attrs {
+		b.WriteByte(' ')
+		b.WriteString(attr.Key)
+		b.WriteByte('=')
+		if strings.ContainsAny(attr.Value, " \t\n\"=") {
+			b.WriteString(strconv.Quote(attr.Value))
+		} else {
+			b.WriteString(attr.Value)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// Zone — прямоугольник в долях кадра (0..1), не зависящий от разрешения.
+type Zone struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+	W float64 `json:"w"`
+	H float64 `json:"h"`
+}
+
+func (z Zone) Contains(x, y float64) bool {
+	return x >= z.X && x < z.X+z.W && y >= z.Y && y < z.Y+z.H
+}
+
+func (z Zone) Empty() bool {
+	return z.W <= 0 || z.H <= 0
+}
+
+// MotionConfig — настройки детектора движения.
+type MotionConfig struct {
+	Enabled bool `json:"enabled"`
+	// Sensitivity 1..100: чем больше, тем меньшая разница яркости считается движением.
+	Sensitivity int `json:"sensitivity"`
+	// MinArea — минимальная доля изменившейся площади кадра, в процентах.
+	MinArea float64 `json:"min_area"`
+	// Zones — области маски, изменения в которых игнорируются.
+	Zones []Zone `json:"zones,omitempty"`
+}
+
+func DefaultMotionConfig() MotionConfig {
+	return MotionConfig{
+		Sensitivity: 60,
+		MinArea:     0.5,
+	}
+}
+
+type MotionEventType int
+
+const (
+	MotionStarted MotionEventType = iota
+	MotionEnded
+)
+
+func (t MotionEventType) String() string {
+	switch t {
+	case MotionStarted:
+		return "движение началось"
+	case MotionEnded:
+		return "движение закончилось"
+	default:
+		return "неизвестно"
+	}
+}
+
+// MotionEvent описывает начало или конец эпизода движения. Box и Area для
+// MotionEnded охватывают весь эпизод.
+type MotionEvent struct {
+	StreamName string
+	Type       MotionEventType
+	Time       time.Time
+	Start      time.Time
+	Box        Zone
+	// Area — доля изменившейся площади, в процентах.
+	Area float64
+}
+
+func (e *MotionEvent) String() string {
+	text := fmt.Sprintf("%s %s: %s, площадь %.1f%%, область [%.2f %.2f %.2f %.2f]",
+		e.Time.Format("15:04:05"), e.StreamName, e.Type, e.Area, e.Box.X, e.Box.Y, e.Box.W, e.Box.H)
+	if e.Type == MotionEnded {
+		text += fmt.Sprintf(", длительность %s", e.Time.Sub(e.Start).Round(100*time.Millisecond))
+	}
+	return text
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// PlaybackInfo — состояние воспроизведения архива NVR. У живого потока nil.
+type PlaybackInfo struct {
+	// Position — время записи, которое показывается сейчас.
+	Position time.Time
+	// Scale — скорость: 1 — обычная, больше 1 — перемотка вперёд,
+	// отрицательная — перемотка назад.
+	Scale  float64
+	Paused bool
+}
+
+// PlaybackScales — скорости, которые предлагает интерфейс.
+var PlaybackScales = []float64{-8, -4, -2, -1, 1, 2, 4, 8}
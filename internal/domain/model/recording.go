@@ -0,0 +1,19 @@
+package model
+
+// RecordingConfig — настройки записи клипов по событию. Длительности
+// задаются в секундах, чтобы конфигурация читалась человеком.
+type RecordingConfig struct {
+	OnMotion bool   `json:"on_motion"`
+	Dir      string `json:"dir,omitempty"`
+	// PreEvent — сколько секунд до события хранится в памяти.
+	PreEvent int `json:"pre_event"`
+	// PostEvent — сколько секунд записывается после последнего срабатывания.
+	PostEvent int `json:"post_event"`
+}
+
+func DefaultRecordingConfig() RecordingConfig {
+	return RecordingConfig{
+		PreEvent:  10,
+		PostEvent: 20,
+	}
+}
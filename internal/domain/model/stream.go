@@ -41,12 +41,48 @@ type StreamInfo struct {
 	FPS              float64
 	Bitrate          int64
 	LastFrameTime    time.Time
+	LastPacketTime   time.Time
 	ReconnectAttempt int
+	// PlayingSince — когда камера подтвердила PLAY текущей сессии. Пока
+	// идут DESCRIBE, SETUP и PLAY, время нулевое.
+	PlayingSince time.Time
+	// Stalled — соединение открыто, но данные не приходят дольше таймаута.
+	Stalled bool
+	// Warnings — проблемы изображения при живом потоке. nil — анализ не
+	// проводился, пустой срез — изображение в норме.
+	Warnings []VideoWarning
+	// Playback заполняется, когда поток воспроизводит архив, а не эфир.
+	Playback *PlaybackInfo
 }
 
 type FrameData struct {
 	Image     image.Image
 	Timestamp time.Time
+	release   func()
+}
+
+func NewFrameData(img image.Image, timestamp time.Time, release func()) *FrameData {
+	return &FrameData{
+		Image:     img,
+		Timestamp: timestamp,
+		release:   release,
+	}
+}
+
+// Release возвращает буфер кадра в пул. Кадр нельзя использовать после вызова.
+func (f *FrameData) Release() {
+	if f == nil || f.release == nil {
+		return
+	}
+	f.release()
+	f.release = nil
+}
+
+type AudioLevel struct {
+	StreamName string
+	Peak       float64
+	RMS        float64
+	Timestamp  time.Time
 }
 
 type StreamStatusUpdate struct {
@@ -54,4 +90,6 @@ type StreamStatusUpdate struct {
 	Status     StreamStatus
 	Error      error
 	Info       *StreamInfo
+	// Motion заполняется, когда обновление несёт событие детектора движения.
+	Motion *MotionEvent
 }
@@ -1,5 +1,21 @@
 package model
 
+// Имена полей формы подключения, на которые ссылается ValidationError.Field.
+const (
+	FieldHost        = "IP"
+	FieldPort        = "Port"
+	FieldLogin       = "Login"
+	FieldPassword    = "Password"
+	FieldRTSPURI1    = "RTSP URI #1"
+	FieldRTSPURI2    = "RTSP URI #2"
+	FieldRTSPURIs    = "RTSP URIs"
+	FieldCAFile      = "CA"
+	FieldFingerprint = "SHA-256"
+	FieldVariables   = "Переменные"
+	FieldMotionArea  = "Мин. площадь"
+	FieldRecording   = "Запись"
+)
+
 type ValidationResult struct {
 	Valid    bool
 	Errors   []ValidationError
@@ -31,6 +47,17 @@ func (vr *ValidationResult) AddWarning(message string) {
 	vr.Warnings = append(vr.Warnings, message)
 }
 
+// FieldErrors возвращает сообщения об ошибках, относящиеся к полю field.
+func (vr *ValidationResult) FieldErrors(field string) []string {
+	var messages []string
+	for _, err := range vr.Errors {
+		if err.Field == field {
+			messages = append(messages, err.Message)
+		}
+	}
+	return messages
+}
+
 func (vr *ValidationResult) GetErrorMessage() string {
 	if vr.Valid {
 		return ""
@@ -63,6 +90,23 @@ const (
 	ErrorTypeUnknown
 )
 
+func (t ErrorType) String() string {
+	switch t {
+	case ErrorTypeValidation:
+		return "validation"
+	case ErrorTypeConnection:
+		return "connection"
+	case ErrorTypeAuthentication:
+		return "authentication"
+	case ErrorTypeDecoding:
+		return "decoding"
+	case ErrorTypeStream:
+		return "stream"
+	default:
+		return "unknown"
+	}
+}
+
 func (e *AppError) Error() string {
 	if e.Cause != nil {
 		return e.Message + ": " + e.Cause.Error()
@@ -0,0 +1,19 @@
+package model
+
+// WatchdogConfig — настройки обнаружения зависшего потока, когда камера
+// держит соединение, но перестаёт присылать данные.
+type WatchdogConfig struct {
+	// Timeout — сколько секунд без RTP пакетов или декодированных кадров
+	// поток считается живым. 0 отключает наблюдение.
+	Timeout float64 `json:"timeout"`
+	// Reconnect переподключает зависший поток и поток, соединение
+	// которого оборвалось.
+	Reconnect bool `json:"reconnect"`
+}
+
+func DefaultWatchdogConfig() WatchdogConfig {
+	return WatchdogConfig{
+		Timeout:   10,
+		Reconnect: true,
+	}
+}
@@ -1,69 +1,408 @@
 package domain
 
 import (
+	"net"
+	"net/netip"
 	"net/url"
-	"sort"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 )
 
-type StreamNormalizer struct{}
+// NormalizeRule — правило канонизации URI, которое изменило исходную строку.
+type NormalizeRule int
+
+const (
+	RuleTrimSpace NormalizeRule = iota
+	RuleLowercaseScheme
+	RuleStripCredentials
+	RuleCanonicalHost
+	RuleDefaultPort
+	RulePercentEncoding
+	RuleTrailingSlash
+	RuleCleanPath
+	RuleLowercasePath
+	RuleStripTrackID
+	RuleSortQuery
+	RuleStripFragment
+)
+
+func (r NormalizeRule) String() string {
+	switch r {
+	case RuleTrimSpace:
+		return "пробелы по краям"
+	case RuleLowercaseScheme:
+		return "регистр схемы"
+	case RuleStripCredentials:
+		return "логин и пароль не учитываются"
+	case RuleCanonicalHost:
+		return "регистр хоста и запись IPv6"
+	case RuleDefaultPort:
+		return "порт по умолчанию"
+	case RulePercentEncoding:
+		return "процентное кодирование"
+	case RuleTrailingSlash:
+		return "завершающий слэш"
+	case RuleCleanPath:
+		return "лишние // и /./ в пути"
+	case RuleLowercasePath:
+		return "регистр пути"
+	case RuleStripTrackID:
+		return "суффикс /trackID=N"
+	case RuleSortQuery:
+		return "порядок параметров запроса"
+	case RuleStripFragment:
+		return "фрагмент #..."
+	default:
+		return "неизвестное правило"
+	}
+}
+
+// defaultPorts — порты, которые клиент подставляет, если в URI порт не указан.
+var defaultPorts = map[string]string{
+	"rtsp":  "554",
+	"rtsps": "322",
+}
+
+// trackIDPattern — адрес дорожки из SDP, который некоторые камеры отдают
+// вместо адреса потока.
+var trackIDPattern = regexp.MustCompile(`(?i)/trackid=\d+$`)
+
+type NormalizerOptions struct {
+	// CaseInsensitivePath сравнивает пути без учёта регистра латиницы.
+	// Большинство камер различают регистр (/Streaming/Channels/101),
+	// поэтому по умолчанию выключено.
+	CaseInsensitivePath bool
+	// KeepCredentials оставляет логин и пароль в результате Normalize.
+	// AreIdentical сравнивает URI без них в любом случае.
+	KeepCredentials bool
+	// KeepTrackID не отбрасывает завершающий /trackID=N.
+	KeepTrackID bool
+}
+
+func DefaultNormalizerOptions() NormalizerOptions {
+	return NormalizerOptions{KeepCredentials: true}
+}
+
+// Normalization — канонический URI и правила, которые его изменили.
+type Normalization struct {
+	URI   string
+	Rules []NormalizeRule
+}
+
+func (n *Normalization) fire(rule NormalizeRule) {
+	if !slices.Contains(n.Rules, rule) {
+		n.Rules = append(n.Rules, rule)
+	}
+}
+
+// Explain перечисляет сработавшие правила через запятую.
+func (n *Normalization) Explain() string {
+	return explainRules(n.Rules)
+}
+
+// Comparison — результат сравнения двух URI по идентичности потока.
+type Comparison struct {
+	Identical bool
+	First     *Normalization
+	Second    *Normalization
+}
+
+// Rules возвращает правила, сработавшие хотя бы для одного URI.
+func (c *Comparison) Rules() []NormalizeRule {
+	rules := slices.Clone(c.First.Rules)
+	for _, rule := range c.Second.Rules {
+		if !slices.Contains(rules, rule) {
+			rules = append(rules, rule)
+		}
+	}
+	slices.Sort(rules)
+	return rules
+}
+
+func (c *Comparison) Explain() string {
+	return explainRules(c.Rules())
+}
+
+func explainRules(rules []NormalizeRule) string {
+	names := make([]string, len(rules))
+	for i, rule := range rules {
+		names[i] = rule.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+type StreamNormalizer struct {
+	options NormalizerOptions
+}
 
 func NewStreamNormalizer() *StreamNormalizer {
-	return &StreamNormalizer{}
+	return NewStreamNormalizerWithOptions(DefaultNormalizerOptions())
 }
 
-func (sn *StreamNormalizer) Normalize(uri string) (string, error) {
-	uri = strings.TrimSpace(uri)
+func NewStreamNormalizerWithOptions(options NormalizerOptions) *StreamNormalizer {
+	return &StreamNormalizer{options: options}
+}
 
-	parsedURL, err := url.Parse(uri)
+func (sn *StreamNormalizer) Options() NormalizerOptions {
+	return sn.options
+}
+
+func (sn *StreamNormalizer) Normalize(uri string) (string, error) {
+	n, err := sn.Canonicalize(uri)
 	if err != nil {
 		return "", err
 	}
+	return n.URI, nil
+}
 
-	parsedURL.Scheme = strings.ToLower(parsedURL.Scheme)
+// Canonicalize приводит URI к каноническому виду и сообщает, какие правила
+// сработали. Повторная канонизация результата ничего не меняет.
+func (sn *StreamNormalizer) Canonicalize(uri string) (*Normalization, error) {
+	return canonicalize(uri, sn.options)
+}
+
+// AreIdentical сообщает, что URI указывают на один поток. Учётные данные
+// при сравнении не учитываются.
+func (sn *StreamNormalizer) AreIdentical(uri1, uri2 string) (bool, error) {
+	comparison, err := sn.Compare(uri1, uri2)
+	if err != nil {
+		return false, err
+	}
+	return comparison.Identical, nil
+}
 
-	if parsedURL.User != nil {
-		username := parsedURL.User.Username()
-		password, hasPassword := parsedURL.User.Password()
-		if hasPassword {
-			parsedURL.User = url.UserPassword(strings.ToLower(username), strings.ToLower(password))
-		} else {
-			parsedURL.User = url.User(strings.ToLower(username))
+func (sn *StreamNormalizer) Compare(uri1, uri2 string) (*Comparison, error) {
+	options := sn.options
+	options.KeepCredentials = false
+
+	first, err := canonicalize(uri1, options)
+	if err != nil {
+		return nil, err
+	}
+
+	second, err := canonicalize(uri2, options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Comparison{
+		Identical: first.URI == second.URI,
+		First:     first,
+		Second:    second,
+	}, nil
+}
+
+func canonicalize(uri string, options NormalizerOptions) (*Normalization, error) {
+	n := &Normalization{}
+
+	trimmed := strings.TrimSpace(uri)
+	if trimmed != uri {
+		n.fire(RuleTrimSpace)
+	}
+
+	parsedURL, err := url.Parse(trimmed)
+	if err != nil {
+		return nil, err
+	}
+
+	// url.Parse сам приводит схему к нижнему регистру, поэтому сравнение
+	// идёт с исходной строкой
+	if parsedURL.Scheme != "" && !strings.HasPrefix(trimmed, parsedURL.Scheme+":") {
+		n.fire(RuleLowercaseScheme)
+	}
+
+	if parsedURL.User != nil && !options.KeepCredentials {
+		parsedURL.User = nil
+		n.fire(RuleStripCredentials)
+	}
+
+	if parsedURL.Host != "" {
+		canonicalizeHost(parsedURL, n)
+	}
+
+	if parsedURL.Opaque == "" {
+		rawPath := canonicalizePath(parsedURL.EscapedPath(), options, n)
+		parsedURL.Path, err = url.PathUnescape(rawPath)
+		if err != nil {
+			return nil, err
+		}
+		parsedURL.RawPath = rawPath
+	}
+
+	canonicalizeQuery(parsedURL, n)
+
+	if parsedURL.Fragment != "" || parsedURL.RawFragment != "" {
+		parsedURL.Fragment, parsedURL.RawFragment = "", ""
+		n.fire(RuleStripFragment)
+	}
+
+	// после отбрасывания фрагмента на краях могут остаться пробелы из
+	// непрозрачной части (a: #)
+	n.URI = parsedURL.String()
+	if uri := strings.TrimSpace(n.URI); uri != n.URI {
+		n.URI = uri
+		n.fire(RuleTrimSpace)
+	}
+
+	// net/url экранирует байты вне ASCII в зоне IPv6 так, что результат
+	// уже не разбирается; такой URI считается некорректным
+	if _, err := url.Parse(n.URI); err != nil {
+		return nil, err
+	}
+
+	return n, nil
+}
+
+func canonicalizeHost(parsedURL *url.URL, n *Normalization) {
+	host := strings.ToLower(parsedURL.Hostname())
+	if addr, err := netip.ParseAddr(host); err == nil && addr.Is6() {
+		host = addr.String()
+	}
+
+	port := parsedURL.Port()
+	if number, err := strconv.Atoi(port); err == nil {
+		port = strconv.Itoa(number)
+	}
+
+	canonical := host
+	if strings.Contains(host, ":") {
+		canonical = "[" + host + "]"
+	}
+	if port != "" {
+		canonical = net.JoinHostPort(host, port)
+	}
+	if canonical != strings.TrimSuffix(parsedURL.Host, ":") {
+		n.fire(RuleCanonicalHost)
+	}
+
+	if port == "" {
+		if defaultPort, ok := defaultPorts[parsedURL.Scheme]; ok {
+			canonical = net.JoinHostPort(host, defaultPort)
+			n.fire(RuleDefaultPort)
+		}
+	}
+
+	parsedURL.Host = canonical
+}
+
+// canonicalizePath работает с экранированным путём, чтобы %2F внутри
+// сегмента не превратился в разделитель.
+func canonicalizePath(rawPath string, options NormalizerOptions, n *Normalization) string {
+	rawPath = normalizePercentEncoding(rawPath, n)
+	// путь "*" (OPTIONS *) net/url всегда печатает без экранирования
+	if rawPath == "%2A" {
+		rawPath = "*"
+		n.fire(RulePercentEncoding)
+	}
+
+	if options.CaseInsensitivePath {
+		if lower := lowerOutsideEscapes(rawPath); lower != rawPath {
+			rawPath = lower
+			n.fire(RuleLowercasePath)
 		}
 	}
 
-	parsedURL.Host = strings.ToLower(parsedURL.Host)
+	if trimmed := strings.TrimRight(rawPath, "/"); trimmed != rawPath {
+		rawPath = trimmed
+		n.fire(RuleTrailingSlash)
+	}
+
+	// относительный путь бывает только у URI без схемы и хоста, его
+	// точечные сегменты net/url восстанавливает при сериализации
+	if strings.HasPrefix(rawPath, "/") {
+		if cleaned := path.Clean(rawPath); cleaned != rawPath {
+			rawPath = cleaned
+			n.fire(RuleCleanPath)
+		}
+		if rawPath == "/" {
+			rawPath = ""
+			n.fire(RuleTrailingSlash)
+		}
+	}
+
+	for !options.KeepTrackID {
+		loc := trackIDPattern.FindStringIndex(rawPath)
+		if loc == nil {
+			break
+		}
+		rawPath = rawPath[:loc[0]]
+		n.fire(RuleStripTrackID)
+	}
+
+	return rawPath
+}
 
-	parsedURL.Path = strings.ToLower(strings.TrimSuffix(parsedURL.Path, "/"))
+// normalizePercentEncoding раскодирует незарезервированные символы
+// (RFC 3986, 2.3) и приводит шестнадцатеричные цифры к верхнему регистру.
+func normalizePercentEncoding(s string, n *Normalization) string {
+	var b strings.Builder
+	b.Grow(len(s))
 
-	if parsedURL.RawQuery != "" {
-		query := parsedURL.Query()
-		keys := make([]string, 0, len(query))
-		for k := range query {
-			keys = append(keys, k)
+	for i := 0; i < len(s); i++ {
+		if s[i] != '%' || i+2 >= len(s) || !isHex(s[i+1]) || !isHex(s[i+2]) {
+			b.WriteByte(s[i])
+			continue
 		}
-		sort.Strings(keys)
 
-		normalizedQuery := url.Values{}
-		for _, k := range keys {
-			normalizedQuery[k] = query[k]
+		value, _ := strconv.ParseUint(s[i+1:i+3], 16, 8)
+		escaped := "%" + strings.ToUpper(s[i+1:i+3])
+		switch {
+		case isUnreserved(byte(value)):
+			b.WriteByte(byte(value))
+			n.fire(RulePercentEncoding)
+		case escaped != s[i:i+3]:
+			b.WriteString(escaped)
+			n.fire(RulePercentEncoding)
+		default:
+			b.WriteString(escaped)
 		}
-		parsedURL.RawQuery = normalizedQuery.Encode()
+		i += 2
 	}
 
-	return parsedURL.String(), nil
+	return b.String()
 }
 
-func (sn *StreamNormalizer) AreIdentical(uri1, uri2 string) (bool, error) {
-	normalized1, err := sn.Normalize(uri1)
-	if err != nil {
-		return false, err
+// lowerOutsideEscapes переводит латиницу в нижний регистр, не трогая
+// шестнадцатеричные цифры экранированных байтов.
+func lowerOutsideEscapes(s string) string {
+	b := []byte(s)
+	for i := 0; i < len(b); i++ {
+		if b[i] == '%' {
+			i += 2
+			continue
+		}
+		if b[i] >= 'A' && b[i] <= 'Z' {
+			b[i] += 'a' - 'A'
+		}
 	}
+	return string(b)
+}
 
-	normalized2, err := sn.Normalize(uri2)
-	if err != nil {
-		return false, err
+func canonicalizeQuery(parsedURL *url.URL, n *Normalization) {
+	if parsedURL.ForceQuery && parsedURL.RawQuery == "" {
+		parsedURL.ForceQuery = false
+		n.fire(RuleSortQuery)
+	}
+	if parsedURL.RawQuery == "" {
+		return
+	}
+
+	// Encode упорядочивает ключи, порядок повторяющихся значений сохраняется
+	encoded := parsedURL.Query().Encode()
+	if encoded != parsedURL.RawQuery {
+		parsedURL.RawQuery = encoded
+		n.fire(RuleSortQuery)
 	}
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
 
-	return normalized1 == normalized2, nil
+func isUnreserved(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
 }
@@ -0,0 +1,139 @@
+package domain
+
+import (
+	"fmt"
+	"ip-camera-viewer/internal/domain/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PlaceholderContext — данные, доступные плейсхолдерам при подстановке.
+type PlaceholderContext struct {
+	Config *model.ConnectionConfig
+	// StreamIndex — 0 для основного (High) потока, 1 для дополнительного (Low).
+	StreamIndex int
+	// Time используется плейсхолдером {date:...}, например для начала записи.
+	Time time.Time
+}
+
+func (pc *PlaceholderContext) variable(name string) string {
+	if pc.Config == nil || pc.Config.Variables == nil {
+		return ""
+	}
+	return pc.Config.Variables[name]
+}
+
+// PlaceholderProvider возвращает значение плейсхолдера. Пустая строка
+// означает отсутствие значения: тогда используется значение по умолчанию.
+type PlaceholderProvider func(ctx *PlaceholderContext, arg string) (string, error)
+
+type PlaceholderRegistry struct {
+	providers map[string]PlaceholderProvider
+}
+
+func NewPlaceholderRegistry() *PlaceholderRegistry {
+	r := &PlaceholderRegistry{
+		providers: make(map[string]PlaceholderProvider),
+	}
+
+	r.Register("login", func(ctx *PlaceholderContext, _ string) (string, error) {
+		return ctx.Config.Login, nil
+	})
+	r.Register("password", func(ctx *PlaceholderContext, _ string) (string, error) {
+		return ctx.Config.Password, nil
+	})
+	r.Register("ip", func(ctx *PlaceholderContext, _ string) (string, error) {
+		return FormatHost(ctx.Config.IP), nil
+	})
+	r.Register("port", func(ctx *PlaceholderContext, _ string) (string, error) {
+		if ctx.Config.Port <= 0 {
+			return "", nil
+		}
+		return strconv.Itoa(ctx.Config.Port), nil
+	})
+	r.Register("channel", func(ctx *PlaceholderContext, _ string) (string, error) {
+		if channel := ctx.variable("channel"); channel != "" {
+			return channel, nil
+		}
+		return "1", nil
+	})
+	r.Register("stream", func(ctx *PlaceholderContext, _ string) (string, error) {
+		if stream := ctx.variable("stream"); stream != "" {
+			return stream, nil
+		}
+		return strconv.Itoa(ctx.StreamIndex), nil
+	})
+	r.Register("profile", func(ctx *PlaceholderContext, _ string) (string, error) {
+		return ctx.variable("profile"), nil
+	})
+	r.Register("date", func(ctx *PlaceholderContext, arg string) (string, error) {
+		if arg == "" {
+			return "", fmt.Errorf("для {date} нужен формат, например {date:YYYYMMDDThhmmssZ}")
+		}
+		t := ctx.Time
+		if t.IsZero() {
+			t = time.Now()
+		}
+		return FormatDate(t, arg), nil
+	})
+
+	return r
+}
+
+func (r *PlaceholderRegistry) Register(name string, provider PlaceholderProvider) {
+	r.providers[name] = provider
+}
+
+func (r *PlaceholderRegistry) Lookup(name string) (PlaceholderProvider, bool) {
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+func (r *PlaceholderRegistry) Names() []string {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// FormatDate форматирует время по шаблону из токенов YYYY, MM, DD, hh, mm, ss;
+// остальные символы выводятся как есть. Если шаблон заканчивается на Z,
+// время приводится к UTC.
+func FormatDate(t time.Time, layout string) string {
+	if strings.HasSuffix(layout, "Z") {
+		t = t.UTC()
+	}
+
+	tokens := []struct {
+		token string
+		value string
+	}{
+		{"YYYY", fmt.Sprintf("%04d", t.Year())},
+		{"MM", fmt.Sprintf("%02d", int(t.Month()))},
+		{"DD", fmt.Sprintf("%02d", t.Day())},
+		{"hh", fmt.Sprintf("%02d", t.Hour())},
+		{"mm", fmt.Sprintf("%02d", t.Minute())},
+		{"ss", fmt.Sprintf("%02d", t.Second())},
+	}
+
+	var result strings.Builder
+	for i := 0; i < len(layout); {
+		matched := false
+		for _, tok := range tokens {
+			if strings.HasPrefix(layout[i:], tok.token) {
+				result.WriteString(tok.value)
+				i += len(tok.token)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			result.WriteByte(layout[i])
+			i++
+		}
+	}
+
+	return result.String()
+}
@@ -0,0 +1,35 @@
+package domain
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+)
+
+//go:embed presets.json
+var presetsJSON []byte
+
+// VendorPreset — шаблоны основного и дополнительного потоков производителя
+// в синтаксисе TemplateResolver.
+type VendorPreset struct {
+	Vendor string `json:"vendor"`
+	Main   string `json:"main"`
+	Sub    string `json:"sub"`
+}
+
+func LoadVendorPresets() ([]VendorPreset, error) {
+	var presets []VendorPreset
+	if err := json.Unmarshal(presetsJSON, &presets); err != nil {
+		return nil, fmt.Errorf("ошибка чтения каталога производителей: %w", err)
+	}
+	return presets, nil
+}
+
+func FindVendorPreset(presets []VendorPreset, vendor string) (VendorPreset, bool) {
+	for _, preset := range presets {
+		if preset.Vendor == vendor {
+			return preset, true
+		}
+	}
+	return VendorPreset{}, false
+}
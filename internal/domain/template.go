@@ -3,29 +3,307 @@ package domain
 import (
 	"fmt"
 	"ip-camera-viewer/internal/domain/model"
+	"net/netip"
+	"net/url"
+	"sort"
 	"strings"
+	"unicode/utf8"
 )
 
-type TemplateResolver struct{}
+type TemplateResolver struct {
+	registry *PlaceholderRegistry
+}
 
 func NewTemplateResolver() *TemplateResolver {
-	return &TemplateResolver{}
+	return &TemplateResolver{
+		registry: NewPlaceholderRegistry(),
+	}
+}
+
+func (tr *TemplateResolver) Registry() *PlaceholderRegistry {
+	return tr.registry
+}
+
+type TemplateError struct {
+	Column      int
+	Placeholder string
+	Message     string
+}
+
+func (e *TemplateError) Error() string {
+	return fmt.Sprintf("%s: %s (позиция %d)", e.Placeholder, e.Message, e.Column)
+}
+
+type placeholder struct {
+	start      int
+	end        int
+	name       string
+	arg        string
+	def        string
+	hasDefault bool
+}
+
+func (p placeholder) raw(template string) string {
+	return template[p.start:p.end]
+}
+
+// parsePlaceholders разбирает плейсхолдеры вида {name}, {name:arg},
+// {name|default} и {name:arg|default}.
+func parsePlaceholders(template string) ([]placeholder, []*TemplateError) {
+	var placeholders []placeholder
+	var errs []*TemplateError
+
+	column := func(pos int) int {
+		return utf8.RuneCountInString(template[:pos]) + 1
+	}
+
+	for i := 0; i < len(template); i++ {
+		switch template[i] {
+		case '}':
+			errs = append(errs, &TemplateError{Column: column(i), Placeholder: "}", Message: "лишняя закрывающая скобка"})
+
+		case '{':
+			end := strings.IndexAny(template[i+1:], "{}")
+			if end == -1 || template[i+1+end] == '{' {
+				errs = append(errs, &TemplateError{Column: column(i), Placeholder: "{", Message: "незакрытый плейсхолдер"})
+				continue
+			}
+			end += i + 1
+
+			p := placeholder{start: i, end: end + 1}
+			body := template[i+1 : end]
+			body, p.def, p.hasDefault = strings.Cut(body, "|")
+			p.name, p.arg, _ = strings.Cut(body, ":")
+
+			if !IsValidPlaceholderName(p.name) {
+				errs = append(errs, &TemplateError{Column: column(i), Placeholder: p.raw(template), Message: "некорректное имя плейсхолдера"})
+			} else {
+				placeholders = append(placeholders, p)
+			}
+			i = end
+		}
+	}
+
+	return placeholders, errs
+}
+
+func IsValidPlaceholderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		isLetter := r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '_'
+		if !isLetter && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func (tr *TemplateResolver) value(p placeholder, ctx *PlaceholderContext) (string, bool, error) {
+	var value string
+	provider, known := tr.registry.Lookup(p.name)
+	if known {
+		var err error
+		value, err = provider(ctx, p.arg)
+		if err != nil {
+			return "", true, err
+		}
+	} else if ctx.Config != nil && ctx.Config.Variables != nil {
+		value, known = ctx.Config.Variables[p.name]
+	}
+
+	if value == "" && p.hasDefault {
+		value = p.def
+	}
+	return value, known, nil
 }
 
 func (tr *TemplateResolver) Resolve(template string, config *model.ConnectionConfig) string {
-	result := template
+	return tr.ResolveContext(template, &PlaceholderContext{Config: config})
+}
+
+// ResolveContext подставляет значения плейсхолдеров. Известный плейсхолдер
+// без значения заменяется пустой строкой (пустой пароль — допустимое
+// значение), неизвестные и ошибочные остаются в строке как есть — их
+// находит Validate.
+func (tr *TemplateResolver) ResolveContext(template string, ctx *PlaceholderContext) string {
+	placeholders, _ := parsePlaceholders(template)
+	layout := newTemplateLayout(template)
+
+	var result strings.Builder
+	last := 0
+	for _, p := range placeholders {
+		value, known, err := tr.value(p, ctx)
+		if !known || err != nil {
+			continue
+		}
+
+		result.WriteString(template[last:p.start])
+		result.WriteString(escapeValue(value, layout.componentAt(p.start)))
+		last = p.end
+	}
+	result.WriteString(template[last:])
+
+	return result.String()
+}
+
+// ResolveSample подставляет примерные значения во все синтаксически
+// корректные плейсхолдеры, чтобы проверить структуру URI без конфигурации.
+func (tr *TemplateResolver) ResolveSample(template string) string {
+	sample := &model.ConnectionConfig{
+		IP:       "192.168.1.1",
+		Port:     554,
+		Login:    "user",
+		Password: "pass",
+	}
+	ctx := &PlaceholderContext{Config: sample}
+
+	placeholders, _ := parsePlaceholders(template)
+
+	var result strings.Builder
+	last := 0
+	for _, p := range placeholders {
+		value, known, err := tr.value(p, ctx)
+		if !known || err != nil || value == "" {
+			value = "x"
+		}
+
+		result.WriteString(template[last:p.start])
+		result.WriteString(value)
+		last = p.end
+	}
+	result.WriteString(template[last:])
+
+	return result.String()
+}
+
+// Validate возвращает ошибки шаблона с позициями: синтаксические,
+// неизвестные плейсхолдеры и плейсхолдеры без значения.
+func (tr *TemplateResolver) Validate(template string, ctx *PlaceholderContext) []*TemplateError {
+	placeholders, errs := parsePlaceholders(template)
+
+	for _, p := range placeholders {
+		column := utf8.RuneCountInString(template[:p.start]) + 1
+
+		value, known, err := tr.value(p, ctx)
+		switch {
+		case !known:
+			errs = append(errs, &TemplateError{Column: column, Placeholder: p.raw(template), Message: "неизвестный плейсхолдер"})
+		case err != nil:
+			errs = append(errs, &TemplateError{Column: column, Placeholder: p.raw(template), Message: err.Error()})
+		case value == "" && !p.hasDefault && p.name != "password" && p.name != "login":
+			errs = append(errs, &TemplateError{Column: column, Placeholder: p.raw(template), Message: "значение не задано"})
+		}
+	}
+
+	sort.Slice(errs, func(i, j int) bool {
+		return errs[i].Column < errs[j].Column
+	})
+	return errs
+}
+
+type uriComponent int
 
-	result = strings.ReplaceAll(result, "{login}", config.Login)
-	result = strings.ReplaceAll(result, "{password}", config.Password)
-	result = strings.ReplaceAll(result, "{ip}", config.IP)
-	result = strings.ReplaceAll(result, "{port}", fmt.Sprintf("%d", config.Port))
+const (
+	componentScheme uriComponent = iota
+	componentUserInfo
+	componentHost
+	componentPath
+	componentQuery
+	componentFragment
+)
 
-	return result
+// templateLayout хранит границы частей URI, определённые по шаблону
+// до подстановки, чтобы значения с '@', '/' или '#' не сдвигали их.
+type templateLayout struct {
+	authorityStart int
+	userInfoEnd    int
+	authorityEnd   int
+	queryStart     int
+	fragmentStart  int
+}
+
+func newTemplateLayout(template string) templateLayout {
+	layout := templateLayout{userInfoEnd: -1}
+
+	if schemeEnd := strings.Index(template, "://"); schemeEnd >= 0 {
+		layout.authorityStart = schemeEnd + 3
+		layout.authorityEnd = len(template)
+		if end := strings.IndexAny(template[layout.authorityStart:], "/?#"); end >= 0 {
+			layout.authorityEnd = layout.authorityStart + end
+		}
+		if at := strings.LastIndexByte(template[layout.authorityStart:layout.authorityEnd], '@'); at >= 0 {
+			layout.userInfoEnd = layout.authorityStart + at
+		}
+	}
+
+	layout.fragmentStart = len(template)
+	if hash := strings.IndexByte(template[layout.authorityEnd:], '#'); hash >= 0 {
+		layout.fragmentStart = layout.authorityEnd + hash
+	}
+
+	layout.queryStart = layout.fragmentStart
+	if question := strings.IndexByte(template[layout.authorityEnd:layout.fragmentStart], '?'); question >= 0 {
+		layout.queryStart = layout.authorityEnd + question
+	}
+
+	return layout
+}
+
+func (l templateLayout) componentAt(pos int) uriComponent {
+	switch {
+	case pos < l.authorityStart:
+		return componentScheme
+	case pos < l.authorityEnd:
+		if pos < l.userInfoEnd {
+			return componentUserInfo
+		}
+		return componentHost
+	case pos < l.queryStart:
+		return componentPath
+	case pos < l.fragmentStart:
+		return componentQuery
+	default:
+		return componentFragment
+	}
+}
+
+func escapeValue(value string, component uriComponent) string {
+	switch component {
+	case componentUserInfo:
+		return url.User(value).String()
+	case componentPath, componentFragment:
+		return url.PathEscape(value)
+	case componentQuery:
+		return url.QueryEscape(value)
+	default:
+		return value
+	}
+}
+
+// FormatHost подготавливает адрес для подстановки в URI: IPv6 заключается
+// в квадратные скобки, а идентификатор зоны экранируется как %25.
+func FormatHost(host string) string {
+	host = strings.TrimSpace(host)
+	if strings.HasPrefix(host, "[") {
+		return host
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !addr.Is6() {
+		return host
+	}
+
+	if zone := addr.Zone(); zone != "" {
+		return "[" + addr.WithZone("").String() + "%25" + zone + "]"
+	}
+	return "[" + addr.String() + "]"
 }
 
 func (tr *TemplateResolver) ResolveAll(config *model.ConnectionConfig) *model.ResolvedURIs {
-	uri1 := tr.Resolve(config.RTSPURI1, config)
-	uri2 := tr.Resolve(config.RTSPURI2, config)
+	uri1 := tr.ResolveContext(config.RTSPURI1, &PlaceholderContext{Config: config, StreamIndex: 0})
+	uri2 := tr.ResolveContext(config.RTSPURI2, &PlaceholderContext{Config: config, StreamIndex: 1})
 
 	return &model.ResolvedURIs{
 		URI1:       uri1,
@@ -36,33 +314,69 @@ func (tr *TemplateResolver) ResolveAll(config *model.ConnectionConfig) *model.Re
 }
 
 func (tr *TemplateResolver) MaskPassword(uri string) string {
+	parsedURL, err := url.Parse(uri)
+	if err != nil || parsedURL.Host == "" {
+		return maskUserInfo(uri)
+	}
 
-	if !strings.Contains(uri, "://") {
-		return uri
+	masked := false
+
+	if parsedURL.RawQuery != "" {
+		pairs := strings.Split(parsedURL.RawQuery, "&")
+		for i, pair := range pairs {
+			key, _, _ := strings.Cut(pair, "=")
+			if unescaped, err := url.QueryUnescape(key); err == nil && isPasswordParam(unescaped) {
+				pairs[i] = key + "=***"
+				masked = true
+			}
+		}
+		parsedURL.RawQuery = strings.Join(pairs, "&")
 	}
 
-	parts := strings.SplitN(uri, "://", 2)
-	if len(parts) != 2 {
+	hasPassword := false
+	if parsedURL.User != nil {
+		_, hasPassword = parsedURL.User.Password()
+	}
+
+	if !hasPassword {
+		if masked {
+			return parsedURL.String()
+		}
 		return uri
 	}
 
-	scheme := parts[0]
-	rest := parts[1]
+	// url.UserPassword экранирует '*', поэтому маска вставляется вручную
+	parsedURL.User = url.User(parsedURL.User.Username())
+	result := parsedURL.String()
+	atIndex := strings.Index(result, "@")
+	return result[:atIndex] + ":***" + result[atIndex:]
+}
 
-	atIndex := strings.Index(rest, "@")
-	if atIndex == -1 {
+func isPasswordParam(key string) bool {
+	switch strings.ToLower(key) {
+	case "password", "pwd", "pass", "passwd":
+		return true
+	}
+	return false
+}
+
+// maskUserInfo маскирует пароль в строке, которую не удалось разобрать как URL.
+func maskUserInfo(uri string) string {
+	schemeEnd := strings.Index(uri, "://")
+	if schemeEnd == -1 {
 		return uri
 	}
 
-	authPart := rest[:atIndex]
-	hostPart := rest[atIndex:]
+	rest := uri[schemeEnd+3:]
+	atIndex := strings.LastIndex(rest, "@")
+	if atIndex == -1 {
+		return uri
+	}
 
-	colonIndex := strings.Index(authPart, ":")
+	colonIndex := strings.Index(rest[:atIndex], ":")
 	if colonIndex == -1 {
 		return uri
 	}
 
-	login := authPart[:colonIndex]
-
-	return fmt.Sprintf("%s://%s:***%s", scheme, login, hostPart)
+	return uri[:schemeEnd+3] + rest[:colonIndex] + ":***" + rest[atIndex:]
 }
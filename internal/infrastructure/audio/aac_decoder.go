@@ -0,0 +1,152 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"runtime"
+	"unsafe"
+)
+
+// #cgo pkg-config: libavcodec libavutil
+// #include <libavcodec/avcodec.h>
+// #include <libavutil/samplefmt.h>
+import "C"
+
+// AACDecoder is a wrapper around FFmpeg's AAC decoder.
+type AACDecoder struct {
+	codecCtx *C.AVCodecContext
+	frame    *C.AVFrame
+}
+
+// Initialize initializes the decoder with a MPEG-4 AudioSpecificConfig.
+func (d *AACDecoder) Initialize(config []byte) error {
+	codec := C.avcodec_find_decoder(C.AV_CODEC_ID_AAC)
+	if codec == nil {
+		return fmt.Errorf("avcodec_find_decoder() failed")
+	}
+
+	d.codecCtx = C.avcodec_alloc_context3(codec)
+	if d.codecCtx == nil {
+		return fmt.Errorf("avcodec_alloc_context3() failed")
+	}
+
+	if len(config) > 0 {
+		extradata := C.av_mallocz(C.size_t(len(config) + C.AV_INPUT_BUFFER_PADDING_SIZE))
+		if extradata == nil {
+			C.avcodec_free_context(&d.codecCtx)
+			return fmt.Errorf("av_mallocz() failed")
+		}
+		copy(unsafe.Slice((*byte)(extradata), len(config)), config)
+		d.codecCtx.extradata = (*C.uint8_t)(extradata)
+		d.codecCtx.extradata_size = C.int(len(config))
+	}
+
+	res := C.avcodec_open2(d.codecCtx, codec, nil)
+	if res < 0 {
+		C.avcodec_free_context(&d.codecCtx)
+		return fmt.Errorf("avcodec_open2() failed")
+	}
+
+	d.frame = C.av_frame_alloc()
+	if d.frame == nil {
+		C.avcodec_free_context(&d.codecCtx)
+		return fmt.Errorf("av_frame_alloc() failed")
+	}
+
+	return nil
+}
+
+// Close closes the decoder.
+func (d *AACDecoder) Close() {
+	if d.frame != nil {
+		C.av_frame_free(&d.frame)
+	}
+	if d.codecCtx != nil {
+		C.avcodec_free_context(&d.codecCtx)
+	}
+}
+
+// Decode decodes an AAC access unit into interleaved 16-bit PCM.
+func (d *AACDecoder) Decode(au []byte) ([]int16, Format, error) {
+	if len(au) == 0 {
+		return nil, Format{}, nil
+	}
+
+	// send access unit to decoder
+	var pkt C.AVPacket
+	ptr := &au[0]
+	var p runtime.Pinner
+	p.Pin(ptr)
+	pkt.data = (*C.uint8_t)(ptr)
+	pkt.size = (C.int)(len(au))
+	res := C.avcodec_send_packet(d.codecCtx, &pkt)
+	p.Unpin()
+	if res < 0 {
+		return nil, Format{}, nil
+	}
+
+	// receive all available frames
+	var samples []int16
+	var format Format
+	for {
+		res = C.avcodec_receive_frame(d.codecCtx, d.frame)
+		if res < 0 {
+			break
+		}
+
+		format = Format{
+			SampleRate: int(d.frame.sample_rate),
+			Channels:   int(d.frame.ch_layout.nb_channels),
+		}
+
+		converted, err := d.convertFrame(format.Channels)
+		if err != nil {
+			return nil, format, err
+		}
+		samples = append(samples, converted...)
+	}
+
+	return samples, format, nil
+}
+
+func (d *AACDecoder) convertFrame(channels int) ([]int16, error) {
+	n := int(d.frame.nb_samples)
+	out := make([]int16, n*channels)
+	planes := unsafe.Slice(d.frame.extended_data, channels)
+
+	switch int32(d.frame.format) {
+	case int32(C.AV_SAMPLE_FMT_FLTP):
+		for ch := 0; ch < channels; ch++ {
+			plane := unsafe.Slice((*float32)(unsafe.Pointer(planes[ch])), n)
+			for i, v := range plane {
+				out[i*channels+ch] = floatToS16(v)
+			}
+		}
+
+	case int32(C.AV_SAMPLE_FMT_FLT):
+		data := unsafe.Slice((*float32)(unsafe.Pointer(planes[0])), n*channels)
+		for i, v := range data {
+			out[i] = floatToS16(v)
+		}
+
+	case int32(C.AV_SAMPLE_FMT_S16P):
+		for ch := 0; ch < channels; ch++ {
+			plane := unsafe.Slice((*int16)(unsafe.Pointer(planes[ch])), n)
+			for i, v := range plane {
+				out[i*channels+ch] = v
+			}
+		}
+
+	case int32(C.AV_SAMPLE_FMT_S16):
+		copy(out, unsafe.Slice((*int16)(unsafe.Pointer(planes[0])), n*channels))
+
+	default:
+		return nil, fmt.Errorf("unsupported sample format %d", int(d.frame.format))
+	}
+
+	return out, nil
+}
+
+func floatToS16(v float32) int16 {
+	return int16(math.Max(-32768, math.Min(32767, float64(v)*32767)))
+}
@@ -0,0 +1,47 @@
+package audio
+
+// Convert приводит PCM к нужной частоте дискретизации и числу каналов.
+// Каналы сводятся в моно, частота меняется линейной интерполяцией.
+func Convert(samples []int16, from, to Format) []int16 {
+	if from == to {
+		return samples
+	}
+
+	mono := samples
+	if from.Channels > 1 {
+		mono = make([]int16, len(samples)/from.Channels)
+		for i := range mono {
+			var sum int
+			for ch := 0; ch < from.Channels; ch++ {
+				sum += int(samples[i*from.Channels+ch])
+			}
+			mono[i] = int16(sum / from.Channels)
+		}
+	}
+
+	resampled := mono
+	if from.SampleRate != to.SampleRate && len(mono) > 0 {
+		n := len(mono) * to.SampleRate / from.SampleRate
+		resampled = make([]int16, n)
+		ratio := float64(from.SampleRate) / float64(to.SampleRate)
+		for i := range resampled {
+			pos := float64(i) * ratio
+			idx := int(pos)
+			next := min(idx+1, len(mono)-1)
+			frac := pos - float64(idx)
+			resampled[i] = int16(float64(mono[idx])*(1-frac) + float64(mono[next])*frac)
+		}
+	}
+
+	if to.Channels <= 1 {
+		return resampled
+	}
+
+	out := make([]int16, len(resampled)*to.Channels)
+	for i, s := range resampled {
+		for ch := 0; ch < to.Channels; ch++ {
+			out[i*to.Channels+ch] = s
+		}
+	}
+	return out
+}
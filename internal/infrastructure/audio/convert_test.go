@@ -0,0 +1,35 @@
+package audio
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestConvert(t *testing.T) {
+	mono8k := Format{SampleRate: 8000, Channels: 1}
+	mono16k := Format{SampleRate: 16000, Channels: 1}
+	stereo8k := Format{SampleRate: 8000, Channels: 2}
+
+	tests := []struct {
+		name     string
+		samples  []int16
+		from, to Format
+		want     []int16
+	}{
+		{"тот же формат", []int16{1, -2, 3}, mono8k, mono8k, []int16{1, -2, 3}},
+		{"стерео в моно", []int16{100, 300, -100, -300}, stereo8k, mono8k, []int16{200, -200}},
+		{"моно в стерео", []int16{5, -7}, mono8k, stereo8k, []int16{5, 5, -7, -7}},
+		{"понижение частоты", []int16{0, 10, 20, 30}, mono16k, mono8k, []int16{0, 20}},
+		{"повышение частоты", []int16{0, 100, 200}, mono8k, mono16k, []int16{0, 50, 100, 150, 200, 200}},
+		{"пусто", nil, mono16k, stereo8k, []int16{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Convert(tt.samples, tt.from, tt.to)
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("получено %v, ожидалось %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/gen2brain/malgo"
+)
+
+// device — звуковая карта по умолчанию через miniaudio. У каждого потока
+// своё устройство, поэтому потоки с разным форматом звучат одновременно,
+// а преобразование частоты берёт на себя miniaudio.
+type device struct {
+	ctx    *malgo.AllocatedContext
+	device *malgo.Device
+}
+
+func openDevice(kind malgo.DeviceType, format Format, onData malgo.DataProc) (*device, error) {
+	if format.SampleRate <= 0 || format.Channels <= 0 {
+		return nil, fmt.Errorf("некорректный формат аудио: %d Гц, %d каналов", format.SampleRate, format.Channels)
+	}
+
+	ctx, err := malgo.InitContext(nil, malgo.ContextConfig{}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("звуковая подсистема недоступна: %w", err)
+	}
+
+	config := malgo.DefaultDeviceConfig(kind)
+	config.SampleRate = uint32(format.SampleRate)
+	config.Playback.Format = malgo.FormatS16
+	config.Playback.Channels = uint32(format.Channels)
+	config.Capture.Format = malgo.FormatS16
+	config.Capture.Channels = uint32(format.Channels)
+
+	dev, err := malgo.InitDevice(ctx.Context, config, malgo.DeviceCallbacks{Data: onData})
+	if err != nil {
+		ctx.Uninit()
+		ctx.Free()
+		return nil, fmt.Errorf("ошибка открытия звукового устройства: %w", err)
+	}
+
+	if err := dev.Start(); err != nil {
+		dev.Uninit()
+		ctx.Uninit()
+		ctx.Free()
+		return nil, fmt.Errorf("ошибка запуска звукового устройства: %w", err)
+	}
+
+	return &device{ctx: ctx, device: dev}, nil
+}
+
+func (d *device) close() {
+	d.device.Uninit()
+	d.ctx.Uninit()
+	d.ctx.Free()
+}
+
+// DeviceSink играет звук потока на устройстве вывода по умолчанию.
+// Камера и звуковая карта тактируются независимо, поэтому очередь
+// ограничена полсекундой: лишнее отбрасывается с начала, чтобы звук не
+// отставал от видео, а при нехватке карта получает тишину.
+type DeviceSink struct {
+	device *device
+
+	mu    sync.Mutex
+	queue []int16
+	limit int
+}
+
+// NewDeviceSink открывает устройство вывода. Подходит как SinkFactory.
+func NewDeviceSink(_ string, format Format) (Sink, error) {
+	s := &DeviceSink{
+		limit: format.SampleRate / 2 * format.Channels,
+	}
+
+	dev, err := openDevice(malgo.Playback, format, s.play)
+	if err != nil {
+		return nil, err
+	}
+	s.device = dev
+
+	return s, nil
+}
+
+func (s *DeviceSink) Write(samples []int16) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.queue = append(s.queue, samples...)
+	if over := len(s.queue) - s.limit; over > 0 {
+		s.queue = s.queue[over:]
+	}
+	return nil
+}
+
+// play вызывается из потока miniaudio и заполняет буфер устройства.
+func (s *DeviceSink) play(output, _ []byte, _ uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := min(len(output)/2, len(s.queue))
+	for i, sample := range s.queue[:n] {
+		binary.NativeEndian.PutUint16(output[2*i:], uint16(sample))
+	}
+	clear(output[2*n:])
+	s.queue = s.queue[n:]
+}
+
+func (s *DeviceSink) Close() error {
+	s.device.close()
+	return nil
+}
+
+// DeviceSource захватывает звук с микрофона по умолчанию в формате
+// обратного канала камер (8 кГц, моно), чтобы talkLoop не пересчитывал
+// частоту. Read отдаёт накопленное без ожидания; если говорящий не успевает
+// забрать данные, в очереди остаётся последняя полсекунда.
+type DeviceSource struct {
+	device *device
+	format Format
+
+	mu    sync.Mutex
+	queue []int16
+	limit int
+}
+
+// NewDeviceSource открывает микрофон. Подходит как SourceFactory.
+func NewDeviceSource() (Source, error) {
+	format := Format{SampleRate: 8000, Channels: 1}
+	s := &DeviceSource{
+		format: format,
+		limit:  format.SampleRate / 2 * format.Channels,
+	}
+
+	dev, err := openDevice(malgo.Capture, format, s.capture)
+	if err != nil {
+		return nil, err
+	}
+	s.device = dev
+
+	return s, nil
+}
+
+func (s *DeviceSource) Format() Format {
+	return s.format
+}
+
+// capture вызывается из потока miniaudio с новыми отсчётами микрофона.
+func (s *DeviceSource) capture(_, input []byte, _ uint32) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := 0; i+1 < len(input); i += 2 {
+		s.queue = append(s.queue, int16(binary.NativeEndian.Uint16(input[i:])))
+	}
+	if over := len(s.queue) - s.limit; over > 0 {
+		s.queue = s.queue[over:]
+	}
+}
+
+func (s *DeviceSource) Read(samples []int16) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := copy(samples, s.queue)
+	s.queue = s.queue[n:]
+	return n, nil
+}
+
+func (s *DeviceSource) Close() error {
+	s.device.close()
+	return nil
+}
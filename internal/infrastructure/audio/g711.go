@@ -0,0 +1,40 @@
+package audio
+
+import (
+	"encoding/binary"
+
+	"github.com/bluenviron/mediacommon/v2/pkg/codecs/g711"
+)
+
+// DecodeG711 декодирует µ-law или A-law в 16-битный PCM.
+func DecodeG711(payload []byte, muLaw bool) []int16 {
+	var lpcm []byte
+	if muLaw {
+		var dec g711.Mulaw
+		dec.Unmarshal(payload)
+		lpcm = dec
+	} else {
+		var dec g711.Alaw
+		dec.Unmarshal(payload)
+		lpcm = dec
+	}
+
+	samples := make([]int16, len(lpcm)/2)
+	for i := range samples {
+		samples[i] = int16(binary.BigEndian.Uint16(lpcm[2*i:]))
+	}
+	return samples
+}
+
+// EncodeG711 кодирует 16-битный PCM в µ-law или A-law.
+func EncodeG711(samples []int16, muLaw bool) ([]byte, error) {
+	lpcm := make([]byte, 2*len(samples))
+	for i, s := range samples {
+		binary.BigEndian.PutUint16(lpcm[2*i:], uint16(s))
+	}
+
+	if muLaw {
+		return g711.Mulaw(lpcm).Marshal()
+	}
+	return g711.Alaw(lpcm).Marshal()
+}
@@ -0,0 +1,79 @@
+package audio
+
+import "testing"
+
+func TestDecodeG711(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload []byte
+		muLaw   bool
+		want    []int16
+	}{
+		{"µ-law нули", []byte{0xff, 0x7f}, true, []int16{0, 0}},
+		{"µ-law максимум", []byte{0x80, 0x00}, true, []int16{32124, -32124}},
+		{"A-law минимальный шаг", []byte{0xd5, 0x55}, false, []int16{8, -8}},
+		{"A-law максимум", []byte{0xaa, 0x2a}, false, []int16{32256, -32256}},
+		{"пустой пакет", nil, true, []int16{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DecodeG711(tt.payload, tt.muLaw)
+			if len(got) != len(tt.want) {
+				t.Fatalf("получено %d отсчётов, ожидалось %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("отсчёт %d: получено %d, ожидалось %d", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeG711(t *testing.T) {
+	for _, muLaw := range []bool{true, false} {
+		// каждый код G.711 переживает декодирование и повторное кодирование;
+		// у µ-law два нуля, и отрицательный кодируется как положительный
+		codes := make([]byte, 256)
+		for i := range codes {
+			codes[i] = byte(i)
+		}
+		encoded, err := EncodeG711(DecodeG711(codes, muLaw), muLaw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, code := range encoded {
+			if code != codes[i] && !(muLaw && codes[i] == 0x7f && code == 0xff) {
+				t.Fatalf("µ-law=%v: код %#02x превратился в %#02x", muLaw, codes[i], code)
+			}
+		}
+
+		// ошибка квантования растёт с амплитудой: не больше 1/16 отсчёта и
+		// нескольких младших шагов у тихих отсчётов
+		samples := make([]int16, 0, 65536)
+		for v := -32768; v <= 32767; v++ {
+			samples = append(samples, int16(v))
+		}
+		encoded, err = EncodeG711(samples, muLaw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(encoded) != len(samples) {
+			t.Fatalf("µ-law=%v: %d байт на %d отсчётов", muLaw, len(encoded), len(samples))
+		}
+		for i, got := range DecodeG711(encoded, muLaw) {
+			want := int(samples[i])
+			if diff := abs(int(got) - want); diff > max(abs(want)/16, 36) {
+				t.Fatalf("µ-law=%v: %d декодирован как %d", muLaw, want, got)
+			}
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
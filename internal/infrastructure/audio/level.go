@@ -0,0 +1,28 @@
+package audio
+
+import "math"
+
+const SilenceDB = -90.0
+
+// Level возвращает пиковый и среднеквадратичный уровень в dBFS.
+func Level(samples []int16) (peak, rms float64) {
+	if len(samples) == 0 {
+		return SilenceDB, SilenceDB
+	}
+
+	var maxAbs, sum float64
+	for _, s := range samples {
+		v := math.Abs(float64(s)) / 32768
+		maxAbs = max(maxAbs, v)
+		sum += v * v
+	}
+
+	return toDB(maxAbs), toDB(math.Sqrt(sum / float64(len(samples))))
+}
+
+func toDB(v float64) float64 {
+	if v <= 0 {
+		return SilenceDB
+	}
+	return max(20*math.Log10(v), SilenceDB)
+}
@@ -0,0 +1,40 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLevel(t *testing.T) {
+	sine := make([]int16, 8000)
+	for i := range sine {
+		sine[i] = int16(32767 * math.Sin(2*math.Pi*float64(i)/80))
+	}
+
+	tests := []struct {
+		name     string
+		samples  []int16
+		peak     float64
+		rms      float64
+		accuracy float64
+	}{
+		{"пусто", nil, SilenceDB, SilenceDB, 0},
+		{"тишина", make([]int16, 160), SilenceDB, SilenceDB, 0},
+		{"полная шкала", []int16{-32768, -32768}, 0, 0, 0.001},
+		{"половина шкалы", []int16{16384, -16384}, -6.02, -6.02, 0.01},
+		{"синус", sine, 0, -3.01, 0.01},
+		{"младший бит", []int16{1}, -90, -90, 0.01},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			peak, rms := Level(tt.samples)
+			if math.Abs(peak-tt.peak) > tt.accuracy {
+				t.Errorf("пик %.3f дБ, ожидалось %.3f", peak, tt.peak)
+			}
+			if math.Abs(rms-tt.rms) > tt.accuracy {
+				t.Errorf("RMS %.3f дБ, ожидалось %.3f", rms, tt.rms)
+			}
+		})
+	}
+}
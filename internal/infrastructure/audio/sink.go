@@ -0,0 +1,134 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type Format struct {
+	SampleRate int
+	Channels   int
+}
+
+// Sink принимает декодированный PCM (signed 16-bit, interleaved).
+type Sink interface {
+	Write(samples []int16) error
+	Close() error
+}
+
+type SinkFactory func(streamName string, format Format) (Sink, error)
+
+type NullSink struct{}
+
+func NewNullSink(string, Format) (Sink, error) {
+	return &NullSink{}, nil
+}
+
+func (s *NullSink) Write([]int16) error {
+	return nil
+}
+
+func (s *NullSink) Close() error {
+	return nil
+}
+
+type WAVSink struct {
+	file      *os.File
+	format    Format
+	dataBytes uint32
+}
+
+const wavHeaderSize = 44
+
+// WAVSinkFactory пишет звук каждого потока в dir/<поток>.wav вместо
+// звуковой карты. При смене формата файл потока перезаписывается.
+func WAVSinkFactory(dir string) SinkFactory {
+	return func(streamName string, format Format) (Sink, error) {
+		sink, err := NewWAVSink(filepath.Join(dir, streamName+".wav"), format)
+		if err != nil {
+			return nil, err
+		}
+		return sink, nil
+	}
+}
+
+func NewWAVSink(path string, format Format) (*WAVSink, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &WAVSink{
+		file:   file,
+		format: format,
+	}
+
+	if err := s.writeHeader(); err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *WAVSink) Write(samples []int16) error {
+	if err := binary.Write(s.file, binary.LittleEndian, samples); err != nil {
+		return err
+	}
+	s.dataBytes += uint32(2 * len(samples))
+	return nil
+}
+
+func (s *WAVSink) Close() error {
+	if _, err := s.file.Seek(0, 0); err != nil {
+		s.file.Close()
+		return err
+	}
+	if err := s.writeHeader(); err != nil {
+		s.file.Close()
+		return err
+	}
+	return s.file.Close()
+}
+
+func (s *WAVSink) writeHeader() error {
+	if s.format.SampleRate <= 0 || s.format.Channels <= 0 {
+		return fmt.Errorf("некорректный формат аудио: %d Гц, %d каналов", s.format.SampleRate, s.format.Channels)
+	}
+
+	blockAlign := uint16(2 * s.format.Channels)
+
+	header := struct {
+		RIFF          [4]byte
+		ChunkSize     uint32
+		WAVE          [4]byte
+		Fmt           [4]byte
+		FmtSize       uint32
+		AudioFormat   uint16
+		Channels      uint16
+		SampleRate    uint32
+		ByteRate      uint32
+		BlockAlign    uint16
+		BitsPerSample uint16
+		Data          [4]byte
+		DataSize      uint32
+	}{
+		RIFF:          [4]byte{'R', 'I', 'F', 'F'},
+		ChunkSize:     wavHeaderSize - 8 + s.dataBytes,
+		WAVE:          [4]byte{'W', 'A', 'V', 'E'},
+		Fmt:           [4]byte{'f', 'm', 't', ' '},
+		FmtSize:       16,
+		AudioFormat:   1,
+		Channels:      uint16(s.format.Channels),
+		SampleRate:    uint32(s.format.SampleRate),
+		ByteRate:      uint32(s.format.SampleRate) * uint32(blockAlign),
+		BlockAlign:    blockAlign,
+		BitsPerSample: 16,
+		Data:          [4]byte{'d', 'a', 't', 'a'},
+		DataSize:      s.dataBytes,
+	}
+
+	return binary.Write(s.file, binary.LittleEndian, &header)
+}
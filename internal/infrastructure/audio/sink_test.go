@@ -0,0 +1,63 @@
+package audio
+
+import (
+	"io"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestWAVSinkRoundTrip(t *testing.T) {
+	for _, format := range []Format{
+		{SampleRate: 8000, Channels: 1},
+		{SampleRate: 44100, Channels: 2},
+	} {
+		dir := t.TempDir()
+
+		samples := make([]int16, 2*format.SampleRate/10*format.Channels)
+		for i := range samples {
+			samples[i] = int16(i*37 - 20000)
+		}
+
+		sink, err := WAVSinkFactory(dir)("High", format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		half := len(samples) / 2
+		if err := sink.Write(samples[:half]); err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.Write(samples[half:]); err != nil {
+			t.Fatal(err)
+		}
+		if err := sink.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		source, err := NewWAVSource(filepath.Join(dir, "High.wav"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if source.Format() != format {
+			t.Fatalf("формат %+v, ожидался %+v", source.Format(), format)
+		}
+
+		var got []int16
+		buf := make([]int16, 333)
+		for {
+			n, err := source.Read(buf)
+			got = append(got, buf[:n]...)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		source.Close()
+
+		if !slices.Equal(got, samples) {
+			t.Fatalf("%+v: прочитано %d отсчётов, записано %d, содержимое отличается", format, len(got), len(samples))
+		}
+	}
+}
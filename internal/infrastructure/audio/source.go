@@ -0,0 +1,174 @@
+package audio
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Source отдаёт захваченный PCM (signed 16-bit, interleaved) для передачи в камеру.
+type Source interface {
+	Format() Format
+	Read(samples []int16) (int, error)
+	Close() error
+}
+
+type SourceFactory func() (Source, error)
+
+type SilenceSource struct {
+	format Format
+}
+
+func NewSilenceSource() (Source, error) {
+	return &SilenceSource{
+		format: Format{SampleRate: 8000, Channels: 1},
+	}, nil
+}
+
+func (s *SilenceSource) Format() Format {
+	return s.format
+}
+
+func (s *SilenceSource) Read(samples []int16) (int, error) {
+	clear(samples)
+	return len(samples), nil
+}
+
+func (s *SilenceSource) Close() error {
+	return nil
+}
+
+type WAVSource struct {
+	file      *os.File
+	reader    *bufio.Reader
+	format    Format
+	remaining uint32
+	buf       []byte
+}
+
+// WAVSourceFactory передаёт в камеру звук из WAV файла вместо микрофона.
+// Каждое нажатие «Говорить» начинает файл сначала.
+func WAVSourceFactory(path string) SourceFactory {
+	return func() (Source, error) {
+		source, err := NewWAVSource(path)
+		if err != nil {
+			return nil, err
+		}
+		return source, nil
+	}
+}
+
+func NewWAVSource(path string) (*WAVSource, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &WAVSource{
+		file:   file,
+		reader: bufio.NewReader(file),
+	}
+
+	if err := s.readHeader(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("некорректный WAV файл %s: %w", path, err)
+	}
+
+	return s, nil
+}
+
+func (s *WAVSource) readHeader() error {
+	var riff struct {
+		ID   [4]byte
+		Size uint32
+		WAVE [4]byte
+	}
+	if err := binary.Read(s.reader, binary.LittleEndian, &riff); err != nil {
+		return err
+	}
+	if string(riff.ID[:]) != "RIFF" || string(riff.WAVE[:]) != "WAVE" {
+		return fmt.Errorf("отсутствует заголовок RIFF/WAVE")
+	}
+
+	for {
+		var chunk struct {
+			ID   [4]byte
+			Size uint32
+		}
+		if err := binary.Read(s.reader, binary.LittleEndian, &chunk); err != nil {
+			return err
+		}
+
+		switch string(chunk.ID[:]) {
+		case "fmt ":
+			var fmtChunk struct {
+				AudioFormat   uint16
+				Channels      uint16
+				SampleRate    uint32
+				ByteRate      uint32
+				BlockAlign    uint16
+				BitsPerSample uint16
+			}
+			if err := binary.Read(s.reader, binary.LittleEndian, &fmtChunk); err != nil {
+				return err
+			}
+			if fmtChunk.AudioFormat != 1 || fmtChunk.BitsPerSample != 16 {
+				return fmt.Errorf("поддерживается только 16-битный PCM")
+			}
+			s.format = Format{
+				SampleRate: int(fmtChunk.SampleRate),
+				Channels:   int(fmtChunk.Channels),
+			}
+			if _, err := s.reader.Discard(int(chunk.Size) - 16); err != nil {
+				return err
+			}
+
+		case "data":
+			if s.format.SampleRate == 0 {
+				return fmt.Errorf("блок data перед блоком fmt")
+			}
+			s.remaining = chunk.Size
+			return nil
+
+		default:
+			if _, err := s.reader.Discard(int(chunk.Size + chunk.Size%2)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *WAVSource) Format() Format {
+	return s.format
+}
+
+func (s *WAVSource) Read(samples []int16) (int, error) {
+	if s.remaining < 2 {
+		return 0, io.EOF
+	}
+
+	size := min(uint32(2*len(samples)), s.remaining&^1)
+	if cap(s.buf) < int(size) {
+		s.buf = make([]byte, size)
+	}
+	buf := s.buf[:size]
+
+	n, err := io.ReadFull(s.reader, buf)
+	n &^= 1
+	for i := 0; i < n/2; i++ {
+		samples[i] = int16(binary.LittleEndian.Uint16(buf[2*i:]))
+	}
+	s.remaining -= uint32(n)
+
+	if err == io.ErrUnexpectedEOF {
+		err = nil
+		s.remaining = 0
+	}
+	return n / 2, err
+}
+
+func (s *WAVSource) Close() error {
+	return s.file.Close()
+}
@@ -0,0 +1,445 @@
+// Package fakecamera — встроенная RTSP камера для тестов и демонстраций.
+// Она отдаёт синтетические кадры H.264 по настраиваемым путям и умеет
+// имитировать неполадки настоящих камер: авторизацию, задержку ответов,
+// потерю пакетов и обрыв сессии, а также принимать звук по обратному каналу.
+package fakecamera
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v5"
+	"github.com/bluenviron/gortsplib/v5/pkg/auth"
+	"github.com/bluenviron/gortsplib/v5/pkg/base"
+	"github.com/bluenviron/gortsplib/v5/pkg/description"
+	"github.com/bluenviron/gortsplib/v5/pkg/format"
+	"github.com/bluenviron/gortsplib/v5/pkg/liberrors"
+	"github.com/pion/rtp"
+)
+
+const (
+	defaultWidth  = 320
+	defaultHeight = 240
+	defaultFPS    = 10
+)
+
+// AuthMethod — способ проверки учётных данных клиента.
+type AuthMethod int
+
+const (
+	AuthNone AuthMethod = iota
+	AuthBasic
+	AuthDigest
+)
+
+// Stream — поток, который камера отдаёт по пути Path.
+type Stream struct {
+	Path    string
+	Pattern Pattern
+	// Width и Height округляются вверх до кратных 16.
+	Width  int
+	Height int
+	FPS    int
+	// Aliases — другие пути с тем же содержимым, как у камер, где один
+	// поток доступен по нескольким URL.
+	Aliases []string
+	// Backchannel добавляет обратный аудиоканал ONVIF (G.711 µ-law, 8 кГц).
+	// Принятый звук возвращает Camera.Backchannel.
+	Backchannel bool
+}
+
+type Options struct {
+	// Address — адрес RTSP сервера. По умолчанию 127.0.0.1 со свободным портом.
+	Address string
+	// Streams по умолчанию — один поток /live.
+	Streams []Stream
+
+	Auth     AuthMethod
+	Login    string
+	Password string
+
+	// Latency — задержка перед ответом на DESCRIBE, SETUP и PLAY.
+	Latency time.Duration
+	// PacketLoss — доля RTP пакетов, которые камера не отправляет, от 0 до 1.
+	PacketLoss float64
+	// DisconnectAfter закрывает сессию через заданное время после PLAY.
+	DisconnectAfter time.Duration
+}
+
+// Camera — запущенная фальшивая камера. Обработчики gortsplib вызываются
+// из горутин сервера.
+type Camera struct {
+	opts    Options
+	server  *gortsplib.Server
+	address string
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	streams map[string]*stream
+	timers  map[*gortsplib.ServerSession]*time.Timer
+}
+
+type stream struct {
+	config Stream
+	media  *description.Media
+	server *gortsplib.ServerStream
+
+	mu       sync.Mutex
+	received []byte
+}
+
+func (s *stream) receive(payload []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.received = append(s.received, payload...)
+}
+
+// Start запускает сервер и генераторы кадров всех потоков.
+func Start(opts Options) (*Camera, error) {
+	if opts.Address == "" {
+		opts.Address = "127.0.0.1:0"
+	}
+	if len(opts.Streams) == 0 {
+		opts.Streams = []Stream{{Path: "/live"}}
+	}
+	if opts.Auth != AuthNone && opts.Login == "" {
+		return nil, errors.New("для авторизации нужен логин")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Camera{
+		opts:    opts,
+		ctx:     ctx,
+		cancel:  cancel,
+		streams: make(map[string]*stream),
+		timers:  make(map[*gortsplib.ServerSession]*time.Timer),
+	}
+
+	c.server = &gortsplib.Server{
+		Handler:     c,
+		RTSPAddress: opts.Address,
+		Listen:      c.listen,
+	}
+	switch opts.Auth {
+	case AuthBasic:
+		c.server.AuthMethods = []auth.VerifyMethod{auth.VerifyMethodBasic}
+	case AuthDigest:
+		c.server.AuthMethods = []auth.VerifyMethod{auth.VerifyMethodDigestMD5}
+	}
+
+	if err := c.server.Start(); err != nil {
+		cancel()
+		return nil, fmt.Errorf("ошибка запуска RTSP сервера: %w", err)
+	}
+
+	var created []*stream
+	streams := make(map[string]*stream)
+	fail := func(err error) (*Camera, error) {
+		for _, s := range created {
+			s.server.Close()
+		}
+		c.server.Close()
+		cancel()
+		return nil, err
+	}
+
+	for _, config := range opts.Streams {
+		s, err := c.newStream(config)
+		if err != nil {
+			return fail(err)
+		}
+		created = append(created, s)
+
+		for _, path := range append([]string{config.Path}, config.Aliases...) {
+			path = normalizePath(path)
+			if _, exists := streams[path]; exists {
+				return fail(fmt.Errorf("путь %s указан дважды", path))
+			}
+			streams[path] = s
+		}
+	}
+
+	c.mu.Lock()
+	c.streams = streams
+	c.mu.Unlock()
+
+	for _, s := range created {
+		c.wg.Add(1)
+		go c.run(s)
+	}
+
+	return c, nil
+}
+
+func (c *Camera) newStream(config Stream) (*stream, error) {
+	if config.Width <= 0 {
+		config.Width = defaultWidth
+	}
+	if config.Height <= 0 {
+		config.Height = defaultHeight
+	}
+	if config.FPS <= 0 {
+		config.FPS = defaultFPS
+	}
+	config.Width = (config.Width + mbSize - 1) / mbSize * mbSize
+	config.Height = (config.Height + mbSize - 1) / mbSize * mbSize
+
+	enc := &pcmEncoder{width: config.Width, height: config.Height}
+	media := &description.Media{
+		Type: description.MediaTypeVideo,
+		Formats: []format.Format{&format.H264{
+			PayloadTyp:        96,
+			PacketizationMode: 1,
+			SPS:               enc.sps(),
+			PPS:               enc.pps(),
+		}},
+	}
+
+	desc := &description.Session{Medias: []*description.Media{media}}
+	if config.Backchannel {
+		desc.Medias = append(desc.Medias, &description.Media{
+			Type:          description.MediaTypeAudio,
+			IsBackChannel: true,
+			Formats: []format.Format{&format.G711{
+				PayloadTyp:   0,
+				MULaw:        true,
+				SampleRate:   8000,
+				ChannelCount: 1,
+			}},
+		})
+	}
+
+	server := &gortsplib.ServerStream{
+		Server: c.server,
+		Desc:   desc,
+	}
+	if err := server.Initialize(); err != nil {
+		return nil, fmt.Errorf("ошибка создания потока %s: %w", config.Path, err)
+	}
+
+	return &stream{config: config, media: media, server: server}, nil
+}
+
+// run кодирует и отправляет кадры потока с частотой FPS, пока камера открыта.
+func (c *Camera) run(s *stream) {
+	defer c.wg.Done()
+
+	forma := s.media.Formats[0].(*format.H264)
+	rtpEnc, err := forma.CreateEncoder()
+	if err != nil {
+		return
+	}
+
+	config := s.config
+	enc := &pcmEncoder{width: config.Width, height: config.Height}
+	frame := newYUVFrame(config.Width, config.Height)
+	randomStart := rand.Uint32()
+
+	ticker := time.NewTicker(time.Second / time.Duration(config.FPS))
+	defer ticker.Stop()
+
+	for n := 0; ; n++ {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if n == 0 || config.Pattern == PatternBars {
+			config.Pattern.render(frame, config.Width, config.Height, n)
+		}
+
+		pkts, err := rtpEnc.Encode(enc.encode(frame))
+		if err != nil {
+			continue
+		}
+
+		timestamp := randomStart + uint32(int64(n)*90000/int64(config.FPS))
+		for _, pkt := range pkts {
+			pkt.Timestamp = timestamp
+			if c.opts.PacketLoss > 0 && rand.Float64() < c.opts.PacketLoss {
+				continue
+			}
+			s.server.WritePacketRTP(s.media, pkt)
+		}
+	}
+}
+
+func (c *Camera) listen(network, address string) (net.Listener, error) {
+	ln, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	c.address = ln.Addr().String()
+	return ln, nil
+}
+
+// Backchannel возвращает полезную нагрузку G.711 всех RTP пакетов,
+// принятых по обратному каналу потока path, в порядке получения.
+func (c *Camera) Backchannel(path string) []byte {
+	s := c.find(path)
+	if s == nil {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.received)
+}
+
+// Address возвращает адрес сервера вида host:port.
+func (c *Camera) Address() string {
+	return c.address
+}
+
+func (c *Camera) Host() string {
+	host, _, _ := net.SplitHostPort(c.address)
+	return host
+}
+
+func (c *Camera) Port() int {
+	_, port, _ := net.SplitHostPort(c.address)
+	n, _ := strconv.Atoi(port)
+	return n
+}
+
+// URL возвращает адрес потока без учётных данных.
+func (c *Camera) URL(path string) string {
+	return "rtsp://" + c.address + normalizePath(path)
+}
+
+// Close закрывает сессии клиентов и останавливает сервер.
+func (c *Camera) Close() {
+	c.cancel()
+	c.wg.Wait()
+
+	c.mu.Lock()
+	for session, timer := range c.timers {
+		timer.Stop()
+		delete(c.timers, session)
+	}
+	streams := make(map[*stream]struct{})
+	for _, s := range c.streams {
+		streams[s] = struct{}{}
+	}
+	c.mu.Unlock()
+
+	// сессии закрываются из горутин сервера и вызывают OnSessionClose
+	for s := range streams {
+		s.server.Close()
+	}
+	c.server.Close()
+}
+
+func (c *Camera) find(path string) *stream {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.streams[normalizePath(path)]
+}
+
+// delay имитирует медленную камеру.
+func (c *Camera) delay() {
+	if c.opts.Latency <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(c.opts.Latency)
+	defer timer.Stop()
+
+	select {
+	case <-c.ctx.Done():
+	case <-timer.C:
+	}
+}
+
+// authorize возвращает 401, если учётные данные не подходят. Без
+// учётных данных gortsplib добавит к ответу WWW-Authenticate, с неверными
+// закроет соединение.
+func (c *Camera) authorize(conn *gortsplib.ServerConn, req *base.Request) (*base.Response, error) {
+	if c.opts.Auth == AuthNone || conn.VerifyCredentials(req, c.opts.Login, c.opts.Password) {
+		return nil, nil
+	}
+	return &base.Response{StatusCode: base.StatusUnauthorized}, liberrors.ErrServerAuth{}
+}
+
+func (c *Camera) OnDescribe(ctx *gortsplib.ServerHandlerOnDescribeCtx) (*base.Response, *gortsplib.ServerStream, error) {
+	c.delay()
+
+	if res, err := c.authorize(ctx.Conn, ctx.Request); res != nil {
+		return res, nil, err
+	}
+
+	s := c.find(ctx.Path)
+	if s == nil {
+		return &base.Response{StatusCode: base.StatusNotFound}, nil, nil
+	}
+	return &base.Response{StatusCode: base.StatusOK}, s.server, nil
+}
+
+func (c *Camera) OnSetup(ctx *gortsplib.ServerHandlerOnSetupCtx) (*base.Response, *gortsplib.ServerStream, error) {
+	c.delay()
+
+	if res, err := c.authorize(ctx.Conn, ctx.Request); res != nil {
+		return res, nil, err
+	}
+
+	s := c.find(ctx.Path)
+	if s == nil {
+		return &base.Response{StatusCode: base.StatusNotFound}, nil, nil
+	}
+	return &base.Response{StatusCode: base.StatusOK}, s.server, nil
+}
+
+func (c *Camera) OnPlay(ctx *gortsplib.ServerHandlerOnPlayCtx) (*base.Response, error) {
+	c.delay()
+
+	if c.opts.DisconnectAfter > 0 {
+		session := ctx.Session
+		c.mu.Lock()
+		if timer, exists := c.timers[session]; exists {
+			timer.Stop()
+		}
+		c.timers[session] = time.AfterFunc(c.opts.DisconnectAfter, session.Close)
+		c.mu.Unlock()
+	}
+
+	if s := c.find(ctx.Path); s != nil && s.config.Backchannel {
+		ctx.Session.OnPacketRTPAny(func(medi *description.Media, _ format.Format, pkt *rtp.Packet) {
+			if medi.IsBackChannel {
+				s.receive(pkt.Payload)
+			}
+		})
+	}
+
+	return &base.Response{StatusCode: base.StatusOK}, nil
+}
+
+func (c *Camera) OnSessionClose(ctx *gortsplib.ServerHandlerOnSessionCloseCtx) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if timer, exists := c.timers[ctx.Session]; exists {
+		timer.Stop()
+		delete(c.timers, ctx.Session)
+	}
+}
+
+// normalizePath приводит путь к виду, в котором его передаёт gortsplib:
+// с ведущим и без завершающего слэша.
+func normalizePath(path string) string {
+	return "/" + strings.Trim(path, "/")
+}
@@ -0,0 +1,381 @@
+package fakecamera
+
+import (
+	"bytes"
+	"errors"
+	"math/bits"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v5"
+	"github.com/bluenviron/gortsplib/v5/pkg/base"
+	"github.com/bluenviron/gortsplib/v5/pkg/description"
+	"github.com/bluenviron/gortsplib/v5/pkg/format"
+	"github.com/bluenviron/gortsplib/v5/pkg/format/rtph264"
+	"github.com/bluenviron/mediacommon/v2/pkg/codecs/h264"
+	"github.com/pion/rtp"
+)
+
+const (
+	testLogin    = "admin"
+	testPassword = "p@ssword"
+)
+
+func TestSPSDescribesFrameSize(t *testing.T) {
+	enc := &pcmEncoder{width: 64, height: 48}
+
+	var sps h264.SPS
+	if err := sps.Unmarshal(enc.sps()); err != nil {
+		t.Fatal(err)
+	}
+	if sps.Width() != 64 || sps.Height() != 48 {
+		t.Fatalf("размер из SPS %dx%d, ожидался 64x48", sps.Width(), sps.Height())
+	}
+}
+
+// bitReader разбирает RBSP обратно, чтобы сверить отсчёты PCM с кадром.
+type bitReader struct {
+	buf []byte
+	pos int
+}
+
+func (r *bitReader) bits(n int) uint64 {
+	var v uint64
+	for i := 0; i < n; i++ {
+		v = v<<1 | uint64(r.buf[r.pos/8]>>(7-r.pos%8)&1)
+		r.pos++
+	}
+	return v
+}
+
+func (r *bitReader) ue() uint64 {
+	zeros := 0
+	for r.bits(1) == 0 {
+		zeros++
+	}
+	return 1<<zeros - 1 + r.bits(zeros)
+}
+
+func TestEncodeStoresSamplesAsPCM(t *testing.T) {
+	const width, height = 32, 32
+
+	frame := newYUVFrame(width, height)
+	PatternBars.render(frame, width, height, 0)
+	// нули и единицы подряд проверяют защиту от стартовых кодов
+	for i := range frame.y {
+		frame.y[i] = byte(i % 3)
+	}
+
+	enc := &pcmEncoder{width: width, height: height}
+	au := enc.encode(frame)
+	if len(au) != 3 || h264.NALUType(au[2][0]&0x1f) != h264.NALUTypeIDR {
+		t.Fatalf("ожидались SPS, PPS и IDR, получено %d NAL", len(au))
+	}
+	if bytes.Contains(au[2], []byte{0, 0, 1}) {
+		t.Fatal("в NAL остался стартовый код")
+	}
+
+	r := &bitReader{buf: h264.EmulationPreventionRemove(au[2][1:])}
+	r.ue()    // first_mb_in_slice
+	r.ue()    // slice_type
+	r.ue()    // pic_parameter_set_id
+	r.bits(4) // frame_num
+	r.ue()    // idr_pic_id
+	r.bits(2) // dec_ref_pic_marking
+	r.ue()    // slice_qp_delta = 0
+
+	got := newYUVFrame(width, height)
+	for mbY := 0; mbY < height/mbSize; mbY++ {
+		for mbX := 0; mbX < width/mbSize; mbX++ {
+			if mbType := r.ue(); mbType != 25 {
+				t.Fatalf("mb_type %d, ожидался I_PCM", mbType)
+			}
+			r.pos = (r.pos + 7) / 8 * 8
+
+			for y := 0; y < mbSize; y++ {
+				for x := 0; x < mbSize; x++ {
+					got.y[(mbY*mbSize+y)*width+mbX*mbSize+x] = byte(r.bits(8))
+				}
+			}
+			for _, plane := range [][]byte{got.cb, got.cr} {
+				for y := 0; y < mbSize/2; y++ {
+					for x := 0; x < mbSize/2; x++ {
+						plane[(mbY*mbSize/2+y)*width/2+mbX*mbSize/2+x] = byte(r.bits(8))
+					}
+				}
+			}
+		}
+	}
+
+	if !bytes.Equal(got.y, frame.y) || !bytes.Equal(got.cb, frame.cb) || !bytes.Equal(got.cr, frame.cr) {
+		t.Fatal("отсчёты PCM не совпадают с кадром")
+	}
+	if stop := r.bits(1); stop != 1 {
+		t.Fatal("нет rbsp_stop_one_bit")
+	}
+	if rest := len(r.buf)*8 - r.pos; rest >= 8 || bits.OnesCount8(r.buf[len(r.buf)-1]<<(8-rest)) != 0 {
+		t.Fatal("после rbsp_stop_one_bit лишние данные")
+	}
+}
+
+func startCamera(t *testing.T, opts Options) *Camera {
+	t.Helper()
+
+	camera, err := Start(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(camera.Close)
+	return camera
+}
+
+func withCredentials(rawURL, login, password string) string {
+	return strings.Replace(rawURL, "rtsp://", "rtsp://"+login+":"+password+"@", 1)
+}
+
+// play подключается к камере и возвращает первый принятый кадр H.264.
+func play(t *testing.T, rawURL string) ([][]byte, *gortsplib.Client, error) {
+	t.Helper()
+
+	u, err := base.ParseURL(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := &gortsplib.Client{Scheme: u.Scheme, Host: u.Host, ReadTimeout: 3 * time.Second}
+	if err := client.Start(); err != nil {
+		return nil, nil, err
+	}
+	t.Cleanup(client.Close)
+
+	desc, _, err := client.Describe(u)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var forma *format.H264
+	medi := desc.FindFormat(&forma)
+	if medi == nil {
+		t.Fatal("в SDP нет H.264")
+	}
+
+	dec, err := forma.CreateDecoder()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Setup(desc.BaseURL, medi, 0, 0); err != nil {
+		return nil, nil, err
+	}
+
+	frames := make(chan [][]byte, 1)
+	client.OnPacketRTP(medi, forma, func(pkt *rtp.Packet) {
+		au, err := dec.Decode(pkt)
+		if err != nil {
+			if !errors.Is(err, rtph264.ErrMorePacketsNeeded) && !errors.Is(err, rtph264.ErrNonStartingPacketAndNoPrevious) {
+				t.Errorf("ошибка разбора RTP: %v", err)
+			}
+			return
+		}
+		select {
+		case frames <- au:
+		default:
+		}
+	})
+
+	if _, err := client.Play(nil); err != nil {
+		return nil, nil, err
+	}
+
+	select {
+	case au := <-frames:
+		return au, client, nil
+	case <-time.After(5 * time.Second):
+		t.Fatal("камера не прислала ни одного кадра")
+		return nil, nil, nil
+	}
+}
+
+func TestCameraServesPatternWithAuth(t *testing.T) {
+	for _, method := range []AuthMethod{AuthBasic, AuthDigest} {
+		camera := startCamera(t, Options{
+			Streams:  []Stream{{Path: "/main", Width: 64, Height: 48, FPS: 25}},
+			Auth:     method,
+			Login:    testLogin,
+			Password: testPassword,
+		})
+
+		au, _, err := play(t, withCredentials(camera.URL("main"), testLogin, "p%40ssword"))
+		if err != nil {
+			t.Fatalf("авторизация %d: %v", method, err)
+		}
+		if !h264.IsRandomAccess(au) {
+			t.Fatalf("авторизация %d: первый кадр не ключевой", method)
+		}
+	}
+}
+
+func TestCameraRejectsWrongPassword(t *testing.T) {
+	camera := startCamera(t, Options{
+		Auth:     AuthDigest,
+		Login:    testLogin,
+		Password: testPassword,
+	})
+
+	_, _, err := play(t, withCredentials(camera.URL("live"), testLogin, "wrong"))
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("ожидалась ошибка 401, получено %v", err)
+	}
+}
+
+func TestCameraUnknownPath(t *testing.T) {
+	camera := startCamera(t, Options{})
+
+	_, _, err := play(t, camera.URL("missing"))
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("ожидалась ошибка 404, получено %v", err)
+	}
+}
+
+func TestCameraAliasServesSameStream(t *testing.T) {
+	camera := startCamera(t, Options{
+		Streams: []Stream{{Path: "/main", Width: 32, Height: 32, FPS: 25, Aliases: []string{"/h264/ch1/main/av_stream"}}},
+	})
+
+	main, _, err := play(t, camera.URL("main"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	alias, _, err := play(t, camera.URL("h264/ch1/main/av_stream/"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// SPS одинаковый — поток один и тот же
+	if !bytes.Equal(main[0], alias[0]) {
+		t.Fatal("псевдоним отдаёт другой поток")
+	}
+}
+
+func TestCameraRejectsDuplicatePath(t *testing.T) {
+	_, err := Start(Options{Streams: []Stream{{Path: "/a"}, {Path: "/b", Aliases: []string{"a/"}}}})
+	if err == nil {
+		t.Fatal("повторный путь должен быть ошибкой")
+	}
+}
+
+func TestCameraDisconnectsAfterTimeout(t *testing.T) {
+	camera := startCamera(t, Options{
+		Streams:         []Stream{{Path: "/live", Width: 32, Height: 32, FPS: 25}},
+		DisconnectAfter: 300 * time.Millisecond,
+	})
+
+	_, client, err := play(t, camera.URL("live"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Wait()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("камера не разорвала сессию")
+	}
+}
+
+func TestCameraDropsPackets(t *testing.T) {
+	camera := startCamera(t, Options{
+		Streams:    []Stream{{Path: "/live", Width: 32, Height: 32, FPS: 50}},
+		PacketLoss: 1,
+	})
+
+	u, _ := base.ParseURL(camera.URL("live"))
+	client := &gortsplib.Client{Scheme: u.Scheme, Host: u.Host}
+	if err := client.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	desc, _, err := client.Describe(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := client.SetupAll(desc.BaseURL, desc.Medias); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan struct{}, 1)
+	client.OnPacketRTPAny(func(*description.Media, format.Format, *rtp.Packet) {
+		select {
+		case received <- struct{}{}:
+		default:
+		}
+	})
+	if _, err := client.Play(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-received:
+		t.Fatal("при полной потере пакетов кадры дошли")
+	case <-time.After(500 * time.Millisecond):
+	}
+}
+
+func TestCameraReceivesBackchannel(t *testing.T) {
+	camera := startCamera(t, Options{
+		Streams: []Stream{{Path: "/live", Width: 32, Height: 32, FPS: 25, Backchannel: true}},
+	})
+
+	u, _ := base.ParseURL(camera.URL("live"))
+	client := &gortsplib.Client{Scheme: u.Scheme, Host: u.Host, RequestBackChannels: true}
+	if err := client.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	desc, _, err := client.Describe(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var backchannel *description.Media
+	for _, medi := range desc.Medias {
+		if medi.IsBackChannel {
+			backchannel = medi
+		}
+	}
+	if backchannel == nil {
+		t.Fatal("в SDP нет обратного канала")
+	}
+	if _, err := client.Setup(desc.BaseURL, backchannel, 0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Play(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	var sent []byte
+	for i := range 3 {
+		payload := bytes.Repeat([]byte{byte(0x10 * (i + 1))}, 160)
+		sent = append(sent, payload...)
+		pkt := &rtp.Packet{
+			Header:  rtp.Header{Version: 2, PayloadType: 0, SequenceNumber: uint16(i), Timestamp: uint32(160 * i)},
+			Payload: payload,
+		}
+		if err := client.WritePacketRTP(backchannel, pkt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !bytes.Equal(camera.Backchannel("/live"), sent) {
+		if time.Now().After(deadline) {
+			t.Fatalf("камера приняла %d байт из %d", len(camera.Backchannel("/live")), len(sent))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
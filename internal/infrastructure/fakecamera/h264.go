@@ -0,0 +1,168 @@
+package fakecamera
+
+import (
+	"math/bits"
+
+	"github.com/bluenviron/mediacommon/v2/pkg/codecs/h264"
+)
+
+// Кодер без сжатия: каждый кадр — IDR из макроблоков I_PCM, в которых
+// отсчёты яркости и цветности записаны как есть. Такой поток декодирует
+// любой декодер H.264, а собрать его можно без libavcodec и x264.
+
+const mbSize = 16
+
+// pcmEncoder собирает SPS, PPS и кадры I_PCM размера width×height,
+// кратного 16.
+type pcmEncoder struct {
+	width  int
+	height int
+	idrID  uint
+}
+
+func (e *pcmEncoder) sps() []byte {
+	var w bitWriter
+	w.bits(66, 8)   // profile_idc: Baseline
+	w.bits(0xc0, 8) // constraint_set0_flag, constraint_set1_flag
+	w.bits(30, 8)   // level_idc
+	w.ue(0)         // seq_parameter_set_id
+	w.ue(0)         // log2_max_frame_num_minus4
+	w.ue(2)         // pic_order_cnt_type
+	w.ue(1)         // max_num_ref_frames
+	w.bits(0, 1)    // gaps_in_frame_num_value_allowed_flag
+	w.ue(uint(e.width/mbSize - 1))
+	w.ue(uint(e.height/mbSize - 1))
+	w.bits(1, 1) // frame_mbs_only_flag
+	w.bits(1, 1) // direct_8x8_inference_flag
+	w.bits(0, 1) // frame_cropping_flag
+	w.bits(0, 1) // vui_parameters_present_flag
+	w.trailing()
+
+	return nalu(h264.NALUTypeSPS, w.buf)
+}
+
+func (e *pcmEncoder) pps() []byte {
+	var w bitWriter
+	w.ue(0)      // pic_parameter_set_id
+	w.ue(0)      // seq_parameter_set_id
+	w.bits(0, 1) // entropy_coding_mode_flag: CAVLC
+	w.bits(0, 1) // bottom_field_pic_order_in_frame_present_flag
+	w.ue(0)      // num_slice_groups_minus1
+	w.ue(0)      // num_ref_idx_l0_default_active_minus1
+	w.ue(0)      // num_ref_idx_l1_default_active_minus1
+	w.bits(0, 1) // weighted_pred_flag
+	w.bits(0, 2) // weighted_bipred_idc
+	w.se(0)      // pic_init_qp_minus26
+	w.se(0)      // pic_init_qs_minus26
+	w.se(0)      // chroma_qp_index_offset
+	w.bits(0, 1) // deblocking_filter_control_present_flag
+	w.bits(0, 1) // constrained_intra_pred_flag
+	w.bits(0, 1) // redundant_pic_cnt_present_flag
+	w.trailing()
+
+	return nalu(h264.NALUTypePPS, w.buf)
+}
+
+// encode упаковывает кадр YUV 4:2:0 в access unit: SPS, PPS и IDR срез.
+// SPS и PPS повторяются в каждом кадре, чтобы клиент мог начать с любого.
+func (e *pcmEncoder) encode(frame *yuvFrame) [][]byte {
+	var w bitWriter
+	w.ue(0)           // first_mb_in_slice
+	w.ue(7)           // slice_type: I, все срезы кадра
+	w.ue(0)           // pic_parameter_set_id
+	w.bits(0, 4)      // frame_num: у IDR всегда 0
+	w.ue(e.idrID % 2) // соседние IDR должны различаться idr_pic_id
+	w.bits(0, 1)      // no_output_of_prior_pics_flag
+	w.bits(0, 1)      // long_term_reference_flag
+	w.se(0)           // slice_qp_delta
+	e.idrID++
+
+	mbWidth := e.width / mbSize
+	mbHeight := e.height / mbSize
+	for mbY := 0; mbY < mbHeight; mbY++ {
+		for mbX := 0; mbX < mbWidth; mbX++ {
+			w.ue(25) // mb_type: I_PCM
+			w.align()
+
+			for y := 0; y < mbSize; y++ {
+				row := (mbY*mbSize+y)*e.width + mbX*mbSize
+				w.buf = append(w.buf, frame.y[row:row+mbSize]...)
+			}
+			for _, plane := range [][]byte{frame.cb, frame.cr} {
+				for y := 0; y < mbSize/2; y++ {
+					row := (mbY*mbSize/2+y)*e.width/2 + mbX*mbSize/2
+					w.buf = append(w.buf, plane[row:row+mbSize/2]...)
+				}
+			}
+		}
+	}
+	w.trailing()
+
+	return [][]byte{e.sps(), e.pps(), nalu(h264.NALUTypeIDR, w.buf)}
+}
+
+// nalu добавляет заголовок с nal_ref_idc = 3 и защиту от стартовых кодов.
+func nalu(typ h264.NALUType, rbsp []byte) []byte {
+	out := make([]byte, 1, len(rbsp)+len(rbsp)/64+1)
+	out[0] = 3<<5 | byte(typ)
+
+	zeros := 0
+	for _, b := range rbsp {
+		if zeros >= 2 && b <= 3 {
+			out = append(out, 3)
+			zeros = 0
+		}
+		out = append(out, b)
+		if b == 0 {
+			zeros++
+		} else {
+			zeros = 0
+		}
+	}
+	return out
+}
+
+type bitWriter struct {
+	buf []byte
+	cur byte
+	n   uint
+}
+
+func (w *bitWriter) bits(v uint64, n int) {
+	for i := n - 1; i >= 0; i-- {
+		w.cur = w.cur<<1 | byte(v>>uint(i)&1)
+		w.n++
+		if w.n == 8 {
+			w.buf = append(w.buf, w.cur)
+			w.cur, w.n = 0, 0
+		}
+	}
+}
+
+// ue пишет беззнаковый экспоненциальный код Голомба.
+func (w *bitWriter) ue(v uint) {
+	x := uint64(v) + 1
+	n := bits.Len64(x)
+	w.bits(0, n-1)
+	w.bits(x, n)
+}
+
+func (w *bitWriter) se(v int) {
+	if v > 0 {
+		w.ue(uint(2*v - 1))
+	} else {
+		w.ue(uint(-2 * v))
+	}
+}
+
+func (w *bitWriter) align() {
+	if w.n > 0 {
+		w.bits(0, int(8-w.n))
+	}
+}
+
+// trailing пишет rbsp_stop_one_bit и выравнивание.
+func (w *bitWriter) trailing() {
+	w.bits(1, 1)
+	w.align()
+}
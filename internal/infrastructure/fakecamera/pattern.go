@@ -0,0 +1,79 @@
+package fakecamera
+
+// Pattern — содержимое синтетического кадра.
+type Pattern int
+
+const (
+	// PatternBars — цветные полосы и квадрат, который движется по кругу.
+	PatternBars Pattern = iota
+	// PatternStatic — те же полосы без движения, для проверки застывшего кадра.
+	PatternStatic
+	// PatternBlack — чёрный кадр.
+	PatternBlack
+)
+
+var barColors = [][3]int{
+	{0xff, 0xff, 0xff},
+	{0xff, 0xff, 0x00},
+	{0x00, 0xff, 0xff},
+	{0x00, 0xff, 0x00},
+	{0xff, 0x00, 0xff},
+	{0xff, 0x00, 0x00},
+	{0x00, 0x00, 0xff},
+	{0x10, 0x10, 0x10},
+}
+
+// yuvFrame — кадр 4:2:0: полная яркость и цветность в половинном размере.
+type yuvFrame struct {
+	y  []byte
+	cb []byte
+	cr []byte
+}
+
+func newYUVFrame(width, height int) *yuvFrame {
+	return &yuvFrame{
+		y:  make([]byte, width*height),
+		cb: make([]byte, width*height/4),
+		cr: make([]byte, width*height/4),
+	}
+}
+
+// render рисует кадр с номером n.
+func (p Pattern) render(frame *yuvFrame, width, height, n int) {
+	boxSize := max(height/4, mbSize)
+	boxX, boxY := -1, -1
+	if p == PatternBars {
+		// квадрат сдвигается на 4 пиксела за кадр и возвращается к левому краю
+		boxX = n * 4 % max(width-boxSize, 1)
+		boxY = (height - boxSize) / 2
+	}
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var rgb [3]int
+			switch {
+			case p == PatternBlack:
+			case boxX >= 0 && x >= boxX && x < boxX+boxSize && y >= boxY && y < boxY+boxSize:
+				rgb = [3]int{0x80, 0x80, 0x80}
+			default:
+				rgb = barColors[x*len(barColors)/width]
+			}
+
+			luma, cb, cr := toYCbCr(rgb)
+			frame.y[y*width+x] = luma
+			if x%2 == 0 && y%2 == 0 {
+				frame.cb[(y/2)*(width/2)+x/2] = cb
+				frame.cr[(y/2)*(width/2)+x/2] = cr
+			}
+		}
+	}
+}
+
+// toYCbCr переводит RGB в YCbCr BT.601 ограниченного диапазона.
+func toYCbCr(rgb [3]int) (byte, byte, byte) {
+	r, g, b := rgb[0], rgb[1], rgb[2]
+	y := (66*r+129*g+25*b+128)>>8 + 16
+	cb := (-38*r-74*g+112*b+128)>>8 + 128
+	cr := (112*r-94*g-18*b+128)>>8 + 128
+	return byte(y), byte(cb), byte(cr)
+}
@@ -0,0 +1,359 @@
+//go:build cgo
+
+package filesource
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/draw"
+	"ip-camera-viewer/internal/domain/model"
+	"ip-camera-viewer/internal/infrastructure/record"
+	videodecoder "ip-camera-viewer/internal/infrastructure/video"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	framePoolSize = 4
+	statsWindow   = time.Second
+)
+
+// Source воспроизводит MP4, fMP4 или сырой H.264 в канал кадров так же,
+// как rtsp.Client воспроизводит камеру: с исходной или ускоренной
+// скоростью и, если задано, по кругу.
+type Source struct {
+	config     *model.StreamConfig
+	mutex      sync.RWMutex
+	opts       Options
+	file       *os.File
+	track      track
+	isRunning  bool
+	cancelFunc context.CancelFunc
+
+	outputWidth  int
+	outputHeight int
+
+	logger *slog.Logger
+	stats  playbackStats
+
+	recorder atomic.Pointer[record.Recorder]
+}
+
+func NewSource(config *model.StreamConfig) *Source {
+	return &Source{config: config}
+}
+
+// Connect открывает файл и строит таблицу кадров.
+func (s *Source) Connect(ctx context.Context) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.file != nil {
+		return nil
+	}
+
+	opts, err := ParseURI(s.config.RTSPURI)
+	if err != nil {
+		return fmt.Errorf("ошибка разбора URI файла: %w", err)
+	}
+
+	file, err := os.Open(opts.Path)
+	if err != nil {
+		return fmt.Errorf("ошибка открытия файла: %w", err)
+	}
+
+	t, err := openTrack(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	s.opts = opts
+	s.file = file
+	s.track = t
+	return nil
+}
+
+func (s *Source) StartStreaming(ctx context.Context, frameChannel chan<- *model.FrameData) error {
+	s.mutex.Lock()
+	if s.isRunning {
+		s.mutex.Unlock()
+		return fmt.Errorf("поток уже запущен")
+	}
+	if s.track == nil {
+		s.mutex.Unlock()
+		return fmt.Errorf("файл не открыт")
+	}
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	s.cancelFunc = cancel
+	s.isRunning = true
+	t, opts := s.track, s.opts
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		s.isRunning = false
+		s.cancelFunc = nil
+		s.mutex.Unlock()
+	}()
+
+	s.log().Info("Воспроизведение файла", "path", opts.Path, "frames", t.len(), "speed", opts.Speed, "loop", opts.Loop)
+	s.stats.setPlaying(time.Now())
+
+	for {
+		if err := s.play(streamCtx, t, opts.Speed, frameChannel); err != nil {
+			return err
+		}
+		if streamCtx.Err() != nil {
+			return nil
+		}
+		if !opts.Loop {
+			s.log().Info("Файл воспроизведён до конца", "path", opts.Path)
+			return nil
+		}
+	}
+}
+
+// play проходит файл один раз. Кадры выдаются по DTS, поделённому на
+// скорость; если декодер не успевает, кадры идут без пауз, пока не
+// догонят график.
+func (s *Source) play(ctx context.Context, t track, speed float64, frameChannel chan<- *model.FrameData) error {
+	dec := &videodecoder.H264Decoder{}
+	if err := dec.Initialize(); err != nil {
+		return fmt.Errorf("ошибка инициализации декодера H264: %v", err)
+	}
+	defer dec.Close()
+
+	sps, pps := t.params()
+	if sps != nil {
+		dec.Decode([][]byte{sps})
+	}
+	if pps != nil {
+		dec.Decode([][]byte{pps})
+	}
+
+	// метки времени начинаются заново на каждом круге
+	if rec := s.recorder.Load(); rec != nil {
+		rec.Reset(sps, pps)
+	}
+
+	framePool := videodecoder.NewFramePool(framePoolSize)
+	start := time.Now()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for i := 0; i < t.len(); i++ {
+		au, err := t.sample(i)
+		if err != nil {
+			// Close закрывает файл, не дожидаясь конца воспроизведения
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("ошибка чтения кадра %d: %w", i, err)
+		}
+
+		due := start.Add(time.Duration(float64(au.dts) / speed))
+		if wait := time.Until(due); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-timer.C:
+			}
+		} else if ctx.Err() != nil {
+			return nil
+		}
+
+		s.stats.addPacket(au.size)
+
+		if rec := s.recorder.Load(); rec != nil {
+			rec.WriteAccessUnit(au.nalus, int64(au.pts)*90000/int64(time.Second))
+		}
+
+		dec.SetOutputSize(s.OutputSize())
+
+		img, err := dec.Decode(au.nalus)
+		if err != nil || img == nil {
+			continue
+		}
+
+		s.stats.addFrame(dec.SourceSize())
+
+		safeImage := createSafeImageCopy(img, framePool)
+		if safeImage == nil {
+			continue
+		}
+
+		frame := model.NewFrameData(safeImage, time.Now(), func() {
+			framePool.Put(safeImage)
+		})
+
+		select {
+		case frameChannel <- frame:
+		case <-ctx.Done():
+			frame.Release()
+			return nil
+		default:
+			frame.Release()
+		}
+	}
+	return nil
+}
+
+// SetRecorder подключает буфер записи клипов, как у rtsp.Client.
+func (s *Source) SetRecorder(recorder *record.Recorder) {
+	s.recorder.Store(recorder)
+}
+
+func (s *Source) SetLogger(logger *slog.Logger) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.logger = logger
+}
+
+func (s *Source) log() *slog.Logger {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if s.logger == nil {
+		return slog.Default()
+	}
+	return s.logger
+}
+
+func (s *Source) SetOutputSize(width, height int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.outputWidth = width
+	s.outputHeight = height
+}
+
+func (s *Source) OutputSize() (int, int) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.outputWidth, s.outputHeight
+}
+
+// Stats возвращает разрешение, FPS и битрейт воспроизведения.
+func (s *Source) Stats() model.StreamInfo {
+	s.stats.mu.Lock()
+	defer s.stats.mu.Unlock()
+
+	return model.StreamInfo{
+		Width:          s.stats.width,
+		Height:         s.stats.height,
+		FPS:            s.stats.fps,
+		Bitrate:        s.stats.bitrate,
+		LastFrameTime:  s.stats.lastFrameTime,
+		LastPacketTime: s.stats.lastPacket,
+		PlayingSince:   s.stats.playingSince,
+	}
+}
+
+func (s *Source) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.cancelFunc != nil {
+		s.cancelFunc()
+		s.cancelFunc = nil
+	}
+
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+		s.track = nil
+	}
+	return nil
+}
+
+func createSafeImageCopy(src image.Image, pool *videodecoder.FramePool) *image.RGBA {
+	if src == nil {
+		return nil
+	}
+
+	bounds := src.Bounds()
+	if bounds.Empty() {
+		return nil
+	}
+
+	dst := pool.Get(bounds)
+	if dst == nil {
+		return nil
+	}
+	draw.Draw(dst, bounds, src, bounds.Min, draw.Src)
+
+	return dst
+}
+
+// playbackStats считает FPS и битрейт по окну в одну секунду.
+type playbackStats struct {
+	mu sync.Mutex
+
+	width         int
+	height        int
+	fps           float64
+	bitrate       int64
+	lastFrameTime time.Time
+	lastPacket    time.Time
+	playingSince  time.Time
+
+	windowStart  time.Time
+	windowFrames int
+	windowBytes  int64
+}
+
+func (s *playbackStats) addPacket(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.lastPacket = now
+	s.windowBytes += int64(size)
+	s.roll(now)
+}
+
+func (s *playbackStats) addFrame(width, height int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.width = width
+	s.height = height
+	s.lastFrameTime = now
+	s.windowFrames++
+	s.roll(now)
+}
+
+func (s *playbackStats) setPlaying(since time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.playingSince = since
+}
+
+func (s *playbackStats) roll(now time.Time) {
+	if s.windowStart.IsZero() {
+		s.windowStart = now
+		return
+	}
+
+	elapsed := now.Sub(s.windowStart)
+	if elapsed < statsWindow {
+		return
+	}
+
+	s.fps = float64(s.windowFrames) / elapsed.Seconds()
+	s.bitrate = int64(float64(s.windowBytes*8) / elapsed.Seconds())
+	s.windowStart = now
+	s.windowFrames = 0
+	s.windowBytes = 0
+}
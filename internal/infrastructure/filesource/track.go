@@ -0,0 +1,242 @@
+package filesource
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"ip-camera-viewer/internal/infrastructure/mp4"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bluenviron/mediacommon/v2/pkg/codecs/h264"
+)
+
+const (
+	// maxAnnexBSize ограничивает сырой файл H.264, который читается в память.
+	maxAnnexBSize = 1 << 30
+
+	// defaultFPS используется для сырого H.264 без тайминга в SPS.
+	defaultFPS = 25
+)
+
+// accessUnit — кадр с метками времени от начала файла.
+type accessUnit struct {
+	nalus [][]byte
+	size  int
+	dts   time.Duration
+	pts   time.Duration
+}
+
+// track — дорожка H.264, прочитанная из файла.
+type track interface {
+	params() (sps, pps []byte)
+	len() int
+	sample(i int) (accessUnit, error)
+}
+
+// openTrack выбирает разбор по расширению, а при неизвестном расширении —
+// по сигнатуре ftyp в начале файла.
+func openTrack(file *os.File) (track, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(filepath.Ext(file.Name())) {
+	case ".mp4", ".m4v", ".mov", ".m4s":
+		return newMP4Track(file, info.Size())
+	case ".h264", ".264", ".avc", ".h26l":
+		return newAnnexBTrack(file, info.Size())
+	}
+
+	var header [8]byte
+	if _, err := file.ReadAt(header[:], 0); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if string(header[4:8]) == "ftyp" {
+		return newMP4Track(file, info.Size())
+	}
+	return newAnnexBTrack(file, info.Size())
+}
+
+type mp4Track struct {
+	reader *mp4.Reader
+}
+
+func newMP4Track(file *os.File, size int64) (*mp4Track, error) {
+	reader, err := mp4.NewReader(file, size)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка разбора MP4: %w", err)
+	}
+	return &mp4Track{reader: reader}, nil
+}
+
+func (t *mp4Track) params() ([]byte, []byte) {
+	return t.reader.SPS, t.reader.PPS
+}
+
+func (t *mp4Track) len() int {
+	return len(t.reader.Samples)
+}
+
+func (t *mp4Track) sample(i int) (accessUnit, error) {
+	info := t.reader.Samples[i]
+	nalus, err := t.reader.ReadSample(info)
+	if err != nil {
+		return accessUnit{}, err
+	}
+
+	start := t.reader.Samples[0].DTS
+	scale := int64(t.reader.TimeScale)
+	return accessUnit{
+		nalus: nalus,
+		size:  int(info.Size),
+		dts:   time.Duration((info.DTS - start) * int64(time.Second) / scale),
+		pts:   time.Duration((info.DTS - start + int64(info.PTSOffset)) * int64(time.Second) / scale),
+	}, nil
+}
+
+// annexBTrack — сырой поток с стартовыми кодами. В нём нет меток времени,
+// поэтому кадры идут с частотой из SPS или defaultFPS.
+type annexBTrack struct {
+	units    [][][]byte
+	sps      []byte
+	pps      []byte
+	interval time.Duration
+}
+
+func newAnnexBTrack(file *os.File, size int64) (*annexBTrack, error) {
+	if size > maxAnnexBSize {
+		return nil, fmt.Errorf("файл H.264 больше %d МБ", maxAnnexBSize>>20)
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(io.NewSectionReader(file, 0, size), data); err != nil {
+		return nil, err
+	}
+
+	t := &annexBTrack{units: splitAccessUnits(splitNALUs(data))}
+	if len(t.units) == 0 {
+		return nil, errors.New("в файле не найдено NAL единиц H.264")
+	}
+
+	for _, au := range t.units {
+		for _, nalu := range au {
+			switch h264.NALUType(nalu[0] & 0x1f) {
+			case h264.NALUTypeSPS:
+				if t.sps == nil {
+					t.sps = nalu
+				}
+			case h264.NALUTypePPS:
+				if t.pps == nil {
+					t.pps = nalu
+				}
+			}
+		}
+	}
+	if t.sps == nil {
+		return nil, errors.New("в файле нет SPS")
+	}
+
+	fps := float64(defaultFPS)
+	var sps h264.SPS
+	if err := sps.Unmarshal(t.sps); err == nil {
+		if v := sps.FPS(); v >= 1 && v <= 240 {
+			fps = v
+		}
+	}
+	t.interval = time.Duration(float64(time.Second) / fps)
+	return t, nil
+}
+
+func (t *annexBTrack) params() ([]byte, []byte) {
+	return t.sps, t.pps
+}
+
+func (t *annexBTrack) len() int {
+	return len(t.units)
+}
+
+func (t *annexBTrack) sample(i int) (accessUnit, error) {
+	size := 0
+	for _, nalu := range t.units[i] {
+		size += len(nalu)
+	}
+	ts := time.Duration(i) * t.interval
+	return accessUnit{nalus: t.units[i], size: size, dts: ts, pts: ts}, nil
+}
+
+// splitNALUs делит поток по стартовым кодам 00 00 01 и 00 00 00 01.
+func splitNALUs(data []byte) [][]byte {
+	startCode := []byte{0, 0, 1}
+	var nalus [][]byte
+
+	pos := bytes.Index(data, startCode)
+	for pos >= 0 {
+		begin := pos + len(startCode)
+		next := bytes.Index(data[begin:], startCode)
+
+		end := len(data)
+		if next >= 0 {
+			end = begin + next
+		}
+
+		nalu := bytes.TrimRight(data[begin:end], "\x00")
+		if len(nalu) > 0 {
+			nalus = append(nalus, nalu)
+		}
+
+		if next < 0 {
+			break
+		}
+		pos = begin + next
+	}
+	return nalus
+}
+
+// splitAccessUnits группирует NAL единицы в кадры. Новый кадр начинается с
+// разделителя AUD, с SPS/PPS/SEI после слайсов или со слайса, у которого
+// first_mb_in_slice равен нулю.
+func splitAccessUnits(nalus [][]byte) [][][]byte {
+	var units [][][]byte
+	var current [][]byte
+	hasSlice := false
+
+	// наборы параметров перед первым слайсом остаются в текущем кадре
+	flush := func() {
+		if !hasSlice {
+			return
+		}
+		units = append(units, current)
+		current = nil
+		hasSlice = false
+	}
+
+	for _, nalu := range nalus {
+		switch typ := h264.NALUType(nalu[0] & 0x1f); typ {
+		case h264.NALUTypeAccessUnitDelimiter:
+			flush()
+			continue
+
+		case h264.NALUTypeSPS, h264.NALUTypePPS, h264.NALUTypeSEI:
+			if hasSlice {
+				flush()
+			}
+
+		case h264.NALUTypeIDR, h264.NALUTypeNonIDR:
+			// first_mb_in_slice = 0 кодируется в ue(v) одним единичным битом
+			if hasSlice && len(nalu) > 1 && nalu[1]&0x80 != 0 {
+				flush()
+			}
+			hasSlice = true
+		}
+
+		current = append(current, nalu)
+	}
+	flush()
+
+	return units
+}
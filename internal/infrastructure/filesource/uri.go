@@ -0,0 +1,61 @@
+package filesource
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const maxSpeed = 16
+
+// Options — параметры воспроизведения из file:// URI:
+// file:///path/clip.mp4?speed=2&loop=1.
+type Options struct {
+	Path  string
+	Speed float64
+	Loop  bool
+}
+
+// IsFileURI сообщает, что URI указывает на локальный файл.
+func IsFileURI(uri string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(uri)), "file://")
+}
+
+// ParseURI разбирает file:// URI. Скорость по умолчанию 1 (исходная),
+// повтор по умолчанию выключен.
+func ParseURI(uri string) (Options, error) {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil {
+		return Options{}, err
+	}
+	if !strings.EqualFold(u.Scheme, "file") {
+		return Options{}, errors.New("ожидается URI вида file://")
+	}
+
+	opts := Options{Path: u.Path, Speed: 1}
+	// file://C:/video.mp4 — диск Windows попадает в хост
+	if u.Host != "" && !strings.EqualFold(u.Host, "localhost") {
+		opts.Path = u.Host + u.Path
+	}
+	if opts.Path == "" || strings.HasSuffix(opts.Path, "/") {
+		return Options{}, errors.New("в URI не указан путь к файлу")
+	}
+
+	query := u.Query()
+	if v := query.Get("speed"); v != "" {
+		opts.Speed, err = strconv.ParseFloat(v, 64)
+		if err != nil || opts.Speed <= 0 || opts.Speed > maxSpeed {
+			return Options{}, fmt.Errorf("скорость воспроизведения должна быть больше 0 и не больше %d", maxSpeed)
+		}
+	}
+	if v := query.Get("loop"); v != "" {
+		opts.Loop, err = strconv.ParseBool(v)
+		if err != nil {
+			return Options{}, errors.New("параметр loop принимает 1 или 0")
+		}
+	}
+
+	return opts, nil
+}
@@ -0,0 +1,281 @@
+package health
+
+import (
+	"hash/fnv"
+	"image"
+	"ip-camera-viewer/internal/domain/model"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+const (
+	gridWidth        = 160
+	analysisInterval = 200 * time.Millisecond
+
+	// movedDiff — разница яркости ячейки, которая считается изменением
+	// при проверке сдвига камеры.
+	movedDiff = 40
+	// movedHold — сколько держится предупреждение о сдвиге после скачка.
+	movedHold = 10 * time.Second
+
+	// baselineAlpha — скорость, с которой обычная резкость камеры
+	// подстраивается под сцену.
+	baselineAlpha = 0.02
+	// baselineWarmup — число анализов до первой проверки расфокусировки.
+	baselineWarmup = 25
+)
+
+// Analyzer следит за изображением уже идущего потока: застывшие, чёрные,
+// закрытые и расфокусированные кадры, резкий сдвиг камеры. Process
+// вызывается из одной горутины, SetConfig — из любой.
+type Analyzer struct {
+	mu     sync.Mutex
+	config model.HealthConfig
+
+	width  int
+	height int
+	cur    []uint8
+	prev   []uint8
+
+	lastAnalysis time.Time
+	prevBlack    bool
+	lastHash     uint64
+	lastChange   time.Time
+	since        map[model.VideoWarning]time.Time
+	movedUntil   time.Time
+
+	edgeBaseline    float64
+	baselineSamples int
+
+	warnings []model.VideoWarning
+}
+
+func NewAnalyzer(config model.HealthConfig) *Analyzer {
+	return &Analyzer{
+		config: config,
+		since:  make(map[model.VideoWarning]time.Time),
+	}
+}
+
+func (a *Analyzer) SetConfig(config model.HealthConfig) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.config = config
+}
+
+// Process анализирует кадр и возвращает текущие предупреждения и признак
+// того, что их набор изменился с прошлого вызова.
+func (a *Analyzer) Process(img image.Image, timestamp time.Time) ([]model.VideoWarning, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if img == nil || timestamp.Sub(a.lastAnalysis) < analysisInterval {
+		return a.warnings, false
+	}
+	a.lastAnalysis = timestamp
+
+	hash := frameHash(img)
+	if hash != a.lastHash || a.lastChange.IsZero() {
+		a.lastHash = hash
+		a.lastChange = timestamp
+	}
+
+	a.downscale(img)
+	if a.width == 0 {
+		return a.warnings, false
+	}
+
+	mean, contrast, edges := a.measure()
+	config := a.config
+
+	black := mean < config.BlackLuma
+	covered := !black && contrast < config.CoveredContrast
+	uniform := black || covered
+
+	defocused := false
+	if !uniform {
+		switch {
+		case a.baselineSamples == 0:
+			a.edgeBaseline = edges
+			a.baselineSamples++
+		case edges < a.edgeBaseline*config.DefocusRatio:
+			defocused = a.baselineSamples >= baselineWarmup
+		default:
+			a.edgeBaseline += (edges - a.edgeBaseline) * baselineAlpha
+			a.baselineSamples++
+		}
+	}
+
+	// включение и выключение света меняет весь кадр, но сдвигом не является
+	if config.MovedChange > 0 && a.prev != nil && !black && !a.prevBlack && a.changedPercent() >= config.MovedChange {
+		a.movedUntil = timestamp.Add(movedHold)
+	}
+	a.prev, a.cur = a.cur, a.prev
+	a.prevBlack = black
+
+	frozenFor := timestamp.Sub(a.lastChange)
+	frozen := config.FrozenAfter > 0 && !uniform && frozenFor >= seconds(config.FrozenAfter)
+
+	persist := seconds(config.Persist)
+	var warnings []model.VideoWarning
+	if frozen {
+		warnings = append(warnings, model.WarningFrozen)
+	}
+	if a.held(model.WarningBlack, black, timestamp, persist) {
+		warnings = append(warnings, model.WarningBlack)
+	}
+	if a.held(model.WarningCovered, covered, timestamp, persist) {
+		warnings = append(warnings, model.WarningCovered)
+	}
+	if a.held(model.WarningDefocused, defocused, timestamp, persist) {
+		warnings = append(warnings, model.WarningDefocused)
+	}
+	if timestamp.Before(a.movedUntil) {
+		warnings = append(warnings, model.WarningMoved)
+	}
+
+	changed := !slices.Equal(warnings, a.warnings)
+	a.warnings = warnings
+	return warnings, changed
+}
+
+// held сообщает, держится ли признак дольше persist.
+func (a *Analyzer) held(warning model.VideoWarning, present bool, timestamp time.Time, persist time.Duration) bool {
+	if !present {
+		delete(a.since, warning)
+		return false
+	}
+
+	since, ok := a.since[warning]
+	if !ok {
+		a.since[warning] = timestamp
+		return persist <= 0
+	}
+	return timestamp.Sub(since) >= persist
+}
+
+// frameHash хэширует пиксели кадра целиком: повторно декодированный
+// одинаковый кадр даёт тот же хэш, а шум живой камеры — нет.
+func frameHash(img image.Image) uint64 {
+	h := fnv.New64a()
+
+	if rgba, ok := img.(*image.RGBA); ok {
+		h.Write(rgba.Pix)
+		return h.Sum64()
+	}
+
+	bounds := img.Bounds()
+	buf := make([]byte, 0, 4*bounds.Dx())
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		buf = buf[:0]
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, _ := img.At(x, y).RGBA()
+			buf = append(buf, byte(r>>8), byte(g>>8), byte(b>>8))
+		}
+		h.Write(buf)
+	}
+	return h.Sum64()
+}
+
+// downscale усредняет яркость блоков кадра в сетку gridWidth × height.
+func (a *Analyzer) downscale(img image.Image) {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= 0 || srcH <= 0 {
+		return
+	}
+
+	width := min(gridWidth, srcW)
+	height := max(1, width*srcH/srcW)
+
+	if width != a.width || height != a.height {
+		a.width, a.height = width, height
+		a.cur = make([]uint8, width*height)
+		a.prev = nil
+		a.baselineSamples = 0
+	}
+	if a.cur == nil {
+		a.cur = make([]uint8, width*height)
+	}
+
+	rgba, isRGBA := img.(*image.RGBA)
+
+	for gy := 0; gy < height; gy++ {
+		y0 := bounds.Min.Y + gy*srcH/height
+		y1 := bounds.Min.Y + (gy+1)*srcH/height
+
+		for gx := 0; gx < width; gx++ {
+			x0 := bounds.Min.X + gx*srcW/width
+			x1 := bounds.Min.X + (gx+1)*srcW/width
+
+			var sum, count uint32
+			for y := y0; y < y1; y++ {
+				if isRGBA {
+					row := rgba.Pix[rgba.PixOffset(x0, y):rgba.PixOffset(x1, y)]
+					for i := 0; i+3 < len(row); i += 4 {
+						sum += luma(uint32(row[i]), uint32(row[i+1]), uint32(row[i+2]))
+						count++
+					}
+					continue
+				}
+
+				for x := x0; x < x1; x++ {
+					r, g, b, _ := img.At(x, y).RGBA()
+					sum += luma(r>>8, g>>8, b>>8)
+					count++
+				}
+			}
+
+			if count > 0 {
+				a.cur[gy*width+gx] = uint8(sum / count)
+			}
+		}
+	}
+}
+
+func luma(r, g, b uint32) uint32 {
+	return (299*r + 587*g + 114*b) / 1000
+}
+
+// measure возвращает среднюю яркость, её стандартное отклонение и
+// среднюю величину градиента (энергию границ) по сетке.
+func (a *Analyzer) measure() (float64, float64, float64) {
+	var sum, sumSq, edges float64
+	for gy := 0; gy < a.height; gy++ {
+		for gx := 0; gx < a.width; gx++ {
+			v := float64(a.cur[gy*a.width+gx])
+			sum += v
+			sumSq += v * v
+
+			if gx+1 < a.width {
+				edges += math.Abs(float64(a.cur[gy*a.width+gx+1]) - v)
+			}
+			if gy+1 < a.height {
+				edges += math.Abs(float64(a.cur[(gy+1)*a.width+gx]) - v)
+			}
+		}
+	}
+
+	n := float64(len(a.cur))
+	mean := sum / n
+	variance := max(sumSq/n-mean*mean, 0)
+	return mean, math.Sqrt(variance), edges / n
+}
+
+func (a *Analyzer) changedPercent() float64 {
+	changed := 0
+	for i := range a.cur {
+		diff := int(a.cur[i]) - int(a.prev[i])
+		if diff > movedDiff || diff < -movedDiff {
+			changed++
+		}
+	}
+	return float64(changed) * 100 / float64(len(a.cur))
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
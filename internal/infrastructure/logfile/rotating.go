@@ -0,0 +1,261 @@
+package logfile
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const backupTimeFormat = "20060102T150405.000"
+
+// rename подменяется в тестах, чтобы проверить неудачную ротацию.
+var rename = os.Rename
+
+type Options struct {
+	// Path — путь к текущему файлу журнала.
+	Path string
+	// MaxSize — размер в байтах, после которого файл ротируется. 0 — без ограничения.
+	MaxSize int64
+	// MaxAge — возраст файла, после которого он ротируется. 0 — без ограничения.
+	MaxAge time.Duration
+	// MaxBackups — сколько старых файлов хранить. 0 — хранить все.
+	MaxBackups int
+	// Compress сжимает ротированные файлы gzip.
+	Compress bool
+}
+
+// RotatingWriter пишет журнал в файл и переименовывает его по размеру или
+// возрасту в name-<время>.ext, при необходимости сжимая копию.
+type RotatingWriter struct {
+	opts Options
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+func NewRotatingWriter(opts Options) (*RotatingWriter, error) {
+	if opts.Path == "" {
+		return nil, fmt.Errorf("не указан путь к файлу журнала")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(opts.Path), 0700); err != nil {
+		return nil, fmt.Errorf("не удалось создать каталог журналов: %w", err)
+	}
+
+	w := &RotatingWriter{opts: opts}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, os.ErrClosed
+	}
+
+	// файл мог остаться закрытым после неудачной ротации
+	if w.file == nil {
+		if err := w.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	// если ротация не удалась, запись продолжается в прежний файл, а
+	// ротация повторится со следующей записью
+	if w.needsRotation(int64(len(p))) {
+		if err := w.rotate(); err != nil && w.file == nil {
+			return 0, err
+		}
+	}
+
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// Rotate принудительно начинает новый файл.
+func (w *RotatingWriter) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return os.ErrClosed
+	}
+	return w.rotate()
+}
+
+// Path возвращает путь к текущему файлу журнала.
+func (w *RotatingWriter) Path() string {
+	return w.opts.Path
+}
+
+// Files возвращает текущий файл и все сохранённые копии, от новых к старым.
+func (w *RotatingWriter) Files() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return append([]string{w.opts.Path}, w.backups()...)
+}
+
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	var err error
+	w.closed = true
+	if w.file != nil {
+		err = w.file.Close()
+		w.file = nil
+	}
+	w.mu.Unlock()
+
+	w.wg.Wait()
+	return err
+}
+
+func (w *RotatingWriter) needsRotation(incoming int64) bool {
+	if w.opts.MaxSize > 0 && w.size > 0 && w.size+incoming > w.opts.MaxSize {
+		return true
+	}
+	return w.opts.MaxAge > 0 && time.Since(w.openedAt) > w.opts.MaxAge
+}
+
+func (w *RotatingWriter) open() error {
+	file, err := os.OpenFile(w.opts.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return fmt.Errorf("не удалось открыть файл журнала: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = info.Size()
+	w.openedAt = info.ModTime()
+	if w.size == 0 {
+		w.openedAt = time.Now()
+	}
+
+	return nil
+}
+
+// rotate переименовывает текущий файл и открывает новый. Если
+// переименовать не удалось, снова открывается прежний файл, чтобы журнал
+// не остался без файла.
+func (w *RotatingWriter) rotate() error {
+	if w.file != nil {
+		err := w.file.Close()
+		w.file = nil
+		if err != nil {
+			return errors.Join(err, w.open())
+		}
+	}
+
+	ext := filepath.Ext(w.opts.Path)
+	base := strings.TrimSuffix(w.opts.Path, ext)
+	backup := base + "-" + time.Now().Format(backupTimeFormat) + ext
+
+	if err := rename(w.opts.Path, backup); err != nil && !os.IsNotExist(err) {
+		return errors.Join(fmt.Errorf("не удалось ротировать файл журнала: %w", err), w.open())
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+
+		if w.opts.Compress {
+			compressFile(backup)
+		}
+
+		w.mu.Lock()
+		stale := w.staleBackups()
+		w.mu.Unlock()
+
+		for _, name := range stale {
+			os.Remove(name)
+		}
+	}()
+
+	return nil
+}
+
+// backups возвращает ротированные копии, от новых к старым.
+func (w *RotatingWriter) backups() []string {
+	ext := filepath.Ext(w.opts.Path)
+	base := strings.TrimSuffix(w.opts.Path, ext)
+
+	matches, _ := filepath.Glob(base + "-*" + ext + "*")
+	var backups []string
+	for _, name := range matches {
+		if strings.HasSuffix(name, ext) || strings.HasSuffix(name, ext+".gz") {
+			backups = append(backups, name)
+		}
+	}
+
+	// метка времени в имени сортируется лексикографически
+	sort.Sort(sort.Reverse(sort.StringSlice(backups)))
+	return backups
+}
+
+func (w *RotatingWriter) staleBackups() []string {
+	if w.opts.MaxBackups <= 0 {
+		return nil
+	}
+
+	backups := w.backups()
+	if len(backups) <= w.opts.MaxBackups {
+		return nil
+	}
+	return backups[w.opts.MaxBackups:]
+}
+
+func compressFile(name string) {
+	src, err := os.Open(name)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return
+	}
+
+	gz := gzip.NewWriter(dst)
+	_, err = io.Copy(gz, src)
+	if closeErr := gz.Close(); err == nil {
+		err = closeErr
+	}
+	if closeErr := dst.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err != nil {
+		os.Remove(name + ".gz")
+		return
+	}
+
+	src.Close()
+	os.Remove(name)
+}
@@ -0,0 +1,176 @@
+package logfile
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestWriter(t *testing.T, opts Options) *RotatingWriter {
+	t.Helper()
+
+	opts.Path = filepath.Join(t.TempDir(), "app.log")
+	w, err := NewRotatingWriter(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { w.Close() })
+	return w
+}
+
+func write(t *testing.T, w *RotatingWriter, s string) {
+	t.Helper()
+
+	if _, err := w.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// rotate принудительно ротирует файл. Имя копии содержит миллисекунды,
+// поэтому между ротациями выдерживается пауза.
+func rotate(t *testing.T, w *RotatingWriter) {
+	t.Helper()
+
+	if err := w.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(2 * time.Millisecond)
+}
+
+func readLog(t *testing.T, name string) string {
+	t.Helper()
+
+	file, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(name, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// contents возвращает содержимое текущего файла и копий, от новых к старым.
+func contents(t *testing.T, w *RotatingWriter) []string {
+	t.Helper()
+
+	var result []string
+	for _, name := range w.Files() {
+		result = append(result, readLog(t, name))
+	}
+	return result
+}
+
+func equal(a, b []string) bool {
+	return strings.Join(a, "|") == strings.Join(b, "|")
+}
+
+func TestRotatesBySize(t *testing.T) {
+	w := newTestWriter(t, Options{MaxSize: 10})
+
+	write(t, w, "aaaa\n")
+	write(t, w, "bbb\n")
+	// запись больше MaxSize ротирует файл, но сама целиком попадает в новый
+	write(t, w, "cccccccccccc\n")
+	w.Close()
+
+	want := []string{"cccccccccccc\n", "aaaa\nbbb\n"}
+	if got := contents(t, w); !equal(got, want) {
+		t.Errorf("файлы %q, ожидалось %q", got, want)
+	}
+}
+
+func TestRotatesByAge(t *testing.T) {
+	w := newTestWriter(t, Options{MaxAge: 50 * time.Millisecond})
+
+	write(t, w, "old\n")
+	write(t, w, "fresh\n")
+	time.Sleep(60 * time.Millisecond)
+	write(t, w, "new\n")
+	w.Close()
+
+	want := []string{"new\n", "old\nfresh\n"}
+	if got := contents(t, w); !equal(got, want) {
+		t.Errorf("файлы %q, ожидалось %q", got, want)
+	}
+}
+
+func TestMaxBackupsPrunesOldest(t *testing.T) {
+	w := newTestWriter(t, Options{MaxBackups: 2})
+
+	for _, line := range []string{"1\n", "2\n", "3\n", "4\n"} {
+		write(t, w, line)
+		rotate(t, w)
+	}
+	write(t, w, "5\n")
+	w.Close()
+
+	want := []string{"5\n", "4\n", "3\n"}
+	if got := contents(t, w); !equal(got, want) {
+		t.Errorf("файлы %q, ожидалось %q", got, want)
+	}
+}
+
+func TestCompressesBackups(t *testing.T) {
+	w := newTestWriter(t, Options{Compress: true, MaxBackups: 1})
+
+	write(t, w, "first\n")
+	rotate(t, w)
+	write(t, w, "second\n")
+	rotate(t, w)
+	write(t, w, "third\n")
+	w.Close()
+
+	files := w.Files()
+	if len(files) != 2 || !strings.HasSuffix(files[1], ".log.gz") {
+		t.Fatalf("ожидался текущий файл и одна сжатая копия: %q", files)
+	}
+
+	want := []string{"third\n", "second\n"}
+	if got := contents(t, w); !equal(got, want) {
+		t.Errorf("файлы %q, ожидалось %q", got, want)
+	}
+}
+
+func TestWriteContinuesAfterFailedRotation(t *testing.T) {
+	w := newTestWriter(t, Options{MaxSize: 10})
+
+	failure := errors.New("файл занят")
+	rename = func(string, string) error { return failure }
+	defer func() { rename = os.Rename }()
+
+	write(t, w, "aaaaaa\n")
+	// ротация не удалась, запись продолжается в прежний файл
+	write(t, w, "bbbbbb\n")
+	if err := w.Rotate(); !errors.Is(err, failure) {
+		t.Errorf("Rotate вернул %v, ожидалось %v", err, failure)
+	}
+	write(t, w, "cccccc\n")
+
+	rename = os.Rename
+	write(t, w, "dddddd\n")
+	w.Close()
+
+	want := []string{"dddddd\n", "aaaaaa\nbbbbbb\ncccccc\n"}
+	if got := contents(t, w); !equal(got, want) {
+		t.Errorf("файлы %q, ожидалось %q", got, want)
+	}
+}
@@ -0,0 +1,37 @@
+package logfile
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// UserStateDir возвращает каталог для изменяемых данных приложения
+// (журналы, история): $XDG_STATE_HOME или ~/.local/state в Linux,
+// ~/Library/Logs в macOS и %LocalAppData% в Windows.
+func UserStateDir() (string, error) {
+	switch runtime.GOOS {
+	case "windows":
+		if dir := os.Getenv("LocalAppData"); dir != "" {
+			return dir, nil
+		}
+		return os.UserCacheDir()
+
+	case "darwin", "ios":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, "Library", "Logs"), nil
+
+	default:
+		if dir := os.Getenv("XDG_STATE_HOME"); dir != "" && filepath.IsAbs(dir) {
+			return dir, nil
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		return filepath.Join(home, ".local", "state"), nil
+	}
+}
@@ -0,0 +1,301 @@
+package motion
+
+import (
+	"image"
+	"ip-camera-viewer/internal/domain/model"
+	"sync"
+	"time"
+)
+
+const (
+	// gridWidth — ширина уменьшенного кадра в оттенках серого, на котором
+	// сравниваются соседние кадры. Высота берётся по пропорциям кадра.
+	gridWidth = 160
+
+	// analysisInterval ограничивает анализ пятью кадрами в секунду.
+	analysisInterval = 200 * time.Millisecond
+
+	// holdTime — сколько кадр должен оставаться неподвижным, чтобы эпизод
+	// движения считался законченным.
+	holdTime = 2 * time.Second
+)
+
+// Detector сравнивает соседние кадры и сообщает о начале и конце движения.
+// Вызовы Process должны идти из одной горутины; SetConfig безопасен из любой.
+type Detector struct {
+	mu     sync.Mutex
+	config model.MotionConfig
+
+	width     int
+	height    int
+	prev      []uint8
+	cur       []uint8
+	changed   []bool
+	masked    []bool
+	maskDirty bool
+
+	lastAnalysis time.Time
+	lastMotion   time.Time
+	active       bool
+	episode      model.MotionEvent
+}
+
+func NewDetector(config model.MotionConfig) *Detector {
+	return &Detector{
+		config:    config,
+		maskDirty: true,
+	}
+}
+
+func (d *Detector) SetConfig(config model.MotionConfig) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.config = config
+	d.maskDirty = true
+}
+
+func (d *Detector) Config() model.MotionConfig {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.config
+}
+
+// Process анализирует кадр и возвращает событие, если эпизод движения
+// начался или закончился, иначе nil. Кадр читается только во время вызова.
+func (d *Detector) Process(img image.Image, timestamp time.Time) *model.MotionEvent {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if !d.config.Enabled || img == nil {
+		d.prev = nil
+		return d.finish(timestamp)
+	}
+
+	if timestamp.Sub(d.lastAnalysis) < analysisInterval {
+		return nil
+	}
+	d.lastAnalysis = timestamp
+
+	d.downscale(img)
+	if d.width == 0 {
+		return nil
+	}
+	if d.maskDirty {
+		d.buildMask()
+	}
+
+	if d.prev == nil {
+		d.prev, d.cur = d.cur, make([]uint8, len(d.cur))
+		return nil
+	}
+
+	box, area := d.compare()
+	d.prev, d.cur = d.cur, d.prev
+
+	if area > 0 && area >= d.config.MinArea {
+		d.lastMotion = timestamp
+
+		if !d.active {
+			d.active = true
+			d.episode = model.MotionEvent{
+				Type:  model.MotionStarted,
+				Time:  timestamp,
+				Start: timestamp,
+				Box:   box,
+				Area:  area,
+			}
+			event := d.episode
+			return &event
+		}
+
+		d.episode.Box = union(d.episode.Box, box)
+		d.episode.Area = max(d.episode.Area, area)
+		return nil
+	}
+
+	if d.active && timestamp.Sub(d.lastMotion) > holdTime {
+		return d.finish(timestamp)
+	}
+	return nil
+}
+
+func (d *Detector) finish(timestamp time.Time) *model.MotionEvent {
+	if !d.active {
+		return nil
+	}
+
+	d.active = false
+	event := d.episode
+	event.Type = model.MotionEnded
+	event.Time = timestamp
+	return &event
+}
+
+// downscale усредняет яркость блоков кадра в сетку gridWidth × height.
+func (d *Detector) downscale(img image.Image) {
+	bounds := img.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= 0 || srcH <= 0 {
+		return
+	}
+
+	width := min(gridWidth, srcW)
+	height := max(1, width*srcH/srcW)
+
+	if width != d.width || height != d.height {
+		d.width, d.height = width, height
+		d.prev = nil
+		d.cur = make([]uint8, width*height)
+		d.changed = make([]bool, width*height)
+		d.maskDirty = true
+	}
+
+	rgba, isRGBA := img.(*image.RGBA)
+
+	for gy := 0; gy < height; gy++ {
+		y0 := bounds.Min.Y + gy*srcH/height
+		y1 := bounds.Min.Y + (gy+1)*srcH/height
+
+		for gx := 0; gx < width; gx++ {
+			x0 := bounds.Min.X + gx*srcW/width
+			x1 := bounds.Min.X + (gx+1)*srcW/width
+
+			var sum, count uint32
+			for y := y0; y < y1; y++ {
+				if isRGBA {
+					row := rgba.Pix[rgba.PixOffset(x0, y):rgba.PixOffset(x1, y)]
+					for i := 0; i+3 < len(row); i += 4 {
+						sum += luma(uint32(row[i]), uint32(row[i+1]), uint32(row[i+2]))
+						count++
+					}
+					continue
+				}
+
+				for x := x0; x < x1; x++ {
+					r, g, b, _ := img.At(x, y).RGBA()
+					sum += luma(r>>8, g>>8, b>>8)
+					count++
+				}
+			}
+
+			if count > 0 {
+				d.cur[gy*width+gx] = uint8(sum / count)
+			}
+		}
+	}
+}
+
+func luma(r, g, b uint32) uint32 {
+	return (299*r + 587*g + 114*b) / 1000
+}
+
+func (d *Detector) buildMask() {
+	d.maskDirty = false
+	if d.width == 0 {
+		return
+	}
+
+	d.masked = make([]bool, d.width*d.height)
+	for gy := 0; gy < d.height; gy++ {
+		cy := (float64(gy) + 0.5) / float64(d.height)
+		for gx := 0; gx < d.width; gx++ {
+			cx := (float64(gx) + 0.5) / float64(d.width)
+			for _, zone := range d.config.Zones {
+				if zone.Contains(cx, cy) {
+					d.masked[gy*d.width+gx] = true
+					break
+				}
+			}
+		}
+	}
+}
+
+// compare отмечает изменившиеся ячейки вне зон маски, отбрасывает
+// одиночные (шум) и возвращает их общий прямоугольник и площадь в процентах.
+func (d *Detector) compare() (model.Zone, float64) {
+	threshold := pixelThreshold(d.config.Sensitivity)
+
+	unmasked := 0
+	for i := range d.cur {
+		d.changed[i] = false
+		if d.masked[i] {
+			continue
+		}
+		unmasked++
+
+		diff := int(d.cur[i]) - int(d.prev[i])
+		if diff < 0 {
+			diff = -diff
+		}
+		d.changed[i] = diff > threshold
+	}
+
+	if unmasked == 0 {
+		return model.Zone{}, 0
+	}
+
+	minX, minY, maxX, maxY := d.width, d.height, -1, -1
+	count := 0
+
+	for gy := 0; gy < d.height; gy++ {
+		for gx := 0; gx < d.width; gx++ {
+			if !d.changed[gy*d.width+gx] || d.neighbours(gx, gy) < 2 {
+				continue
+			}
+
+			count++
+			minX, maxX = min(minX, gx), max(maxX, gx)
+			minY, maxY = min(minY, gy), max(maxY, gy)
+		}
+	}
+
+	if count == 0 {
+		return model.Zone{}, 0
+	}
+
+	box := model.Zone{
+		X: float64(minX) / float64(d.width),
+		Y: float64(minY) / float64(d.height),
+		W: float64(maxX-minX+1) / float64(d.width),
+		H: float64(maxY-minY+1) / float64(d.height),
+	}
+	return box, float64(count) * 100 / float64(unmasked)
+}
+
+func (d *Detector) neighbours(gx, gy int) int {
+	count := 0
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			x, y := gx+dx, gy+dy
+			if (dx == 0 && dy == 0) || x < 0 || y < 0 || x >= d.width || y >= d.height {
+				continue
+			}
+			if d.changed[y*d.width+x] {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// pixelThreshold переводит чувствительность 1..100 в порог разницы яркости
+// от 100 (почти нечувствительно) до 8.
+func pixelThreshold(sensitivity int) int {
+	sensitivity = min(max(sensitivity, 1), 100)
+	return 8 + (100-sensitivity)*92/99
+}
+
+func union(a, b model.Zone) model.Zone {
+	if a.Empty() {
+		return b
+	}
+	if b.Empty() {
+		return a
+	}
+
+	x0, y0 := min(a.X, b.X), min(a.Y, b.Y)
+	x1, y1 := max(a.X+a.W, b.X+b.W), max(a.Y+a.H, b.Y+b.H)
+	return model.Zone{X: x0, Y: y0, W: x1 - x0, H: y1 - y0}
+}
@@ -0,0 +1,636 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// maxHeaderBoxSize ограничивает moov и moof, которые читаются в память целиком.
+const maxHeaderBoxSize = 256 << 20
+
+// SampleInfo — положение сэмпла в файле и его метки времени в единицах
+// TimeScale дорожки.
+type SampleInfo struct {
+	Offset    int64
+	Size      uint32
+	DTS       int64
+	PTSOffset int32
+	Sync      bool
+}
+
+// Reader находит первую дорожку H.264 в обычном MP4 или во фрагментированном
+// fMP4 и строит таблицу её сэмплов. Данные сэмплов читаются по требованию.
+type Reader struct {
+	r io.ReaderAt
+
+	SPS       []byte
+	PPS       []byte
+	TimeScale uint32
+	Samples   []SampleInfo
+
+	trackID    uint32
+	lengthSize int
+	defaults   trackDefaults
+	nextDTS    int64
+}
+
+type trackDefaults struct {
+	duration uint32
+	size     uint32
+	flags    uint32
+}
+
+type rawBox struct {
+	typ    string
+	offset int64
+	data   []byte
+}
+
+func NewReader(r io.ReaderAt, size int64) (*Reader, error) {
+	rd := &Reader{r: r}
+
+	for offset := int64(0); offset < size; {
+		typ, boxSize, headerSize, err := readBoxHeader(r, offset, size)
+		if err != nil {
+			return nil, err
+		}
+
+		switch typ {
+		case "moov", "moof":
+			if boxSize > maxHeaderBoxSize {
+				return nil, fmt.Errorf("слишком большой блок %s", typ)
+			}
+			data := make([]byte, boxSize-headerSize)
+			if _, err := r.ReadAt(data, offset+headerSize); err != nil {
+				return nil, err
+			}
+
+			if typ == "moov" {
+				err = rd.parseMoov(data)
+			} else {
+				err = rd.parseMoof(data, offset)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		offset += boxSize
+	}
+
+	if rd.trackID == 0 {
+		return nil, errors.New("в файле нет дорожки H.264")
+	}
+	if len(rd.Samples) == 0 {
+		return nil, errors.New("в дорожке H.264 нет кадров")
+	}
+
+	sort.SliceStable(rd.Samples, func(i, j int) bool {
+		return rd.Samples[i].DTS < rd.Samples[j].DTS
+	})
+	return rd, nil
+}
+
+// ReadSample читает сэмпл и разбивает его на NAL единицы.
+func (rd *Reader) ReadSample(sample SampleInfo) ([][]byte, error) {
+	data := make([]byte, sample.Size)
+	if _, err := rd.r.ReadAt(data, sample.Offset); err != nil {
+		return nil, err
+	}
+
+	var au [][]byte
+	for len(data) > 0 {
+		if len(data) < rd.lengthSize {
+			return nil, errors.New("обрезанная длина NAL")
+		}
+
+		var n int
+		for i := 0; i < rd.lengthSize; i++ {
+			n = n<<8 | int(data[i])
+		}
+		data = data[rd.lengthSize:]
+
+		if n > len(data) {
+			return nil, errors.New("NAL выходит за границы сэмпла")
+		}
+		au = append(au, data[:n])
+		data = data[n:]
+	}
+	return au, nil
+}
+
+func readBoxHeader(r io.ReaderAt, offset, limit int64) (string, int64, int64, error) {
+	var header [16]byte
+	if _, err := r.ReadAt(header[:8], offset); err != nil {
+		return "", 0, 0, err
+	}
+
+	size := int64(binary.BigEndian.Uint32(header[:4]))
+	typ := string(header[4:8])
+	headerSize := int64(8)
+
+	switch size {
+	case 0:
+		size = limit - offset
+	case 1:
+		if _, err := r.ReadAt(header[8:16], offset+8); err != nil {
+			return "", 0, 0, err
+		}
+		size = int64(binary.BigEndian.Uint64(header[8:16]))
+		headerSize = 16
+	}
+
+	if size < headerSize || offset+size > limit {
+		return "", 0, 0, fmt.Errorf("повреждённый блок %q", typ)
+	}
+	return typ, size, headerSize, nil
+}
+
+// children разбирает вложенные блоки. offset — смещение data в файле.
+func children(data []byte, offset int64) ([]rawBox, error) {
+	var boxes []rawBox
+	for pos := 0; pos < len(data); {
+		if len(data)-pos < 8 {
+			return nil, errors.New("обрезанный заголовок блока")
+		}
+
+		size := int(binary.BigEndian.Uint32(data[pos:]))
+		typ := string(data[pos+4 : pos+8])
+		headerSize := 8
+
+		switch size {
+		case 0:
+			size = len(data) - pos
+		case 1:
+			if len(data)-pos < 16 {
+				return nil, errors.New("обрезанный заголовок блока")
+			}
+			size = int(binary.BigEndian.Uint64(data[pos+8:]))
+			headerSize = 16
+		}
+
+		if size < headerSize || pos+size > len(data) {
+			return nil, fmt.Errorf("повреждённый блок %q", typ)
+		}
+
+		boxes = append(boxes, rawBox{
+			typ:    typ,
+			offset: offset + int64(pos+headerSize),
+			data:   data[pos+headerSize : pos+size],
+		})
+		pos += size
+	}
+	return boxes, nil
+}
+
+func find(boxes []rawBox, path ...string) (rawBox, bool) {
+	for _, b := range boxes {
+		if b.typ != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			return b, true
+		}
+
+		sub, err := children(b.data, b.offset)
+		if err != nil {
+			return rawBox{}, false
+		}
+		return find(sub, path[1:]...)
+	}
+	return rawBox{}, false
+}
+
+func (rd *Reader) parseMoov(data []byte) error {
+	boxes, err := children(data, 0)
+	if err != nil {
+		return err
+	}
+
+	for _, trak := range boxes {
+		if trak.typ != "trak" || rd.trackID != 0 {
+			continue
+		}
+
+		sub, err := children(trak.data, 0)
+		if err != nil {
+			return err
+		}
+
+		stbl, ok := find(sub, "mdia", "minf", "stbl")
+		if !ok {
+			continue
+		}
+		stblBoxes, err := children(stbl.data, 0)
+		if err != nil {
+			return err
+		}
+
+		stsd, ok := find(stblBoxes, "stsd")
+		if !ok || !rd.parseStsd(stsd.data) {
+			continue
+		}
+
+		tkhd, ok := find(sub, "tkhd")
+		mdhd, ok2 := find(sub, "mdia", "mdhd")
+		if !ok || !ok2 {
+			return errors.New("нет tkhd или mdhd")
+		}
+		rd.trackID = parseTrackID(tkhd.data)
+		rd.TimeScale = parseTimeScale(mdhd.data)
+		if rd.TimeScale == 0 {
+			return errors.New("нулевой timescale")
+		}
+
+		if err := rd.parseSampleTable(stblBoxes); err != nil {
+			return err
+		}
+	}
+
+	if trex, ok := find(boxes, "mvex", "trex"); ok && len(trex.data) >= 24 {
+		if binary.BigEndian.Uint32(trex.data[4:]) == rd.trackID {
+			rd.defaults = trackDefaults{
+				duration: binary.BigEndian.Uint32(trex.data[12:]),
+				size:     binary.BigEndian.Uint32(trex.data[16:]),
+				flags:    binary.BigEndian.Uint32(trex.data[20:]),
+			}
+		}
+	}
+	return nil
+}
+
+// parseStsd ищет avc1/avc3 и достаёт SPS и PPS из avcC.
+func (rd *Reader) parseStsd(data []byte) bool {
+	if len(data) < 8 {
+		return false
+	}
+	entries, err := children(data[8:], 0)
+	if err != nil {
+		return false
+	}
+
+	for _, entry := range entries {
+		if entry.typ != "avc1" && entry.typ != "avc3" {
+			continue
+		}
+		// 78 байт полей VisualSampleEntry до вложенных блоков
+		if len(entry.data) < 78 {
+			return false
+		}
+		sub, err := children(entry.data[78:], 0)
+		if err != nil {
+			return false
+		}
+		avcC, ok := find(sub, "avcC")
+		if !ok {
+			return false
+		}
+		return rd.parseAvcC(avcC.data)
+	}
+	return false
+}
+
+func (rd *Reader) parseAvcC(data []byte) bool {
+	if len(data) < 7 {
+		return false
+	}
+	rd.lengthSize = int(data[4]&0x03) + 1
+
+	pos := 5
+	next := func() []byte {
+		if pos+2 > len(data) {
+			return nil
+		}
+		n := int(binary.BigEndian.Uint16(data[pos:]))
+		pos += 2
+		if pos+n > len(data) {
+			return nil
+		}
+		set := data[pos : pos+n]
+		pos += n
+		return set
+	}
+
+	numSPS := int(data[pos] & 0x1f)
+	pos++
+	for i := 0; i < numSPS; i++ {
+		if sps := next(); sps != nil && rd.SPS == nil {
+			rd.SPS = append([]byte(nil), sps...)
+		}
+	}
+
+	if pos >= len(data) {
+		return true
+	}
+	numPPS := int(data[pos])
+	pos++
+	for i := 0; i < numPPS; i++ {
+		if pps := next(); pps != nil && rd.PPS == nil {
+			rd.PPS = append([]byte(nil), pps...)
+		}
+	}
+
+	// у avc3 наборы параметров могут идти только внутри потока
+	return true
+}
+
+func parseTrackID(tkhd []byte) uint32 {
+	if len(tkhd) < 24 {
+		return 0
+	}
+	if tkhd[0] == 1 {
+		return binary.BigEndian.Uint32(tkhd[20:])
+	}
+	return binary.BigEndian.Uint32(tkhd[12:])
+}
+
+func parseTimeScale(mdhd []byte) uint32 {
+	if len(mdhd) < 24 {
+		return 0
+	}
+	if mdhd[0] == 1 {
+		return binary.BigEndian.Uint32(mdhd[20:])
+	}
+	return binary.BigEndian.Uint32(mdhd[12:])
+}
+
+// parseSampleTable строит сэмплы обычного MP4 из stts, ctts, stss, stsc,
+// stsz и stco/co64. У fMP4 эти таблицы пустые.
+func (rd *Reader) parseSampleTable(boxes []rawBox) error {
+	stsz, ok := find(boxes, "stsz")
+	if !ok || len(stsz.data) < 12 {
+		return nil
+	}
+	uniformSize := binary.BigEndian.Uint32(stsz.data[4:])
+	count := int(binary.BigEndian.Uint32(stsz.data[8:]))
+	if count == 0 {
+		return nil
+	}
+	if uniformSize == 0 && len(stsz.data) < 12+4*count {
+		return errors.New("обрезанный stsz")
+	}
+
+	samples := make([]SampleInfo, count)
+	for i := range samples {
+		samples[i].Size = uniformSize
+		if uniformSize == 0 {
+			samples[i].Size = binary.BigEndian.Uint32(stsz.data[12+4*i:])
+		}
+		samples[i].Sync = true
+	}
+
+	if stts, ok := find(boxes, "stts"); ok {
+		var dts int64
+		i := 0
+		for _, e := range entries(stts.data, 8) {
+			for n := binary.BigEndian.Uint32(e); n > 0 && i < count; n-- {
+				samples[i].DTS = dts
+				dts += int64(binary.BigEndian.Uint32(e[4:]))
+				i++
+			}
+		}
+	}
+
+	if ctts, ok := find(boxes, "ctts"); ok {
+		i := 0
+		for _, e := range entries(ctts.data, 8) {
+			for n := binary.BigEndian.Uint32(e); n > 0 && i < count; n-- {
+				samples[i].PTSOffset = int32(binary.BigEndian.Uint32(e[4:]))
+				i++
+			}
+		}
+	}
+
+	if stss, ok := find(boxes, "stss"); ok {
+		for i := range samples {
+			samples[i].Sync = false
+		}
+		for _, e := range entries(stss.data, 4) {
+			if n := int(binary.BigEndian.Uint32(e)); n >= 1 && n <= count {
+				samples[n-1].Sync = true
+			}
+		}
+	}
+
+	var chunkOffsets []int64
+	if stco, ok := find(boxes, "stco"); ok {
+		for _, e := range entries(stco.data, 4) {
+			chunkOffsets = append(chunkOffsets, int64(binary.BigEndian.Uint32(e)))
+		}
+	} else if co64, ok := find(boxes, "co64"); ok {
+		for _, e := range entries(co64.data, 8) {
+			chunkOffsets = append(chunkOffsets, int64(binary.BigEndian.Uint64(e)))
+		}
+	}
+
+	stsc, ok := find(boxes, "stsc")
+	if !ok {
+		return errors.New("нет stsc")
+	}
+	stscEntries := entries(stsc.data, 12)
+
+	i := 0
+	for chunk := range chunkOffsets {
+		perChunk := 0
+		for _, e := range stscEntries {
+			if int(binary.BigEndian.Uint32(e))-1 <= chunk {
+				perChunk = int(binary.BigEndian.Uint32(e[4:]))
+			}
+		}
+
+		offset := chunkOffsets[chunk]
+		for n := 0; n < perChunk && i < count; n++ {
+			samples[i].Offset = offset
+			offset += int64(samples[i].Size)
+			i++
+		}
+	}
+	if i < count {
+		return errors.New("таблица чанков не покрывает все сэмплы")
+	}
+
+	rd.Samples = append(rd.Samples, samples...)
+	return nil
+}
+
+// entries возвращает записи таблицы полного блока: после версии и флагов
+// идёт число записей, затем записи фиксированного размера.
+func entries(data []byte, size int) [][]byte {
+	if len(data) < 8 {
+		return nil
+	}
+	count := int(binary.BigEndian.Uint32(data[4:]))
+	data = data[8:]
+
+	result := make([][]byte, 0, min(count, len(data)/size))
+	for i := 0; i < count && (i+1)*size <= len(data); i++ {
+		result = append(result, data[i*size:(i+1)*size])
+	}
+	return result
+}
+
+func (rd *Reader) parseMoof(data []byte, moofOffset int64) error {
+	if rd.trackID == 0 {
+		return nil
+	}
+
+	boxes, err := children(data, 0)
+	if err != nil {
+		return err
+	}
+
+	for _, traf := range boxes {
+		if traf.typ != "traf" {
+			continue
+		}
+		sub, err := children(traf.data, 0)
+		if err != nil {
+			return err
+		}
+
+		tfhd, ok := find(sub, "tfhd")
+		if !ok || len(tfhd.data) < 8 || binary.BigEndian.Uint32(tfhd.data[4:]) != rd.trackID {
+			continue
+		}
+
+		base, defaults := rd.parseTfhd(tfhd.data, moofOffset)
+
+		if tfdt, ok := find(sub, "tfdt"); ok && len(tfdt.data) >= 8 {
+			if tfdt.data[0] == 1 && len(tfdt.data) >= 12 {
+				rd.nextDTS = int64(binary.BigEndian.Uint64(tfdt.data[4:]))
+			} else {
+				rd.nextDTS = int64(binary.BigEndian.Uint32(tfdt.data[4:]))
+			}
+		}
+
+		dataEnd := base
+		for _, trun := range sub {
+			if trun.typ != "trun" {
+				continue
+			}
+			end, err := rd.parseTrun(trun.data, base, dataEnd, defaults)
+			if err != nil {
+				return err
+			}
+			dataEnd = end
+		}
+	}
+	return nil
+}
+
+func (rd *Reader) parseTfhd(data []byte, moofOffset int64) (int64, trackDefaults) {
+	flags := binary.BigEndian.Uint32(data) & 0xffffff
+	defaults := rd.defaults
+	base := moofOffset
+	pos := 8
+
+	read32 := func() uint32 {
+		if pos+4 > len(data) {
+			return 0
+		}
+		v := binary.BigEndian.Uint32(data[pos:])
+		pos += 4
+		return v
+	}
+
+	if flags&0x01 != 0 && pos+8 <= len(data) {
+		base = int64(binary.BigEndian.Uint64(data[pos:]))
+		pos += 8
+	}
+	if flags&0x02 != 0 {
+		read32() // sample_description_index
+	}
+	if flags&0x08 != 0 {
+		defaults.duration = read32()
+	}
+	if flags&0x10 != 0 {
+		defaults.size = read32()
+	}
+	if flags&0x20 != 0 {
+		defaults.flags = read32()
+	}
+	return base, defaults
+}
+
+// parseTrun добавляет сэмплы одного trun. Без data_offset данные идут
+// сразу за предыдущим trun.
+func (rd *Reader) parseTrun(data []byte, base, prevEnd int64, defaults trackDefaults) (int64, error) {
+	if len(data) < 8 {
+		return 0, errors.New("обрезанный trun")
+	}
+	flags := binary.BigEndian.Uint32(data) & 0xffffff
+	count := int(binary.BigEndian.Uint32(data[4:]))
+	pos := 8
+
+	read32 := func() (uint32, error) {
+		if pos+4 > len(data) {
+			return 0, errors.New("обрезанный trun")
+		}
+		v := binary.BigEndian.Uint32(data[pos:])
+		pos += 4
+		return v, nil
+	}
+
+	offset := prevEnd
+	if flags&0x01 != 0 {
+		v, err := read32()
+		if err != nil {
+			return 0, err
+		}
+		offset = base + int64(int32(v))
+	}
+
+	firstFlags := defaults.flags
+	hasFirstFlags := flags&0x04 != 0
+	if hasFirstFlags {
+		v, err := read32()
+		if err != nil {
+			return 0, err
+		}
+		firstFlags = v
+	}
+
+	for i := 0; i < count; i++ {
+		sample := SampleInfo{DTS: rd.nextDTS, Offset: offset}
+		duration, size, sampleFlags := defaults.duration, defaults.size, defaults.flags
+		if i == 0 && hasFirstFlags {
+			sampleFlags = firstFlags
+		}
+
+		var err error
+		if flags&0x100 != 0 {
+			if duration, err = read32(); err != nil {
+				return 0, err
+			}
+		}
+		if flags&0x200 != 0 {
+			if size, err = read32(); err != nil {
+				return 0, err
+			}
+		}
+		if flags&0x400 != 0 {
+			if sampleFlags, err = read32(); err != nil {
+				return 0, err
+			}
+		}
+		if flags&0x800 != 0 {
+			v, err := read32()
+			if err != nil {
+				return 0, err
+			}
+			sample.PTSOffset = int32(v)
+		}
+
+		sample.Size = size
+		// sample_is_non_sync_sample
+		sample.Sync = sampleFlags&0x10000 == 0
+		rd.Samples = append(rd.Samples, sample)
+
+		rd.nextDTS += int64(duration)
+		offset += int64(size)
+	}
+	return offset, nil
+}
@@ -0,0 +1,288 @@
+package mp4
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/bluenviron/mediacommon/v2/pkg/codecs/h264"
+)
+
+// TimeScale — частота часов дорожки, совпадающая с частотой RTP для H.264.
+const TimeScale = 90000
+
+const movieTimeScale = 1000
+
+// Sample — одно access unit в формате AVCC (NAL с 4-байтовой длиной).
+// DTS задаётся в единицах TimeScale, PTS = DTS + PTSOffset.
+type Sample struct {
+	DTS       int64
+	PTSOffset int32
+	Sync      bool
+	Data      []byte
+}
+
+// WriteH264 пишет обычный (не фрагментированный) MP4 с одной видеодорожкой
+// H.264: ftyp, moov с полной таблицей сэмплов и mdat одним чанком.
+// Первый сэмпл должен быть ключевым кадром.
+func WriteH264(w io.Writer, sps, pps []byte, samples []Sample) error {
+	if len(samples) == 0 {
+		return errors.New("нет кадров для записи")
+	}
+	if !samples[0].Sync {
+		return errors.New("клип должен начинаться с ключевого кадра")
+	}
+
+	var parsed h264.SPS
+	if err := parsed.Unmarshal(sps); err != nil {
+		return fmt.Errorf("ошибка разбора SPS: %w", err)
+	}
+	if len(pps) == 0 {
+		return errors.New("нет PPS")
+	}
+
+	t := &track{
+		sps:     sps,
+		pps:     pps,
+		width:   parsed.Width(),
+		height:  parsed.Height(),
+		samples: samples,
+	}
+
+	ftyp := box("ftyp",
+		[]byte("isom"), u32(0x200),
+		[]byte("isom"), []byte("iso2"), []byte("avc1"), []byte("mp41"),
+	)
+
+	// размер moov не зависит от смещения чанка, поэтому его можно
+	// собрать один раз для расчёта смещения и второй раз начисто
+	moov := t.moov(0)
+	offset := len(ftyp) + len(moov) + 8
+	moov = t.moov(uint32(offset))
+
+	dataSize := 0
+	for _, s := range samples {
+		dataSize += len(s.Data)
+	}
+	if offset+dataSize > 0xffffffff {
+		return errors.New("клип больше 4 ГБ")
+	}
+
+	for _, part := range [][]byte{ftyp, moov, u32(uint32(8 + dataSize)), []byte("mdat")} {
+		if _, err := w.Write(part); err != nil {
+			return err
+		}
+	}
+	for _, s := range samples {
+		if _, err := w.Write(s.Data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type track struct {
+	sps     []byte
+	pps     []byte
+	width   int
+	height  int
+	samples []Sample
+}
+
+// durations считает длительность сэмплов по разнице DTS. Последний сэмпл
+// получает длительность предыдущего.
+func (t *track) durations() ([]uint32, int64) {
+	durations := make([]uint32, len(t.samples))
+	var total int64
+
+	for i := range t.samples {
+		var d int64
+		if i+1 < len(t.samples) {
+			d = t.samples[i+1].DTS - t.samples[i].DTS
+		} else if i > 0 {
+			d = int64(durations[i-1])
+		}
+		if d <= 0 {
+			d = TimeScale / 30
+		}
+		durations[i] = uint32(d)
+		total += d
+	}
+	return durations, total
+}
+
+func (t *track) moov(chunkOffset uint32) []byte {
+	durations, total := t.durations()
+	movieDuration := uint32(total * movieTimeScale / TimeScale)
+
+	return box("moov",
+		t.mvhd(movieDuration),
+		box("trak",
+			t.tkhd(movieDuration),
+			box("mdia",
+				fullBox("mdhd", 0, 0,
+					u32(0), u32(0), u32(TimeScale), u32(uint32(total)),
+					u16(0x55c4), u16(0), // язык "und"
+				),
+				fullBox("hdlr", 0, 0,
+					u32(0), []byte("vide"), make([]byte, 12), []byte("VideoHandler\x00"),
+				),
+				box("minf",
+					fullBox("vmhd", 0, 1, make([]byte, 8)),
+					box("dinf",
+						fullBox("dref", 0, 0, u32(1), fullBox("url ", 0, 1)),
+					),
+					box("stbl",
+						t.stsd(),
+						t.stts(durations),
+						t.stss(),
+						t.ctts(),
+						fullBox("stsc", 0, 0, u32(1), u32(1), u32(uint32(len(t.samples))), u32(1)),
+						t.stsz(),
+						fullBox("stco", 0, 0, u32(1), u32(chunkOffset)),
+					),
+				),
+			),
+		),
+	)
+}
+
+func (t *track) mvhd(duration uint32) []byte {
+	return fullBox("mvhd", 0, 0,
+		u32(0), u32(0), u32(movieTimeScale), u32(duration),
+		u32(0x00010000), u16(0x0100), make([]byte, 10),
+		matrix(),
+		make([]byte, 24),
+		u32(2),
+	)
+}
+
+func (t *track) tkhd(duration uint32) []byte {
+	return fullBox("tkhd", 0, 3,
+		u32(0), u32(0), u32(1), u32(0), u32(duration),
+		make([]byte, 8), u16(0), u16(0), u16(0), u16(0),
+		matrix(),
+		u32(uint32(t.width)<<16), u32(uint32(t.height)<<16),
+	)
+}
+
+func (t *track) stsd() []byte {
+	avcC := box("avcC",
+		[]byte{1, t.sps[1], t.sps[2], t.sps[3], 0xff, 0xe1},
+		u16(uint16(len(t.sps))), t.sps,
+		[]byte{1},
+		u16(uint16(len(t.pps))), t.pps,
+	)
+
+	compressorName := make([]byte, 32)
+	avc1 := box("avc1",
+		make([]byte, 6), u16(1), // reserved, data_reference_index
+		make([]byte, 16),
+		u16(uint16(t.width)), u16(uint16(t.height)),
+		u32(0x00480000), u32(0x00480000), // 72 dpi
+		u32(0), u16(1),
+		compressorName,
+		u16(0x18), u16(0xffff),
+		avcC,
+	)
+
+	return fullBox("stsd", 0, 0, u32(1), avc1)
+}
+
+func (t *track) stts(durations []uint32) []byte {
+	var entries [][]byte
+	count := 0
+
+	for i, d := range durations {
+		count++
+		if i+1 < len(durations) && durations[i+1] == d {
+			continue
+		}
+		entries = append(entries, u32(uint32(count)), u32(d))
+		count = 0
+	}
+
+	return fullBox("stts", 0, 0, append([][]byte{u32(uint32(len(entries) / 2))}, entries...)...)
+}
+
+func (t *track) stss() []byte {
+	var entries [][]byte
+	for i, s := range t.samples {
+		if s.Sync {
+			entries = append(entries, u32(uint32(i+1)))
+		}
+	}
+	return fullBox("stss", 0, 0, append([][]byte{u32(uint32(len(entries)))}, entries...)...)
+}
+
+// ctts нужен только потокам с B-кадрами; без них возвращается пустой срез.
+func (t *track) ctts() []byte {
+	hasOffsets := false
+	for _, s := range t.samples {
+		if s.PTSOffset != 0 {
+			hasOffsets = true
+			break
+		}
+	}
+	if !hasOffsets {
+		return nil
+	}
+
+	var entries [][]byte
+	count := 0
+	for i, s := range t.samples {
+		count++
+		if i+1 < len(t.samples) && t.samples[i+1].PTSOffset == s.PTSOffset {
+			continue
+		}
+		entries = append(entries, u32(uint32(count)), u32(uint32(s.PTSOffset)))
+		count = 0
+	}
+
+	return fullBox("ctts", 1, 0, append([][]byte{u32(uint32(len(entries) / 2))}, entries...)...)
+}
+
+func (t *track) stsz() []byte {
+	parts := [][]byte{u32(0), u32(uint32(len(t.samples)))}
+	for _, s := range t.samples {
+		parts = append(parts, u32(uint32(len(s.Data))))
+	}
+	return fullBox("stsz", 0, 0, parts...)
+}
+
+func box(typ string, payload ...[]byte) []byte {
+	size := 8
+	for _, p := range payload {
+		size += len(p)
+	}
+
+	buf := make([]byte, 8, size)
+	binary.BigEndian.PutUint32(buf, uint32(size))
+	copy(buf[4:], typ)
+	for _, p := range payload {
+		buf = append(buf, p...)
+	}
+	return buf
+}
+
+func fullBox(typ string, version uint8, flags uint32, payload ...[]byte) []byte {
+	header := u32(uint32(version)<<24 | flags&0xffffff)
+	return box(typ, append([][]byte{header}, payload...)...)
+}
+
+func matrix() []byte {
+	m := make([]byte, 36)
+	binary.BigEndian.PutUint32(m[0:], 0x00010000)
+	binary.BigEndian.PutUint32(m[16:], 0x00010000)
+	binary.BigEndian.PutUint32(m[32:], 0x40000000)
+	return m
+}
+
+func u32(v uint32) []byte {
+	return binary.BigEndian.AppendUint32(nil, v)
+}
+
+func u16(v uint16) []byte {
+	return binary.BigEndian.AppendUint16(nil, v)
+}
@@ -0,0 +1,100 @@
+package pcap
+
+import (
+	"encoding/binary"
+	"io"
+	"time"
+)
+
+const (
+	magicMicroseconds = 0xa1b2c3d4
+	snapLen           = 65535
+
+	// linkTypeIPv4 — кадры начинаются сразу с заголовка IPv4, без Ethernet.
+	linkTypeIPv4 = 228
+
+	ipv4HeaderLen = 20
+	udpHeaderLen  = 8
+)
+
+// Packet — RTP пакет, перехваченный в момент Time. Port задаёт UDP порт
+// назначения, по которому дорожки различаются в Wireshark.
+type Packet struct {
+	Time time.Time
+	Port uint16
+	Data []byte
+}
+
+// Writer пишет RTP пакеты в формате pcap, оборачивая их в синтетические
+// заголовки IPv4/UDP 127.0.0.1 → 127.0.0.1, чтобы Wireshark мог
+// разобрать их как RTP ("Decode As… RTP" или эвристика rtp_udp).
+type Writer struct {
+	w  io.Writer
+	id uint16
+}
+
+func NewWriter(w io.Writer) (*Writer, error) {
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header[0:], magicMicroseconds)
+	binary.LittleEndian.PutUint16(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], snapLen)
+	binary.LittleEndian.PutUint32(header[20:], linkTypeIPv4)
+
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	return &Writer{w: w}, nil
+}
+
+func (pw *Writer) WritePacket(pkt Packet) error {
+	frame := pw.encapsulate(pkt)
+
+	record := make([]byte, 16)
+	binary.LittleEndian.PutUint32(record[0:], uint32(pkt.Time.Unix()))
+	binary.LittleEndian.PutUint32(record[4:], uint32(pkt.Time.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(record[8:], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(record[12:], uint32(len(frame)))
+
+	if _, err := pw.w.Write(record); err != nil {
+		return err
+	}
+	_, err := pw.w.Write(frame)
+	return err
+}
+
+func (pw *Writer) encapsulate(pkt Packet) []byte {
+	total := ipv4HeaderLen + udpHeaderLen + len(pkt.Data)
+	frame := make([]byte, total)
+
+	ip := frame[:ipv4HeaderLen]
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(total))
+	binary.BigEndian.PutUint16(ip[4:], pw.id)
+	ip[8] = 64
+	ip[9] = 17
+	copy(ip[12:], []byte{127, 0, 0, 1})
+	copy(ip[16:], []byte{127, 0, 0, 1})
+	binary.BigEndian.PutUint16(ip[10:], ipv4Checksum(ip))
+	pw.id++
+
+	udp := frame[ipv4HeaderLen : ipv4HeaderLen+udpHeaderLen]
+	binary.BigEndian.PutUint16(udp[0:], pkt.Port)
+	binary.BigEndian.PutUint16(udp[2:], pkt.Port)
+	binary.BigEndian.PutUint16(udp[4:], uint16(udpHeaderLen+len(pkt.Data)))
+
+	copy(frame[ipv4HeaderLen+udpHeaderLen:], pkt.Data)
+	return frame
+}
+
+func ipv4Checksum(header []byte) uint16 {
+	var sum uint32
+	for i := 0; i < len(header); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(header[i:]))
+	}
+	for sum > 0xffff {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
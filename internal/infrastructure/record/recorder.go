@@ -0,0 +1,343 @@
+package record
+
+import (
+	"errors"
+	"fmt"
+	"ip-camera-viewer/internal/infrastructure/mp4"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/mediacommon/v2/pkg/codecs/h264"
+)
+
+const (
+	// maxBufferBytes ограничивает память буфера одного потока, если камера
+	// присылает ключевые кадры реже, чем раз в PreEvent.
+	maxBufferBytes = 64 << 20
+
+	// maxClipDuration ограничивает клип, который постоянно продлевается
+	// новыми срабатываниями.
+	maxClipDuration = 10 * time.Minute
+)
+
+type Options struct {
+	// Name — имя потока, с которого начинается имя файла клипа.
+	Name      string
+	Dir       string
+	PreEvent  time.Duration
+	PostEvent time.Duration
+}
+
+// Clip — результат записи одного клипа.
+type Clip struct {
+	Path     string
+	Reason   string
+	Duration time.Duration
+	Err      error
+}
+
+type gop struct {
+	samples []mp4.Sample
+	bytes   int
+}
+
+type recording struct {
+	path    string
+	reason  string
+	samples []mp4.Sample
+	endDTS  int64
+}
+
+// Recorder держит в памяти последние PreEvent секунд потока целыми GOP,
+// начиная с ключевого кадра, и по Trigger сохраняет их вместе со следующими
+// PostEvent секундами в MP4. WriteAccessUnit вызывается из одной горутины
+// приёма пакетов, Trigger — из любой.
+type Recorder struct {
+	mu     sync.Mutex
+	opts   Options
+	onClip func(Clip)
+
+	sps []byte
+	pps []byte
+	dts *h264.DTSExtractor
+
+	gops     []gop
+	bytes    int
+	lastDTS  int64
+	active   *recording
+	finished sync.WaitGroup
+}
+
+func NewRecorder(opts Options, onClip func(Clip)) *Recorder {
+	return &Recorder{
+		opts:   opts,
+		onClip: onClip,
+	}
+}
+
+// Reset начинает новую сессию: при переподключении метки времени RTP
+// начинаются заново, поэтому буфер сбрасывается, а незаконченный клип
+// сохраняется как есть.
+func (r *Recorder) Reset(sps, pps []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.finishLocked()
+	r.gops = nil
+	r.bytes = 0
+	r.dts = nil
+	r.sps = clone(sps)
+	r.pps = clone(pps)
+}
+
+// WriteAccessUnit добавляет access unit с PTS в единицах 90 кГц.
+func (r *Recorder) WriteAccessUnit(au [][]byte, pts int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hasParams := false
+	var filtered [][]byte
+	for _, nalu := range au {
+		if len(nalu) == 0 {
+			continue
+		}
+		switch h264.NALUType(nalu[0] & 0x1f) {
+		case h264.NALUTypeSPS:
+			r.sps = clone(nalu)
+			hasParams = true
+		case h264.NALUTypePPS:
+			r.pps = clone(nalu)
+		case h264.NALUTypeAccessUnitDelimiter:
+			continue
+		}
+		filtered = append(filtered, nalu)
+	}
+
+	idr := h264.IsRandomAccess(filtered)
+	if r.dts == nil {
+		if !idr || r.sps == nil || r.pps == nil {
+			return
+		}
+		r.dts = h264.NewDTSExtractor()
+	}
+
+	if idr && !hasParams && r.sps != nil && r.pps != nil {
+		filtered = append([][]byte{r.sps, r.pps}, filtered...)
+	}
+
+	dts, err := r.dts.Extract(filtered, pts)
+	if err != nil {
+		// ждём следующий ключевой кадр
+		r.dts = nil
+		return
+	}
+
+	data, err := h264.AVCC(filtered).Marshal()
+	if err != nil {
+		return
+	}
+
+	sample := mp4.Sample{
+		DTS:       dts,
+		PTSOffset: int32(pts - dts),
+		Sync:      idr,
+		Data:      data,
+	}
+	r.lastDTS = dts
+
+	switch {
+	case idr:
+		r.gops = append(r.gops, gop{})
+	case len(r.gops) == 0:
+		return
+	}
+
+	last := &r.gops[len(r.gops)-1]
+	last.samples = append(last.samples, sample)
+	last.bytes += len(data)
+	r.bytes += len(data)
+	r.prune()
+
+	if r.active == nil {
+		return
+	}
+
+	r.active.samples = append(r.active.samples, sample)
+	start := r.active.samples[0].DTS
+	if dts >= r.active.endDTS || dts-start >= ticks(maxClipDuration) {
+		r.finishLocked()
+	}
+}
+
+// prune удаляет старые GOP, пока оставшихся хватает на PreEvent.
+func (r *Recorder) prune() {
+	pre := ticks(r.opts.PreEvent)
+
+	for len(r.gops) > 1 {
+		tooOld := r.lastDTS-r.gops[1].samples[0].DTS >= pre
+		if !tooOld && r.bytes <= maxBufferBytes {
+			return
+		}
+		r.bytes -= r.gops[0].bytes
+		r.gops = r.gops[1:]
+	}
+}
+
+// Trigger сохраняет буфер и продолжает запись PostEvent секунд. Повторное
+// срабатывание во время записи продлевает текущий клип.
+func (r *Recorder) Trigger(reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	endDTS := r.lastDTS + ticks(r.opts.PostEvent)
+
+	if r.active != nil {
+		r.active.endDTS = max(r.active.endDTS, endDTS)
+		return nil
+	}
+
+	if len(r.gops) == 0 {
+		return errors.New("в буфере ещё нет ключевого кадра")
+	}
+
+	dir := r.opts.Dir
+	if dir == "" {
+		dir = DefaultDir()
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s-%s.mp4", r.opts.Name, sanitize(reason), time.Now().Format("20060102-150405"))
+
+	var samples []mp4.Sample
+	for _, g := range r.gops {
+		samples = append(samples, g.samples...)
+	}
+
+	r.active = &recording{
+		path:    filepath.Join(dir, name),
+		reason:  reason,
+		samples: samples,
+		endDTS:  endDTS,
+	}
+	return nil
+}
+
+// Recording сообщает, идёт ли запись клипа.
+func (r *Recorder) Recording() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.active != nil
+}
+
+// Close сохраняет незаконченный клип и ждёт завершения записи файлов.
+func (r *Recorder) Close() {
+	r.mu.Lock()
+	r.finishLocked()
+	r.gops = nil
+	r.bytes = 0
+	r.mu.Unlock()
+
+	r.finished.Wait()
+}
+
+// finishLocked отдаёт клип на запись в отдельной горутине, чтобы не
+// задерживать приём пакетов.
+func (r *Recorder) finishLocked() {
+	rec := r.active
+	if rec == nil {
+		return
+	}
+	r.active = nil
+
+	sps, pps := r.sps, r.pps
+	r.finished.Add(1)
+	go func() {
+		defer r.finished.Done()
+
+		clip := Clip{Reason: rec.reason}
+		clip.Path, clip.Err = writeClip(rec.path, sps, pps, rec.samples)
+		if n := len(rec.samples); n > 0 {
+			clip.Duration = time.Duration(rec.samples[n-1].DTS-rec.samples[0].DTS) * time.Second / mp4.TimeScale
+		}
+
+		if r.onClip != nil {
+			r.onClip(clip)
+		}
+	}()
+}
+
+// writeClip пишет клип и возвращает путь, под которым он сохранён.
+func writeClip(path string, sps, pps []byte, samples []mp4.Sample) (string, error) {
+	file, path, err := createClip(path)
+	if err != nil {
+		return path, err
+	}
+
+	if err := mp4.WriteH264(file, sps, pps, samples); err != nil {
+		file.Close()
+		os.Remove(path)
+		return path, err
+	}
+	return path, file.Close()
+}
+
+// createClip создаёт файл клипа, не перезаписывая существующие: имя
+// содержит время с точностью до секунды, поэтому следующий клип той же
+// секунды получает суффикс -2, -3 и так далее.
+func createClip(path string) (*os.File, string, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+
+	for n := 1; ; n++ {
+		candidate := path
+		if n > 1 {
+			candidate = fmt.Sprintf("%s-%d%s", base, n, ext)
+		}
+
+		file, err := os.OpenFile(candidate, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if !os.IsExist(err) {
+			return file, candidate, err
+		}
+	}
+}
+
+// DefaultDir — каталог клипов по умолчанию: ~/Videos/ip-camera-viewer.
+func DefaultDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "ip-camera-viewer")
+	}
+	return filepath.Join(home, "Videos", "ip-camera-viewer")
+}
+
+func ticks(d time.Duration) int64 {
+	return int64(d) * mp4.TimeScale / int64(time.Second)
+}
+
+func sanitize(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+	if name == "" {
+		return "event"
+	}
+	return name
+}
+
+func clone(b []byte) []byte {
+	if b == nil {
+		return nil
+	}
+	return append([]byte(nil), b...)
+}
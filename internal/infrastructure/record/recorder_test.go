@@ -0,0 +1,131 @@
+package record
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"ip-camera-viewer/internal/infrastructure/mp4"
+)
+
+var (
+	// 1920x1080, baseline, pic_order_cnt_type 2: DTS совпадает с PTS
+	testSPS = []byte{
+		0x67, 0x42, 0xc0, 0x28, 0xd9, 0x00, 0x78, 0x02,
+		0x27, 0xe5, 0x84, 0x00, 0x00, 0x03, 0x00, 0x04,
+		0x00, 0x00, 0x03, 0x00, 0xf0, 0x3c, 0x60, 0xc9, 0x20,
+	}
+	testPPS    = []byte{0x68, 0xce, 0x3c, 0x80}
+	testIDR    = []byte{0x65, 0x88, 0x84, 0x00}
+	testNonIDR = []byte{0x41, 0x9a, 0x02}
+)
+
+const (
+	testFPS       = 10
+	testFrameTick = mp4.TimeScale / testFPS
+)
+
+// writeFrames пишет кадры [from, to) с ключевым кадром раз в секунду.
+func writeFrames(r *Recorder, from, to int) {
+	for n := from; n < to; n++ {
+		nalu := testNonIDR
+		if n%testFPS == 0 {
+			nalu = testIDR
+		}
+		r.WriteAccessUnit([][]byte{nalu}, int64(n*testFrameTick))
+	}
+}
+
+func TestRecorderClipRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	clips := make(chan Clip, 2)
+	r := NewRecorder(Options{
+		Name:      "High",
+		Dir:       dir,
+		PreEvent:  time.Second,
+		PostEvent: time.Second,
+	}, func(clip Clip) { clips <- clip })
+	r.Reset(testSPS, testPPS)
+
+	// в буфере остаются GOP с кадра 10, клип заканчивается на кадре 35
+	writeFrames(r, 0, 26)
+	if err := r.Trigger("motion"); err != nil {
+		t.Fatal(err)
+	}
+	writeFrames(r, 26, 36)
+	if r.Recording() {
+		t.Fatal("клип не завершился после PostEvent")
+	}
+
+	first := <-clips
+
+	// второй клип той же секунды не должен перезаписать первый
+	if err := r.Trigger("motion"); err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	second := <-clips
+
+	for _, clip := range []Clip{first, second} {
+		if clip.Err != nil {
+			t.Fatalf("%s: %v", clip.Path, clip.Err)
+		}
+	}
+	if first.Path == second.Path {
+		t.Fatalf("оба клипа записаны в %s", first.Path)
+	}
+
+	if !strings.HasPrefix(first.Path, dir) || !strings.Contains(first.Path, "High-motion-") {
+		t.Errorf("неожиданное имя клипа: %s", first.Path)
+	}
+	if first.Duration != 2500*time.Millisecond {
+		t.Errorf("длительность клипа %v, ожидалось 2.5s", first.Duration)
+	}
+
+	data, err := os.ReadFile(first.Path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rd, err := mp4.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(rd.SPS, testSPS) || !bytes.Equal(rd.PPS, testPPS) {
+		t.Errorf("SPS/PPS не совпадают: %x %x", rd.SPS, rd.PPS)
+	}
+	if rd.TimeScale != mp4.TimeScale {
+		t.Errorf("TimeScale %d, ожидалось %d", rd.TimeScale, mp4.TimeScale)
+	}
+	if len(rd.Samples) != 26 {
+		t.Fatalf("в клипе %d кадров, ожидалось 26", len(rd.Samples))
+	}
+
+	for i, sample := range rd.Samples {
+		if dts := sample.DTS - rd.Samples[0].DTS; dts != int64(i*testFrameTick) {
+			t.Errorf("кадр %d: DTS %d, ожидалось %d", i, dts, i*testFrameTick)
+		}
+		if want := i%testFPS == 0; sample.Sync != want {
+			t.Errorf("кадр %d: Sync = %v", i, sample.Sync)
+		}
+
+		au, err := rd.ReadSample(sample)
+		if err != nil {
+			t.Fatalf("кадр %d: %v", i, err)
+		}
+		want := [][]byte{testNonIDR}
+		if sample.Sync {
+			want = [][]byte{testSPS, testPPS, testIDR}
+		}
+		if len(au) != len(want) {
+			t.Fatalf("кадр %d: %d NAL, ожидалось %d", i, len(au), len(want))
+		}
+		for j := range au {
+			if !bytes.Equal(au[j], want[j]) {
+				t.Errorf("кадр %d, NAL %d: %x, ожидалось %x", i, j, au[j], want[j])
+			}
+		}
+	}
+}
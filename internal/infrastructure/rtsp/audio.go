@@ -0,0 +1,216 @@
+//go:build cgo
+
+package rtsp
+
+import (
+	"fmt"
+	"ip-camera-viewer/internal/domain/model"
+	"ip-camera-viewer/internal/infrastructure/audio"
+	"sync"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v5/pkg/description"
+	"github.com/bluenviron/gortsplib/v5/pkg/format"
+	"github.com/bluenviron/gortsplib/v5/pkg/format/rtpmpeg4audio"
+	"github.com/pion/rtp"
+)
+
+type audioTrack struct {
+	media  *description.Media
+	format format.Format
+	decode func(pkt *rtp.Packet) ([]int16, audio.Format, error)
+	close  func()
+}
+
+func findAudioTrack(desc *description.Session) (*audioTrack, error) {
+	for _, medi := range desc.Medias {
+		if medi.IsBackChannel {
+			continue
+		}
+
+		for _, forma := range medi.Formats {
+			switch forma := forma.(type) {
+			case *format.MPEG4Audio:
+				return newAACTrack(medi, forma)
+			case *format.G711:
+				return newG711Track(medi, forma)
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func newAACTrack(medi *description.Media, forma *format.MPEG4Audio) (*audioTrack, error) {
+	if forma.Config == nil {
+		return nil, fmt.Errorf("отсутствует AudioSpecificConfig")
+	}
+
+	config, err := forma.Config.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	rtpDec, err := forma.CreateDecoder()
+	if err != nil {
+		return nil, err
+	}
+
+	aacDec := &audio.AACDecoder{}
+	if err := aacDec.Initialize(config); err != nil {
+		return nil, err
+	}
+
+	return &audioTrack{
+		media:  medi,
+		format: forma,
+		decode: func(pkt *rtp.Packet) ([]int16, audio.Format, error) {
+			aus, err := rtpDec.Decode(pkt)
+			if err != nil {
+				if err == rtpmpeg4audio.ErrMorePacketsNeeded {
+					return nil, audio.Format{}, nil
+				}
+				return nil, audio.Format{}, err
+			}
+
+			var samples []int16
+			var pcmFormat audio.Format
+			for _, au := range aus {
+				decoded, f, err := aacDec.Decode(au)
+				if err != nil {
+					return nil, audio.Format{}, err
+				}
+				samples = append(samples, decoded...)
+				pcmFormat = f
+			}
+			return samples, pcmFormat, nil
+		},
+		close: aacDec.Close,
+	}, nil
+}
+
+func newG711Track(medi *description.Media, forma *format.G711) (*audioTrack, error) {
+	rtpDec, err := forma.CreateDecoder()
+	if err != nil {
+		return nil, err
+	}
+
+	pcmFormat := audio.Format{
+		SampleRate: forma.SampleRate,
+		Channels:   forma.ChannelCount,
+	}
+
+	return &audioTrack{
+		media:  medi,
+		format: forma,
+		decode: func(pkt *rtp.Packet) ([]int16, audio.Format, error) {
+			payload, err := rtpDec.Decode(pkt)
+			if err != nil {
+				return nil, audio.Format{}, err
+			}
+			return audio.DecodeG711(payload, forma.MULaw), pcmFormat, nil
+		},
+		close: func() {},
+	}, nil
+}
+
+type audioPlayer struct {
+	client     *Client
+	track      *audioTrack
+	sink       audio.Sink
+	sinkFormat audio.Format
+	closed     bool
+	mutex      sync.Mutex
+}
+
+func (c *Client) setupAudio(desc *description.Session) (*audioPlayer, error) {
+	track, err := findAudioTrack(desc)
+	if err != nil || track == nil {
+		return nil, err
+	}
+
+	_, err = c.rtspClient.Setup(desc.BaseURL, track.media, 0, 0)
+	if err != nil {
+		track.close()
+		return nil, err
+	}
+
+	player := &audioPlayer{
+		client: c,
+		track:  track,
+	}
+	c.rtspClient.OnPacketRTP(track.media, track.format, player.handlePacket)
+
+	return player, nil
+}
+
+func (ap *audioPlayer) handlePacket(pkt *rtp.Packet) {
+	ap.mutex.Lock()
+	defer ap.mutex.Unlock()
+
+	if ap.closed {
+		return
+	}
+
+	ap.client.capturePacket(pkt, audioCapturePort)
+
+	samples, pcmFormat, err := ap.track.decode(pkt)
+	if err != nil {
+		ap.client.log().Warn("Ошибка декодирования аудио", "error", err)
+		return
+	}
+	if len(samples) == 0 {
+		return
+	}
+
+	if handler := ap.client.audioLevelHandler(); handler != nil {
+		peak, rms := audio.Level(samples)
+		handler(&model.AudioLevel{
+			StreamName: ap.client.config.Name,
+			Peak:       peak,
+			RMS:        rms,
+			Timestamp:  time.Now(),
+		})
+	}
+
+	if ap.client.IsMuted() {
+		return
+	}
+
+	if ap.sink == nil || ap.sinkFormat != pcmFormat {
+		if ap.sink != nil {
+			ap.sink.Close()
+			ap.sink = nil
+		}
+
+		// без звуковой карты поток продолжает идти с индикатором уровня;
+		// новая попытка будет только при смене формата
+		sink, err := ap.client.newAudioSink(pcmFormat)
+		if err != nil {
+			ap.client.log().Warn("Ошибка открытия аудиовыхода", "error", err)
+			sink = &audio.NullSink{}
+		}
+		ap.sink = sink
+		ap.sinkFormat = pcmFormat
+	}
+
+	if err := ap.sink.Write(samples); err != nil {
+		ap.client.log().Warn("Ошибка вывода аудио", "error", err)
+	}
+}
+
+func (ap *audioPlayer) Close() {
+	ap.mutex.Lock()
+	defer ap.mutex.Unlock()
+
+	if ap.closed {
+		return
+	}
+	ap.closed = true
+
+	if ap.sink != nil {
+		ap.sink.Close()
+		ap.sink = nil
+	}
+	ap.track.close()
+}
@@ -0,0 +1,167 @@
+//go:build cgo
+
+package rtsp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"ip-camera-viewer/internal/infrastructure/audio"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v5/pkg/description"
+	"github.com/bluenviron/gortsplib/v5/pkg/format"
+	"github.com/bluenviron/gortsplib/v5/pkg/format/rtplpcm"
+)
+
+const talkPacketDuration = 20 * time.Millisecond
+
+type talkSession struct {
+	cancel context.CancelFunc
+}
+
+type backchannel struct {
+	media  *description.Media
+	format *format.G711
+}
+
+func findBackchannel(desc *description.Session) *backchannel {
+	for _, medi := range desc.Medias {
+		if !medi.IsBackChannel {
+			continue
+		}
+		for _, forma := range medi.Formats {
+			if g711, ok := forma.(*format.G711); ok {
+				return &backchannel{media: medi, format: g711}
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Client) setupBackchannel(desc *description.Session) error {
+	bc := findBackchannel(desc)
+	if bc == nil {
+		return fmt.Errorf("камера не предоставляет обратный канал G.711")
+	}
+
+	_, err := c.rtspClient.Setup(desc.BaseURL, bc.media, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	c.mutex.Lock()
+	c.backchannel = bc
+	c.mutex.Unlock()
+	return nil
+}
+
+func (c *Client) HasBackchannel() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.backchannel != nil
+}
+
+func (c *Client) StartTalk(ctx context.Context, source audio.Source) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.backchannel == nil {
+		source.Close()
+		return fmt.Errorf("обратный аудиоканал не настроен")
+	}
+	if c.talk != nil {
+		source.Close()
+		return fmt.Errorf("передача звука уже идёт")
+	}
+
+	encoder, err := c.backchannel.format.CreateEncoder()
+	if err != nil {
+		source.Close()
+		return fmt.Errorf("ошибка создания RTP кодера: %v", err)
+	}
+
+	talkCtx, cancel := context.WithCancel(ctx)
+	session := &talkSession{cancel: cancel}
+	c.talk = session
+
+	go c.talkLoop(talkCtx, session, c.backchannel, encoder, source)
+	return nil
+}
+
+func (c *Client) StopTalk() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.talk != nil {
+		c.talk.cancel()
+		c.talk = nil
+	}
+}
+
+func (c *Client) talkLoop(ctx context.Context, session *talkSession, bc *backchannel, encoder *rtplpcm.Encoder, source audio.Source) {
+	defer source.Close()
+	defer func() {
+		c.mutex.Lock()
+		if c.talk == session {
+			c.talk = nil
+		}
+		c.mutex.Unlock()
+		session.cancel()
+	}()
+
+	srcFormat := source.Format()
+	dstFormat := audio.Format{
+		SampleRate: bc.format.SampleRate,
+		Channels:   bc.format.ChannelCount,
+	}
+
+	buf := make([]int16, srcFormat.SampleRate*srcFormat.Channels*int(talkPacketDuration/time.Millisecond)/1000)
+	ticker := time.NewTicker(talkPacketDuration)
+	defer ticker.Stop()
+
+	var timestamp uint32
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		n, err := source.Read(buf)
+		if n > 0 {
+			pcm := audio.Convert(buf[:n], srcFormat, dstFormat)
+
+			payload, encErr := audio.EncodeG711(pcm, bc.format.MULaw)
+			if encErr != nil {
+				c.log().Warn("Ошибка кодирования G.711", "error", encErr)
+				return
+			}
+
+			pkts, encErr := encoder.Encode(payload)
+			if encErr != nil {
+				c.log().Warn("Ошибка упаковки RTP", "error", encErr)
+				return
+			}
+
+			for _, pkt := range pkts {
+				pkt.Timestamp += timestamp
+				if writeErr := c.rtspClient.WritePacketRTP(bc.media, pkt); writeErr != nil {
+					c.log().Warn("Ошибка отправки звука в камеру", "error", writeErr)
+					return
+				}
+			}
+			timestamp += uint32(len(pcm) / max(dstFormat.Channels, 1))
+		}
+
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			c.log().Warn("Ошибка чтения источника звука", "error", err)
+			return
+		}
+	}
+}
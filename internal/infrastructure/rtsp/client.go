@@ -4,23 +4,30 @@ package rtsp
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"image"
 	"image/draw"
 	"ip-camera-viewer/internal/domain/model"
+	"ip-camera-viewer/internal/infrastructure/audio"
+	"ip-camera-viewer/internal/infrastructure/record"
 	videodecoder "ip-camera-viewer/internal/infrastructure/video"
-	"log"
+	"log/slog"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bluenviron/gortsplib/v5"
 	"github.com/bluenviron/gortsplib/v5/pkg/base"
 	"github.com/bluenviron/gortsplib/v5/pkg/format"
 	"github.com/bluenviron/gortsplib/v5/pkg/format/rtph264"
+	"github.com/bluenviron/gortsplib/v5/pkg/liberrors"
 	"github.com/bluenviron/mediacommon/v2/pkg/codecs/h264"
 	"github.com/pion/rtp"
 )
 
+const framePoolSize = 4
+
 type Client struct {
 	rtspClient  *gortsplib.Client
 	config      *model.StreamConfig
@@ -28,14 +35,37 @@ type Client struct {
 	isRunning   bool
 	mutex       sync.RWMutex
 	cancelFunc  context.CancelFunc
+
+	outputWidth  int
+	outputHeight int
+
+	audioSinkFactory audio.SinkFactory
+	muted            bool
+	onAudioLevel     func(*model.AudioLevel)
+
+	backchannel *backchannel
+	talk        *talkSession
+
+	certErr error
+
+	logger *slog.Logger
+
+	sdp     []byte
+	stats   streamStats
+	capture atomic.Pointer[rtpCapture]
+
+	recorder atomic.Pointer[record.Recorder]
+
+	playback playback
 }
 
 func NewClient(config *model.StreamConfig) *Client {
 	return &Client{
-		rtspClient:  &gortsplib.Client{},
-		config:      config,
-		isConnected: false,
-		isRunning:   false,
+		rtspClient:       &gortsplib.Client{},
+		config:           config,
+		isConnected:      false,
+		isRunning:        false,
+		audioSinkFactory: audio.NewNullSink,
 	}
 }
 
@@ -47,19 +77,32 @@ func (c *Client) Connect(ctx context.Context) error {
 		return nil
 	}
 
+	// время прошлой сессии не должно засчитываться, пока идёт рукопожатие
+	c.stats.setPlaying(time.Time{})
+
 	u, err := base.ParseURL(c.config.RTSPURI)
 	if err != nil {
 		return fmt.Errorf("ошибка парсинга URL: %v", err)
 	}
 
 	c.rtspClient = &gortsplib.Client{
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+		ReadTimeout:         10 * time.Second,
+		WriteTimeout:        10 * time.Second,
+		RequestBackChannels: c.config.Backchannel,
 	}
+	c.installLogHooks(c.rtspClient)
+	c.installPlaybackHook(c.rtspClient)
 
 	c.rtspClient.Scheme = u.Scheme
 	c.rtspClient.Host = u.Host
 
+	if u.Scheme == "rtsps" {
+		c.rtspClient.TLSConfig, err = c.newTLSConfig(u.Host)
+		if err != nil {
+			return err
+		}
+	}
+
 	err = c.rtspClient.Start()
 	if err != nil {
 		return fmt.Errorf("ошибка запуска клиента: %v", err)
@@ -97,11 +140,27 @@ func (c *Client) StartStreaming(ctx context.Context, frameChannel chan<- *model.
 		return fmt.Errorf("ошибка парсинга URL: %v", err)
 	}
 
-	desc, _, err := c.rtspClient.Describe(u)
+	desc, res, err := c.rtspClient.Describe(u)
 	if err != nil {
+		c.mutex.RLock()
+		certErr := c.certErr
+		c.mutex.RUnlock()
+		if certErr != nil {
+			return fmt.Errorf("ошибка описания потока: %w", certErr)
+		}
+		var badStatus liberrors.ErrClientBadStatusCode
+		if errors.As(err, &badStatus) &&
+			(badStatus.Code == base.StatusUnauthorized || badStatus.Code == base.StatusForbidden) {
+			return model.NewAppError(model.ErrorTypeAuthentication, "ошибка описания потока", err,
+				"Камера отклонила логин или пароль")
+		}
 		return fmt.Errorf("ошибка описания потока: %v", err)
 	}
 
+	c.mutex.Lock()
+	c.sdp = res.Body
+	c.mutex.Unlock()
+
 	var forma *format.H264
 	medi := desc.FindFormat(&forma)
 	if medi == nil {
@@ -130,6 +189,11 @@ func (c *Client) StartStreaming(ctx context.Context, frameChannel chan<- *model.
 	if forma.PPS != nil {
 		h264Dec.Decode([][]byte{forma.PPS})
 	}
+	h264Dec.SetOutputSize(c.OutputSize())
+
+	if rec := c.recorder.Load(); rec != nil {
+		rec.Reset(forma.SPS, forma.PPS)
+	}
 
 	_, err = c.rtspClient.Setup(desc.BaseURL, medi, 0, 0)
 	if err != nil {
@@ -138,6 +202,7 @@ func (c *Client) StartStreaming(ctx context.Context, frameChannel chan<- *model.
 
 	var firstRandomAccess bool
 	var packetMutex sync.Mutex
+	framePool := videodecoder.NewFramePool(framePoolSize)
 
 	c.rtspClient.OnPacketRTP(medi, forma, func(pkt *rtp.Packet) {
 		select {
@@ -149,15 +214,27 @@ func (c *Client) StartStreaming(ctx context.Context, frameChannel chan<- *model.
 		packetMutex.Lock()
 		defer packetMutex.Unlock()
 
-		_, ok := c.rtspClient.PacketPTS(medi, pkt)
+		c.capturePacket(pkt, videoCapturePort)
+		c.stats.addPacket(len(pkt.Payload))
+
+		pts, ok := c.rtspClient.PacketPTS(medi, pkt)
 		if !ok {
 			return
 		}
+		c.playback.update(pts)
+
+		// после перехода по архиву поток начинается с другой точки
+		if c.playback.resync.CompareAndSwap(true, false) {
+			firstRandomAccess = false
+			if rec := c.recorder.Load(); rec != nil {
+				rec.Reset(forma.SPS, forma.PPS)
+			}
+		}
 
 		au, err := rtpDec.Decode(pkt)
 		if err != nil {
 			if err != rtph264.ErrNonStartingPacketAndNoPrevious && err != rtph264.ErrMorePacketsNeeded {
-				log.Printf("Ошибка декодировки РТП пакетов: %v", err)
+				c.log().Warn("Ошибка декодировки RTP пакетов", "error", err)
 			}
 			return
 		}
@@ -169,50 +246,141 @@ func (c *Client) StartStreaming(ctx context.Context, frameChannel chan<- *model.
 			firstRandomAccess = true
 		}
 
+		if rec := c.recorder.Load(); rec != nil {
+			rec.WriteAccessUnit(au, pts)
+		}
+
+		h264Dec.SetOutputSize(c.OutputSize())
+
 		img, err := h264Dec.Decode(au)
 		if err != nil || img == nil {
 			return
 		}
 
-		safeImage := c.createSafeImageCopy(img)
+		c.stats.addFrame(h264Dec.SourceSize())
+
+		safeImage := c.createSafeImageCopy(img, framePool)
 		if safeImage == nil {
 			return
 		}
 
-		frame := &model.FrameData{
-			Image:     safeImage,
-			Timestamp: time.Now(),
-		}
+		frame := model.NewFrameData(safeImage, time.Now(), func() {
+			framePool.Put(safeImage)
+		})
 
 		select {
 		case frameChannel <- frame:
 		case <-streamCtx.Done():
+			frame.Release()
 			return
 		default:
+			frame.Release()
 		}
 	})
 
-	_, err = c.rtspClient.Play(nil)
+	if c.config.Audio {
+		player, err := c.setupAudio(desc)
+		if err != nil {
+			c.log().Warn("Аудио недоступно", "error", err)
+		} else if player != nil {
+			defer player.Close()
+		}
+	}
+
+	if c.config.Backchannel {
+		if err := c.setupBackchannel(desc); err != nil {
+			c.log().Warn("Обратный аудиоканал недоступен", "error", err)
+		}
+	}
+
+	_, err = c.rtspClient.Play(c.playback.sessionRange())
 	if err != nil {
 		return fmt.Errorf("ошибка запуска воспроизведения: %v", err)
 	}
+	c.stats.setPlaying(time.Now())
 
-	log.Printf("RTSP поток запущен: %s", c.config.RTSPURI)
+	c.log().Info("RTSP поток запущен", "uri", c.config.RTSPURI)
 
 	select {
 	case <-streamCtx.Done():
-		log.Printf("RTSP поток остановлен по контексту: %s", c.config.RTSPURI)
+		c.log().Info("RTSP поток остановлен по контексту", "uri", c.config.RTSPURI)
 	default:
 	}
 	err = c.rtspClient.Wait()
-	if err != nil {
-		log.Printf("Ошибка RTSP клиента: %v", err)
+	// после Close клиент тоже завершается с ошибкой, это не обрыв
+	if err != nil && streamCtx.Err() == nil {
+		c.log().Error("Ошибка RTSP клиента", "error", err)
+		return fmt.Errorf("соединение с камерой прервано: %w", err)
 	}
 
 	return nil
 }
 
-func (c *Client) createSafeImageCopy(src image.Image) image.Image {
+// SetRecorder подключает буфер записи клипов. Он получает закодированные
+// access unit до декодирования, поэтому клип не перекодируется.
+func (c *Client) SetRecorder(recorder *record.Recorder) {
+	c.recorder.Store(recorder)
+}
+
+func (c *Client) SetOutputSize(width, height int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.outputWidth = width
+	c.outputHeight = height
+}
+
+func (c *Client) OutputSize() (int, int) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.outputWidth, c.outputHeight
+}
+
+func (c *Client) SetAudioSinkFactory(factory audio.SinkFactory) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.audioSinkFactory = factory
+}
+
+func (c *Client) SetAudioLevelHandler(handler func(*model.AudioLevel)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.onAudioLevel = handler
+}
+
+func (c *Client) SetMuted(muted bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.muted = muted
+}
+
+func (c *Client) IsMuted() bool {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.muted
+}
+
+func (c *Client) audioLevelHandler() func(*model.AudioLevel) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.onAudioLevel
+}
+
+func (c *Client) newAudioSink(format audio.Format) (audio.Sink, error) {
+	c.mutex.RLock()
+	factory := c.audioSinkFactory
+	c.mutex.RUnlock()
+
+	return factory(c.config.Name, format)
+}
+
+func (c *Client) createSafeImageCopy(src image.Image, pool *videodecoder.FramePool) *image.RGBA {
 	if src == nil {
 		return nil
 	}
@@ -222,13 +390,18 @@ func (c *Client) createSafeImageCopy(src image.Image) image.Image {
 		return nil
 	}
 
-	dst := image.NewRGBA(bounds)
+	dst := pool.Get(bounds)
+	if dst == nil {
+		return nil
+	}
 	draw.Draw(dst, bounds, src, bounds.Min, draw.Src)
 
 	return dst
 }
 
 func (c *Client) StopStreaming() {
+	c.StopTalk()
+
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 
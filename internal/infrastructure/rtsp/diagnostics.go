@@ -0,0 +1,164 @@
+//go:build cgo
+
+package rtsp
+
+import (
+	"context"
+	"fmt"
+	"ip-camera-viewer/internal/domain/model"
+	"ip-camera-viewer/internal/infrastructure/pcap"
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+const (
+	videoCapturePort   = 5004
+	audioCapturePort   = 5006
+	maxCapturedPackets = 50000
+
+	statsWindow = time.Second
+)
+
+// streamStats считает FPS и битрейт по окну в одну секунду.
+type streamStats struct {
+	mu sync.Mutex
+
+	width         int
+	height        int
+	fps           float64
+	bitrate       int64
+	lastFrameTime time.Time
+	lastPacket    time.Time
+	playingSince  time.Time
+
+	windowStart  time.Time
+	windowFrames int
+	windowBytes  int64
+}
+
+func (s *streamStats) addPacket(size int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.lastPacket = now
+	s.windowBytes += int64(size)
+	s.roll(now)
+}
+
+func (s *streamStats) addFrame(width, height int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.width = width
+	s.height = height
+	s.lastFrameTime = now
+	s.windowFrames++
+	s.roll(now)
+}
+
+func (s *streamStats) setPlaying(since time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.playingSince = since
+}
+
+func (s *streamStats) roll(now time.Time) {
+	if s.windowStart.IsZero() {
+		s.windowStart = now
+		return
+	}
+
+	elapsed := now.Sub(s.windowStart)
+	if elapsed < statsWindow {
+		return
+	}
+
+	s.fps = float64(s.windowFrames) / elapsed.Seconds()
+	s.bitrate = int64(float64(s.windowBytes*8) / elapsed.Seconds())
+	s.windowStart = now
+	s.windowFrames = 0
+	s.windowBytes = 0
+}
+
+// rtpCapture накапливает пакеты, пока активен захват.
+type rtpCapture struct {
+	mu      sync.Mutex
+	packets []pcap.Packet
+}
+
+func (rc *rtpCapture) add(pkt pcap.Packet) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if len(rc.packets) < maxCapturedPackets {
+		rc.packets = append(rc.packets, pkt)
+	}
+}
+
+func (c *Client) capturePacket(pkt *rtp.Packet, port uint16) {
+	capture := c.capture.Load()
+	if capture == nil {
+		return
+	}
+
+	data, err := pkt.Marshal()
+	if err != nil {
+		return
+	}
+
+	capture.add(pcap.Packet{Time: time.Now(), Port: port, Data: data})
+}
+
+// CaptureRTP записывает входящие RTP пакеты видео и звука в течение duration.
+// Видео идёт на UDP порт 5004, звук — на 5006.
+func (c *Client) CaptureRTP(ctx context.Context, duration time.Duration) ([]pcap.Packet, error) {
+	capture := &rtpCapture{}
+	if !c.capture.CompareAndSwap(nil, capture) {
+		return nil, fmt.Errorf("захват RTP уже выполняется")
+	}
+
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+
+	c.capture.Store(nil)
+
+	capture.mu.Lock()
+	defer capture.mu.Unlock()
+	return capture.packets, ctx.Err()
+}
+
+// SDP возвращает тело ответа DESCRIBE в том виде, в каком его прислала камера.
+func (c *Client) SDP() []byte {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.sdp
+}
+
+// Stats возвращает разрешение исходного видео, FPS, битрейт, время
+// последнего кадра и RTP пакета и время начала воспроизведения. Имя и статус потока заполняет вызывающий.
+func (c *Client) Stats() model.StreamInfo {
+	c.stats.mu.Lock()
+	defer c.stats.mu.Unlock()
+
+	return model.StreamInfo{
+		Width:          c.stats.width,
+		Height:         c.stats.height,
+		FPS:            c.stats.fps,
+		Bitrate:        c.stats.bitrate,
+		LastFrameTime:  c.stats.lastFrameTime,
+		LastPacketTime: c.stats.lastPacket,
+		PlayingSince:   c.stats.playingSince,
+		Playback:       c.playback.info(),
+	}
+}
@@ -0,0 +1,55 @@
+//go:build cgo
+
+package rtsp
+
+import (
+	"log/slog"
+
+	"github.com/bluenviron/gortsplib/v5"
+	"github.com/bluenviron/gortsplib/v5/pkg/base"
+)
+
+// SetLogger задаёт журнал клиента. По умолчанию используется slog.Default().
+// Маскирование паролей — забота обработчика журнала: клиент пишет URI как есть.
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.logger = logger
+}
+
+func (c *Client) log() *slog.Logger {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	return c.loggerLocked()
+}
+
+func (c *Client) loggerLocked() *slog.Logger {
+	if c.logger == nil {
+		return slog.Default()
+	}
+	return c.logger
+}
+
+// installLogHooks заменяет обработчики gortsplib, которые по умолчанию пишут
+// в стандартный log, на вызовы журнала клиента. Вызывается под c.mutex.
+func (c *Client) installLogHooks(rc *gortsplib.Client) {
+	logger := c.loggerLocked()
+
+	rc.OnRequest = func(req *base.Request) {
+		logger.Debug("RTSP запрос", "method", string(req.Method), "uri", req.URL.String())
+	}
+	rc.OnResponse = func(res *base.Response) {
+		logger.Debug("RTSP ответ", "status", int(res.StatusCode), "message", res.StatusMessage)
+	}
+	rc.OnTransportSwitch = func(err error) {
+		logger.Warn("Смена транспорта RTSP", "error", err)
+	}
+	rc.OnPacketsLost = func(lost uint64) {
+		logger.Warn("Потеряны RTP пакеты", "lost", lost)
+	}
+	rc.OnDecodeError = func(err error) {
+		logger.Warn("Ошибка разбора RTP/RTCP", "error", err)
+	}
+}
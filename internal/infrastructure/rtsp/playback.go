@@ -0,0 +1,293 @@
+//go:build cgo
+
+package rtsp
+
+import (
+	"errors"
+	"fmt"
+	"ip-camera-viewer/internal/domain/model"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bluenviron/gortsplib/v5"
+	"github.com/bluenviron/gortsplib/v5/pkg/base"
+	"github.com/bluenviron/gortsplib/v5/pkg/headers"
+)
+
+const ptsClockRate = 90000
+
+// playback — режим архива NVR: PLAY с Range: clock= и Scale. Состояние
+// читается из OnRequest gortsplib, поэтому у него свой мьютекс: Play
+// ждёт ответа от того же цикла, который вызывает OnRequest.
+type playback struct {
+	// control не даёт двум командам PAUSE/PLAY идти одновременно.
+	control sync.Mutex
+
+	mu       sync.Mutex
+	active   bool
+	paused   bool
+	scale    float64
+	start    time.Time
+	position time.Time
+	basePTS  int64
+	hasBase  bool
+
+	// resync просит обработчик пакетов дождаться ключевого кадра: после
+	// перехода поток начинается с новой точки.
+	resync atomic.Bool
+}
+
+func (p *playback) info() *model.PlaybackInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.active {
+		return nil
+	}
+	return &model.PlaybackInfo{
+		Position: p.position,
+		Scale:    p.scale,
+		Paused:   p.paused,
+	}
+}
+
+// playRange возвращает Range для PLAY: с текущей позиции архива или nil
+// для эфира.
+func (p *playback) playRange() *headers.Range {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.active {
+		return nil
+	}
+	return &headers.Range{Value: &headers.RangeUTC{Start: p.position.UTC()}}
+}
+
+// sessionRange возвращает Range для первого PLAY сессии: после
+// переподключения архив продолжается с той же позиции.
+func (p *playback) sessionRange() *headers.Range {
+	if info := p.info(); info != nil {
+		p.restart(info.Position)
+	}
+	return p.playRange()
+}
+
+// restart отмечает, что следующий PLAY начнёт архив с позиции position.
+func (p *playback) restart(position time.Time) {
+	p.mu.Lock()
+	p.start = position
+	p.position = position
+	p.hasBase = false
+	p.paused = false
+	p.mu.Unlock()
+
+	p.resync.Store(true)
+}
+
+// update сдвигает позицию по PTS пакета. NVR не масштабирует метки RTP
+// при Scale, поэтому прошедшее время умножается на скорость.
+func (p *playback) update(pts int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.active || p.paused {
+		return
+	}
+	if !p.hasBase {
+		p.basePTS = pts
+		p.hasBase = true
+	}
+
+	elapsed := time.Duration(float64(pts-p.basePTS) * p.scale * float64(time.Second) / ptsClockRate)
+	p.position = p.start.Add(elapsed)
+}
+
+// decorate добавляет к PLAY заголовки воспроизведения архива.
+func (p *playback) decorate(req *base.Request) {
+	if req.Method != base.Play {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.active {
+		return
+	}
+	req.Header["Require"] = append(req.Header["Require"], "onvif-replay")
+	if p.scale != 1 {
+		req.Header["Scale"] = base.HeaderValue{strconv.FormatFloat(p.scale, 'f', -1, 64)}
+	}
+}
+
+// installPlaybackHook дописывает заголовки архива в PLAY перед отправкой.
+// Вызывается после installLogHooks, чтобы в журнал попадал итоговый запрос.
+func (c *Client) installPlaybackHook(rc *gortsplib.Client) {
+	logRequest := rc.OnRequest
+	rc.OnRequest = func(req *base.Request) {
+		c.playback.decorate(req)
+		logRequest(req)
+	}
+}
+
+// Seek переводит поток в режим архива и начинает воспроизведение с
+// момента start повторным PLAY с Range: clock=.
+func (c *Client) Seek(start time.Time) error {
+	c.playback.control.Lock()
+	defer c.playback.control.Unlock()
+
+	c.playback.mu.Lock()
+	c.playback.active = true
+	if c.playback.scale == 0 {
+		c.playback.scale = 1
+	}
+	c.playback.mu.Unlock()
+
+	return c.replay(start)
+}
+
+// Pause останавливает воспроизведение архива запросом PAUSE.
+func (c *Client) Pause() error {
+	c.playback.control.Lock()
+	defer c.playback.control.Unlock()
+
+	c.playback.mu.Lock()
+	active, paused := c.playback.active, c.playback.paused
+	c.playback.mu.Unlock()
+
+	if !active {
+		return errors.New("пауза доступна только при просмотре архива")
+	}
+	if paused {
+		return nil
+	}
+
+	rc, err := c.runningClient()
+	if err != nil {
+		return err
+	}
+	if _, err := rc.Pause(); err != nil {
+		return fmt.Errorf("ошибка паузы: %v", err)
+	}
+
+	c.playback.mu.Lock()
+	c.playback.paused = true
+	c.playback.mu.Unlock()
+	return nil
+}
+
+// Resume продолжает архив с позиции, на которой он был остановлен.
+func (c *Client) Resume() error {
+	c.playback.control.Lock()
+	defer c.playback.control.Unlock()
+
+	c.playback.mu.Lock()
+	active, paused, position := c.playback.active, c.playback.paused, c.playback.position
+	c.playback.mu.Unlock()
+
+	if !active || !paused {
+		return nil
+	}
+	return c.replay(position)
+}
+
+// SetScale меняет скорость архива. Сервер принимает Scale только в PLAY,
+// поэтому воспроизведение перезапускается с текущей позиции.
+func (c *Client) SetScale(scale float64) error {
+	if scale == 0 {
+		return errors.New("скорость не может быть нулевой")
+	}
+
+	c.playback.control.Lock()
+	defer c.playback.control.Unlock()
+
+	c.playback.mu.Lock()
+	active, paused, position := c.playback.active, c.playback.paused, c.playback.position
+	if active {
+		c.playback.scale = scale
+	}
+	c.playback.mu.Unlock()
+
+	if !active {
+		return errors.New("скорость меняется только при просмотре архива")
+	}
+	if paused {
+		return nil
+	}
+	return c.replay(position)
+}
+
+// GoLive выходит из архива и возвращает поток в эфир.
+func (c *Client) GoLive() error {
+	c.playback.control.Lock()
+	defer c.playback.control.Unlock()
+
+	c.playback.mu.Lock()
+	active, paused := c.playback.active, c.playback.paused
+	c.playback.active = false
+	c.playback.paused = false
+	c.playback.scale = 1
+	c.playback.mu.Unlock()
+
+	if !active {
+		return nil
+	}
+
+	rc, err := c.runningClient()
+	if err != nil {
+		return err
+	}
+	if !paused {
+		if _, err := rc.Pause(); err != nil {
+			return fmt.Errorf("ошибка остановки архива: %v", err)
+		}
+	}
+
+	c.playback.resync.Store(true)
+	if _, err := rc.Play(nil); err != nil {
+		return fmt.Errorf("ошибка возврата в эфир: %v", err)
+	}
+	return nil
+}
+
+// replay перезапускает архив с позиции position: PAUSE, если поток идёт,
+// затем PLAY с новым Range. Вызывается под playback.control.
+func (c *Client) replay(position time.Time) error {
+	rc, err := c.runningClient()
+	if err != nil {
+		return err
+	}
+
+	c.playback.mu.Lock()
+	paused := c.playback.paused
+	c.playback.mu.Unlock()
+
+	if !paused {
+		if _, err := rc.Pause(); err != nil {
+			return fmt.Errorf("ошибка паузы перед переходом: %v", err)
+		}
+	}
+
+	c.playback.restart(position)
+	if _, err := rc.Play(c.playback.playRange()); err != nil {
+		c.playback.mu.Lock()
+		c.playback.paused = true
+		c.playback.mu.Unlock()
+		return fmt.Errorf("ошибка воспроизведения архива: %v", err)
+	}
+
+	c.log().Info("Воспроизведение архива", "position", position.Format(time.RFC3339), "scale", c.playback.info().Scale)
+	return nil
+}
+
+func (c *Client) runningClient() (*gortsplib.Client, error) {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	if !c.isRunning || !c.isConnected {
+		return nil, errors.New("поток не воспроизводится")
+	}
+	return c.rtspClient, nil
+}
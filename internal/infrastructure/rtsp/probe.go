@@ -0,0 +1,48 @@
+//go:build cgo
+
+package rtsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/bluenviron/gortsplib/v5/pkg/base"
+	"github.com/bluenviron/gortsplib/v5/pkg/liberrors"
+)
+
+// Probe выполняет DESCRIBE и возвращает код ответа сервера. Ошибка
+// возвращается, только если ответ не был получен.
+func (c *Client) Probe(ctx context.Context) (base.StatusCode, error) {
+	if err := c.Connect(ctx); err != nil {
+		return 0, err
+	}
+	defer c.Close()
+
+	u, err := base.ParseURL(c.config.RTSPURI)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка парсинга URL: %v", err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			c.rtspClient.Close()
+		case <-done:
+		}
+	}()
+
+	_, res, err := c.rtspClient.Describe(u)
+	if err == nil {
+		return res.StatusCode, nil
+	}
+
+	var badStatus liberrors.ErrClientBadStatusCode
+	if errors.As(err, &badStatus) {
+		return badStatus.Code, nil
+	}
+
+	return 0, err
+}
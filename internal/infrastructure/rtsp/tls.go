@@ -0,0 +1,95 @@
+//go:build cgo
+
+package rtsp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"ip-camera-viewer/internal/domain/model"
+	"net"
+	"os"
+)
+
+func (c *Client) newTLSConfig(host string) (*tls.Config, error) {
+	serverName, _, err := net.SplitHostPort(host)
+	if err != nil {
+		serverName = host
+	}
+
+	var roots *x509.CertPool
+	if c.config.CAFile != "" {
+		pem, err := os.ReadFile(c.config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения файла CA: %v", err)
+		}
+
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("файл CA %s не содержит сертификатов", c.config.CAFile)
+		}
+	}
+
+	pinned := ""
+	if c.config.CertFingerprint != "" {
+		var ok bool
+		pinned, ok = model.NormalizeFingerprint(c.config.CertFingerprint)
+		if !ok {
+			return nil, fmt.Errorf("некорректный отпечаток сертификата")
+		}
+	}
+
+	return &tls.Config{
+		ServerName: serverName,
+		// цепочка проверяется в VerifyConnection, чтобы учесть закреплённый отпечаток
+		InsecureSkipVerify: true,
+		VerifyConnection: func(cs tls.ConnectionState) error {
+			err := verifyCertificate(cs, serverName, roots, pinned)
+			if err != nil {
+				c.mutex.Lock()
+				c.certErr = err
+				c.mutex.Unlock()
+			}
+			return err
+		},
+	}, nil
+}
+
+func verifyCertificate(cs tls.ConnectionState, serverName string, roots *x509.CertPool, pinned string) error {
+	if len(cs.PeerCertificates) == 0 {
+		return fmt.Errorf("сервер не предоставил сертификат")
+	}
+
+	leaf := cs.PeerCertificates[0]
+	info := model.NewCertificateInfo(leaf)
+
+	if pinned != "" {
+		if info.Fingerprint == pinned {
+			return nil
+		}
+		return &model.UntrustedCertificateError{
+			Info:   info,
+			Pinned: pinned,
+			Reason: "отпечаток не совпадает с сохранённым",
+		}
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       serverName,
+	})
+	if err != nil {
+		return &model.UntrustedCertificateError{
+			Info:   info,
+			Reason: err.Error(),
+		}
+	}
+
+	return nil
+}
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"image"
 	"runtime"
-	"unsafe"
 
 	"github.com/bluenviron/mediacommon/v2/pkg/codecs/h264"
 )
@@ -14,23 +13,28 @@ import (
 // #include <libavcodec/avcodec.h>
 // #include <libavutil/imgutils.h>
 // #include <libswscale/swscale.h>
+//
+// static int scale_to_rgba(struct SwsContext *ctx, AVFrame *src, uint8_t *dst, int dstStride) {
+//     uint8_t *dstData[4] = {dst, NULL, NULL, NULL};
+//     int dstLinesize[4] = {dstStride, 0, 0, 0};
+//     return sws_scale(ctx, (const uint8_t *const *)src->data, src->linesize, 0, src->height, dstData, dstLinesize);
+// }
 import "C"
 
-func frameData(frame *C.AVFrame) **C.uint8_t {
-	return (**C.uint8_t)(unsafe.Pointer(&frame.data[0]))
-}
-
-func frameLineSize(frame *C.AVFrame) *C.int {
-	return (*C.int)(unsafe.Pointer(&frame.linesize[0]))
-}
-
 // H264Decoder is a wrapper around FFmpeg's H264 decoder.
 type H264Decoder struct {
-	codecCtx     *C.AVCodecContext
-	yuv420Frame  *C.AVFrame
-	rgbaFrame    *C.AVFrame
-	rgbaFramePtr []uint8
-	swsCtx       *C.struct_SwsContext
+	codecCtx    *C.AVCodecContext
+	yuv420Frame *C.AVFrame
+	swsCtx      *C.struct_SwsContext
+
+	srcWidth  int
+	srcHeight int
+	maxWidth  int
+	maxHeight int
+	dstWidth  int
+	dstHeight int
+	dstDirty  bool
+	rgbaBuf   []uint8
 }
 
 // initialize initializes a H264Decoder.
@@ -66,46 +70,69 @@ func (d *H264Decoder) Close() {
 		C.sws_freeContext(d.swsCtx)
 	}
 
-	if d.rgbaFrame != nil {
-		C.av_frame_free(&d.rgbaFrame)
-	}
-
 	C.av_frame_free(&d.yuv420Frame)
 	C.avcodec_close(d.codecCtx)
 }
 
-func (d *H264Decoder) reinitDynamicStuff() error {
-	if d.swsCtx != nil {
-		C.sws_freeContext(d.swsCtx)
+// SetOutputSize limits the size of decoded images. The frame is scaled down
+// by swscale to fit into width x height keeping its aspect ratio; zero values
+// disable scaling.
+func (d *H264Decoder) SetOutputSize(width, height int) {
+	if width == d.maxWidth && height == d.maxHeight {
+		return
 	}
+	d.maxWidth = width
+	d.maxHeight = height
+	d.dstDirty = true
+}
 
-	if d.rgbaFrame != nil {
-		C.av_frame_free(&d.rgbaFrame)
+// SourceSize returns the size of the last decoded frame before scaling.
+func (d *H264Decoder) SourceSize() (int, int) {
+	return d.srcWidth, d.srcHeight
+}
+
+// LibraryVersions returns the versions of the linked FFmpeg libraries.
+func LibraryVersions() map[string]string {
+	return map[string]string{
+		"ffmpeg":     C.GoString(C.av_version_info()),
+		"libavcodec": formatVersion(uint32(C.avcodec_version())),
+		"libavutil":  formatVersion(uint32(C.avutil_version())),
+		"libswscale": formatVersion(uint32(C.swscale_version())),
 	}
+}
 
-	d.rgbaFrame = C.av_frame_alloc()
-	if d.rgbaFrame == nil {
-		return fmt.Errorf("av_frame_alloc() failed")
+func formatVersion(version uint32) string {
+	return fmt.Sprintf("%d.%d.%d", version>>16, version>>8&0xff, version&0xff)
+}
+
+func (d *H264Decoder) reinitDynamicStuff() error {
+	if d.swsCtx != nil {
+		C.sws_freeContext(d.swsCtx)
+		d.swsCtx = nil
 	}
 
-	d.rgbaFrame.format = C.AV_PIX_FMT_RGBA
-	d.rgbaFrame.width = d.yuv420Frame.width
-	d.rgbaFrame.height = d.yuv420Frame.height
-	d.rgbaFrame.color_range = C.AVCOL_RANGE_JPEG
+	d.srcWidth = int(d.yuv420Frame.width)
+	d.srcHeight = int(d.yuv420Frame.height)
+	d.dstWidth, d.dstHeight = fitSize(d.srcWidth, d.srcHeight, d.maxWidth, d.maxHeight)
+	d.dstDirty = false
 
-	res := C.av_frame_get_buffer(d.rgbaFrame, 1)
-	if res < 0 {
-		return fmt.Errorf("av_frame_get_buffer() failed")
+	flags := C.int(C.SWS_BILINEAR)
+	if d.dstWidth < d.srcWidth {
+		flags = C.int(C.SWS_AREA)
 	}
 
 	d.swsCtx = C.sws_getContext(d.yuv420Frame.width, d.yuv420Frame.height, int32(d.yuv420Frame.format),
-		d.rgbaFrame.width, d.rgbaFrame.height, (int32)(d.rgbaFrame.format), C.SWS_BILINEAR, nil, nil, nil)
+		C.int(d.dstWidth), C.int(d.dstHeight), int32(C.AV_PIX_FMT_RGBA), flags, nil, nil, nil)
 	if d.swsCtx == nil {
 		return fmt.Errorf("sws_getContext() failed")
 	}
 
-	rgbaFrameSize := C.av_image_get_buffer_size((int32)(d.rgbaFrame.format), d.rgbaFrame.width, d.rgbaFrame.height, 1)
-	d.rgbaFramePtr = (*[1 << 30]uint8)(unsafe.Pointer(d.rgbaFrame.data[0]))[:rgbaFrameSize:rgbaFrameSize]
+	// the output buffer only grows, so shrinking the preview reuses it
+	size := 4 * d.dstWidth * d.dstHeight
+	if cap(d.rgbaBuf) < size {
+		d.rgbaBuf = make([]uint8, size)
+	}
+	d.rgbaBuf = d.rgbaBuf[:size]
 	return nil
 }
 
@@ -136,27 +163,47 @@ func (d *H264Decoder) Decode(au [][]byte) (*image.RGBA, error) {
 		return nil, nil
 	}
 
-	// if frame size has changed, allocate needed objects
-	if d.rgbaFrame == nil || d.rgbaFrame.width != d.yuv420Frame.width || d.rgbaFrame.height != d.yuv420Frame.height {
+	// if frame or output size has changed, allocate needed objects
+	if d.swsCtx == nil || d.dstDirty ||
+		d.srcWidth != int(d.yuv420Frame.width) || d.srcHeight != int(d.yuv420Frame.height) {
 		err := d.reinitDynamicStuff()
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	// convert color space from YUV420 to RGBA
-	res = C.sws_scale(d.swsCtx, frameData(d.yuv420Frame), frameLineSize(d.yuv420Frame),
-		0, d.yuv420Frame.height, frameData(d.rgbaFrame), frameLineSize(d.rgbaFrame))
+	// convert color space from YUV420 to RGBA and scale to the output size
+	dst := &d.rgbaBuf[0]
+	p.Pin(dst)
+	res = C.scale_to_rgba(d.swsCtx, d.yuv420Frame, (*C.uint8_t)(dst), C.int(4*d.dstWidth))
+	p.Unpin()
 	if res < 0 {
 		return nil, fmt.Errorf("sws_scale() failed")
 	}
 
 	// embed frame into an image.RGBA
 	return &image.RGBA{
-		Pix:    d.rgbaFramePtr,
-		Stride: 4 * (int)(d.rgbaFrame.width),
+		Pix:    d.rgbaBuf,
+		Stride: 4 * d.dstWidth,
 		Rect: image.Rectangle{
-			Max: image.Point{(int)(d.rgbaFrame.width), (int)(d.rgbaFrame.height)},
+			Max: image.Point{d.dstWidth, d.dstHeight},
 		},
 	}, nil
 }
+
+// fitSize returns the largest size not exceeding maxWidth x maxHeight that
+// keeps the aspect ratio of width x height. Images are never upscaled.
+func fitSize(width, height, maxWidth, maxHeight int) (int, int) {
+	if maxWidth <= 0 || maxHeight <= 0 || (width <= maxWidth && height <= maxHeight) {
+		return width, height
+	}
+
+	w, h := maxWidth, height*maxWidth/width
+	if h > maxHeight {
+		w, h = width*maxHeight/height, maxHeight
+	}
+
+	// swscale works best with even dimensions
+	w, h = max(w&^1, 2), max(h&^1, 2)
+	return w, h
+}
@@ -0,0 +1,68 @@
+package videodecoder
+
+import (
+	"image"
+	"sync"
+)
+
+// FramePool is a fixed-size ring of reusable RGBA buffers for a single stream.
+// Buffers handed out by Get must be returned with Put once they are no longer
+// displayed.
+type FramePool struct {
+	mu       sync.Mutex
+	free     []*image.RGBA
+	inUse    int
+	capacity int
+}
+
+func NewFramePool(capacity int) *FramePool {
+	return &FramePool{
+		free:     make([]*image.RGBA, 0, capacity),
+		capacity: capacity,
+	}
+}
+
+// Get returns a buffer with the given bounds or nil when every buffer is still
+// in use, in which case the caller should drop the frame.
+func (p *FramePool) Get(bounds image.Rectangle) *image.RGBA {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	size := 4 * bounds.Dx() * bounds.Dy()
+
+	if n := len(p.free); n > 0 {
+		img := p.free[n-1]
+		p.free = p.free[:n-1]
+		p.inUse++
+
+		if cap(img.Pix) < size {
+			return image.NewRGBA(bounds)
+		}
+		img.Pix = img.Pix[:size]
+		img.Stride = 4 * bounds.Dx()
+		img.Rect = bounds
+		return img
+	}
+
+	if p.inUse >= p.capacity {
+		return nil
+	}
+
+	p.inUse++
+	return image.NewRGBA(bounds)
+}
+
+func (p *FramePool) Put(img *image.RGBA) {
+	if img == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.inUse == 0 {
+		return
+	}
+	p.inUse--
+	p.free = append(p.free, img)
+}
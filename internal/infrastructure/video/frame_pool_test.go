@@ -0,0 +1,53 @@
+package videodecoder
+
+import (
+	"image"
+	"image/draw"
+	"testing"
+)
+
+var benchBounds = image.Rect(0, 0, 1920, 1080)
+
+func BenchmarkFrameCopyAlloc(b *testing.B) {
+	src := image.NewRGBA(benchBounds)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(src.Pix)))
+	for b.Loop() {
+		dst := image.NewRGBA(benchBounds)
+		draw.Draw(dst, benchBounds, src, benchBounds.Min, draw.Src)
+	}
+}
+
+func BenchmarkFrameCopyPooled(b *testing.B) {
+	src := image.NewRGBA(benchBounds)
+	pool := NewFramePool(4)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(src.Pix)))
+	for b.Loop() {
+		dst := pool.Get(benchBounds)
+		draw.Draw(dst, benchBounds, src, benchBounds.Min, draw.Src)
+		pool.Put(dst)
+	}
+}
+
+func TestFramePoolExhaustion(t *testing.T) {
+	pool := NewFramePool(2)
+
+	a := pool.Get(benchBounds)
+	b := pool.Get(benchBounds)
+	if a == nil || b == nil {
+		t.Fatal("expected two buffers")
+	}
+	if pool.Get(benchBounds) != nil {
+		t.Fatal("expected nil when all buffers are in use")
+	}
+
+	pool.Put(a)
+	small := image.Rect(0, 0, 640, 360)
+	c := pool.Get(small)
+	if c == nil || c.Bounds() != small || len(c.Pix) != 4*640*360 {
+		t.Fatalf("expected reused buffer with bounds %v, got %v", small, c)
+	}
+}
@@ -1,8 +1,11 @@
 package ui
 
 import (
+	"errors"
 	"ip-camera-viewer/internal/domain/model"
+	"sort"
 	"strconv"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
@@ -18,14 +21,45 @@ type ConnectionForm struct {
 	passwordEntry *widget.Entry
 	rtspURI1Entry *widget.Entry
 	rtspURI2Entry *widget.Entry
-
+	caFileEntry   *widget.Entry
+	pinEntry      *widget.Entry
+	varsEntry     *widget.Entry
+	audioCheck    *widget.Check
+	talkCheck     *widget.Check
+	showPassCheck *widget.Check
+	rememberCheck *widget.Check
+	motionCheck   *widget.Check
+	sensSlider    *widget.Slider
+	minAreaEntry  *widget.Entry
+	motionZones   []model.Zone
+	recordCheck   *widget.Check
+	recordDir     string
+	healthConfig  model.HealthConfig
+	watchdog      model.WatchdogConfig
+	reconnectChk  *widget.Check
+	preEntry      *widget.Entry
+	postEntry     *widget.Entry
+
+	presetSelect     *widget.Select
+	autoButton       *widget.Button
 	checkButton      *widget.Button
 	connectButton    *widget.Button
 	disconnectButton *widget.Button
 
-	onCheck      func(*model.ConnectionConfig)
-	onConnect    func(*model.ConnectionConfig)
-	onDisconnect func()
+	onCheck          func(*model.ConnectionConfig)
+	onPresetSelected func(vendor string)
+	onAutoDetect     func(*model.ConnectionConfig)
+	onConnect        func(*model.ConnectionConfig)
+	onDisconnect     func()
+	onMotionChanged  func(model.MotionConfig)
+	onRecordChanged  func(onMotion bool)
+	validator        func(*model.ConnectionConfig) *model.ValidationResult
+
+	fieldEntries map[string]*widget.Entry
+	fieldErrors  map[string]*widget.Label
+	touched      map[string]bool
+	valid        bool
+	connected    bool
 
 	container *fyne.Container
 }
@@ -38,8 +72,51 @@ func NewConnectionForm() *ConnectionForm {
 		passwordEntry: widget.NewPasswordEntry(),
 		rtspURI1Entry: widget.NewEntry(),
 		rtspURI2Entry: widget.NewEntry(),
+		caFileEntry:   widget.NewEntry(),
+		pinEntry:      widget.NewEntry(),
+		varsEntry:     widget.NewEntry(),
+		audioCheck:    widget.NewCheck("Звук", nil),
+		talkCheck:     widget.NewCheck("Обратный канал", nil),
+		rememberCheck: widget.NewCheck("Запомнить пароль", nil),
+		reconnectChk:  widget.NewCheck("Переподключать при зависании", nil),
+		minAreaEntry:  widget.NewEntry(),
+		preEntry:      widget.NewEntry(),
+		postEntry:     widget.NewEntry(),
+	}
+
+	f.recordCheck = widget.NewCheck("Запись по движению", func(onMotion bool) {
+		if f.onRecordChanged != nil {
+			f.onRecordChanged(onMotion)
+		}
+	})
+
+	f.motionCheck = widget.NewCheck("Детектор движения", func(bool) {
+		f.notifyMotion()
+	})
+	f.sensSlider = widget.NewSlider(1, 100)
+	f.sensSlider.Step = 1
+	f.sensSlider.OnChangeEnded = func(float64) {
+		f.notifyMotion()
 	}
 
+	f.showPassCheck = widget.NewCheck("Показать пароль", func(show bool) {
+		f.passwordEntry.Password = !show
+		f.passwordEntry.Refresh()
+	})
+
+	f.presetSelect = widget.NewSelect(nil, func(vendor string) {
+		if f.onPresetSelected != nil {
+			f.onPresetSelected(vendor)
+		}
+	})
+	f.presetSelect.PlaceHolder = "Выберите производителя"
+
+	f.autoButton = widget.NewButton("Автоподбор", func() {
+		if f.onAutoDetect != nil {
+			f.onAutoDetect(f.GetConfig())
+		}
+	})
+
 	f.checkButton = widget.NewButton("Проверить", func() {
 		if f.onCheck != nil {
 			config := f.GetConfig()
@@ -60,6 +137,58 @@ func NewConnectionForm() *ConnectionForm {
 		}
 	})
 
+	f.fieldEntries = map[string]*widget.Entry{
+		model.FieldHost:        f.ipEntry,
+		model.FieldPort:        f.portEntry,
+		model.FieldLogin:       f.loginEntry,
+		model.FieldPassword:    f.passwordEntry,
+		model.FieldRTSPURI1:    f.rtspURI1Entry,
+		model.FieldRTSPURI2:    f.rtspURI2Entry,
+		model.FieldCAFile:      f.caFileEntry,
+		model.FieldFingerprint: f.pinEntry,
+		model.FieldVariables:   f.varsEntry,
+		model.FieldMotionArea:  f.minAreaEntry,
+		model.FieldRecording:   f.preEntry,
+	}
+	f.fieldErrors = make(map[string]*widget.Label, len(f.fieldEntries))
+	f.touched = make(map[string]bool, len(f.fieldEntries))
+
+	for field, entry := range f.fieldEntries {
+		label := widget.NewLabel("")
+		label.Importance = widget.DangerImportance
+		label.Wrapping = fyne.TextWrapWord
+		label.Hide()
+		f.fieldErrors[field] = label
+
+		entry.AlwaysShowValidationError = true
+		entry.OnChanged = func(string) {
+			f.touched[field] = true
+			f.revalidate()
+		}
+	}
+
+	onAreaChanged := f.minAreaEntry.OnChanged
+	f.minAreaEntry.OnChanged = func(text string) {
+		onAreaChanged(text)
+		f.notifyMotion()
+	}
+
+	f.ipEntry.SetPlaceHolder("IPv4, IPv6 или имя хоста")
+	f.caFileEntry.SetPlaceHolder("путь к PEM файлу (для rtsps://)")
+	f.pinEntry.SetPlaceHolder("отпечаток сертификата SHA-256")
+	f.varsEntry.SetPlaceHolder("channel=1; profile=main — переменные для {channel}, {profile} и своих плейсхолдеров")
+	f.minAreaEntry.SetPlaceHolder("% кадра")
+	f.setMotionConfig(model.DefaultMotionConfig())
+	f.setRecordingConfig(model.DefaultRecordingConfig())
+	f.healthConfig = model.DefaultHealthConfig()
+	f.setWatchdogConfig(model.DefaultWatchdogConfig())
+
+	// обе длительности записи показывают ошибки под одним полем
+	f.postEntry.OnChanged = func(string) {
+		f.touched[model.FieldRecording] = true
+		f.revalidate()
+	}
+
 	f.disconnectButton.Disable()
 	f.connectButton.Disable()
 
@@ -73,7 +202,7 @@ func (cf *ConnectionForm) buildUI() {
 	title.TextStyle.Bold = true
 	title.Alignment = fyne.TextAlignCenter
 
-	ip := widget.NewLabel("IP:")
+	ip := widget.NewLabel("Host:")
 	port := widget.NewLabel("Port:")
 	login := widget.NewLabel("Login:")
 	password := widget.NewLabel("Password:")
@@ -82,28 +211,86 @@ func (cf *ConnectionForm) buildUI() {
 		nil, nil,
 		container.NewVBox(ip),
 		nil,
-		container.NewVBox(cf.ipEntry),
+		container.NewVBox(cf.ipEntry, cf.fieldErrors[model.FieldHost]),
 	)
 
 	portContainer := container.NewBorder(
 		nil, nil,
 		container.NewVBox(port),
 		nil,
-		container.NewVBox(cf.portEntry),
+		container.NewVBox(cf.portEntry, cf.fieldErrors[model.FieldPort]),
 	)
 
 	loginContainer := container.NewBorder(
 		nil, nil,
 		container.NewVBox(login),
 		nil,
-		container.NewVBox(cf.loginEntry),
+		container.NewVBox(cf.loginEntry, cf.fieldErrors[model.FieldLogin]),
 	)
 
 	passwordContainer := container.NewBorder(
 		nil, nil,
 		container.NewVBox(password),
 		nil,
-		container.NewVBox(cf.passwordEntry),
+		container.NewVBox(
+			cf.passwordEntry,
+			cf.fieldErrors[model.FieldPassword],
+			container.NewHBox(cf.showPassCheck, cf.rememberCheck),
+		),
+	)
+
+	caFileContainer := container.NewBorder(
+		nil, nil,
+		container.NewVBox(widget.NewLabel("CA:")),
+		nil,
+		container.NewVBox(cf.caFileEntry, cf.fieldErrors[model.FieldCAFile]),
+	)
+
+	pinContainer := container.NewBorder(
+		nil, nil,
+		container.NewVBox(widget.NewLabel("SHA-256:")),
+		nil,
+		container.NewVBox(cf.pinEntry, cf.fieldErrors[model.FieldFingerprint]),
+	)
+
+	varsContainer := container.NewBorder(
+		nil, nil,
+		container.NewVBox(widget.NewLabel("Переменные:")),
+		nil,
+		container.NewVBox(cf.varsEntry, cf.fieldErrors[model.FieldVariables]),
+	)
+
+	motionContainer := container.NewVBox(
+		container.NewBorder(
+			nil, nil,
+			container.NewVBox(widget.NewLabel("Чувствительность:")),
+			container.NewHBox(
+				widget.NewLabel("Мин. площадь, %:"),
+				container.NewGridWrap(fyne.NewSize(100, cf.minAreaEntry.MinSize().Height), cf.minAreaEntry),
+			),
+			container.NewVBox(cf.sensSlider),
+		),
+		cf.fieldErrors[model.FieldMotionArea],
+	)
+
+	secondsEntry := func(entry *widget.Entry) fyne.CanvasObject {
+		return container.NewGridWrap(fyne.NewSize(70, entry.MinSize().Height), entry)
+	}
+	recordContainer := container.NewVBox(
+		container.NewHBox(
+			widget.NewLabel("Запись клипа, с: до события"),
+			secondsEntry(cf.preEntry),
+			widget.NewLabel("после"),
+			secondsEntry(cf.postEntry),
+		),
+		cf.fieldErrors[model.FieldRecording],
+	)
+
+	presetContainer := container.NewBorder(
+		nil, nil,
+		container.NewVBox(widget.NewLabel("Производитель:")),
+		container.NewVBox(cf.autoButton),
+		container.NewVBox(cf.presetSelect),
 	)
 
 	rtspH := widget.NewLabel("RTSP High:")
@@ -113,14 +300,14 @@ func (cf *ConnectionForm) buildUI() {
 		nil, nil,
 		container.NewVBox(rtspH),
 		nil,
-		container.NewVBox(cf.rtspURI1Entry),
+		container.NewVBox(cf.rtspURI1Entry, cf.fieldErrors[model.FieldRTSPURI1]),
 	)
 
 	rtspLContainer := container.NewBorder(
 		nil, nil,
 		container.NewVBox(rtspL),
 		nil,
-		container.NewVBox(cf.rtspURI2Entry),
+		container.NewVBox(cf.rtspURI2Entry, cf.fieldErrors[model.FieldRTSPURI2]),
 	)
 
 	rtspContainer := container.NewBorder(
@@ -128,10 +315,22 @@ func (cf *ConnectionForm) buildUI() {
 		nil,
 		container.NewVBox(
 			cf.checkButton,
+			cf.audioCheck,
+			cf.talkCheck,
+			cf.motionCheck,
+			cf.recordCheck,
+			cf.reconnectChk,
 		),
 		container.NewVBox(
+			presetContainer,
 			rtspHContainer,
 			rtspLContainer,
+			varsContainer,
+			container.NewGridWithColumns(2,
+				caFileContainer,
+				pinContainer,
+			),
+			container.NewBorder(nil, nil, nil, recordContainer, motionContainer),
 		),
 	)
 
@@ -158,21 +357,146 @@ func (f *ConnectionForm) CreateRenderer() fyne.WidgetRenderer {
 
 func (f *ConnectionForm) GetConfig() *model.ConnectionConfig {
 	port, _ := strconv.Atoi(f.portEntry.Text)
+	health := f.healthConfig
 
 	return &model.ConnectionConfig{
-		IP:       f.ipEntry.Text,
-		Port:     port,
-		Login:    f.loginEntry.Text,
-		Password: f.passwordEntry.Text,
-		RTSPURI1: f.rtspURI1Entry.Text,
-		RTSPURI2: f.rtspURI2Entry.Text,
+		IP:          f.ipEntry.Text,
+		Port:        port,
+		Login:       f.loginEntry.Text,
+		Password:    f.passwordEntry.Text,
+		RTSPURI1:    f.rtspURI1Entry.Text,
+		RTSPURI2:    f.rtspURI2Entry.Text,
+		Audio:       f.audioCheck.Checked,
+		Backchannel: f.talkCheck.Checked,
+
+		CAFile:          f.caFileEntry.Text,
+		CertFingerprint: f.pinEntry.Text,
+
+		RememberPassword: f.rememberCheck.Checked,
+
+		Motion:    f.motionConfig(),
+		Recording: f.recordingConfig(),
+		Health:    &health,
+		Watchdog:  f.watchdogConfig(),
+
+		Variables: parseVariables(f.varsEntry.Text),
+	}
+}
+
+// motionConfig собирает настройки детектора. Нечисловая площадь
+// превращается в -1, чтобы проверка формы показала ошибку у поля.
+func (f *ConnectionForm) motionConfig() model.MotionConfig {
+	minArea := 0.0
+	if text := strings.TrimSpace(f.minAreaEntry.Text); text != "" {
+		value, err := strconv.ParseFloat(strings.ReplaceAll(text, ",", "."), 64)
+		if err != nil {
+			value = -1
+		}
+		minArea = value
+	}
+
+	return model.MotionConfig{
+		Enabled:     f.motionCheck.Checked,
+		Sensitivity: int(f.sensSlider.Value),
+		MinArea:     minArea,
+		Zones:       append([]model.Zone(nil), f.motionZones...),
+	}
+}
+
+func (f *ConnectionForm) setMotionConfig(config model.MotionConfig) {
+	f.motionZones = append([]model.Zone(nil), config.Zones...)
+	f.sensSlider.SetValue(float64(config.Sensitivity))
+	f.minAreaEntry.SetText(strconv.FormatFloat(config.MinArea, 'f', -1, 64))
+	f.motionCheck.SetChecked(config.Enabled)
+}
+
+// recordingConfig собирает настройки записи; нечисловые значения
+// превращаются в -1 и подсвечиваются проверкой формы.
+func (f *ConnectionForm) recordingConfig() model.RecordingConfig {
+	seconds := func(entry *widget.Entry) int {
+		value, err := strconv.Atoi(strings.TrimSpace(entry.Text))
+		if err != nil {
+			return -1
+		}
+		return value
+	}
+
+	return model.RecordingConfig{
+		OnMotion:  f.recordCheck.Checked,
+		Dir:       f.recordDir,
+		PreEvent:  seconds(f.preEntry),
+		PostEvent: seconds(f.postEntry),
+	}
+}
+
+func (f *ConnectionForm) setRecordingConfig(config model.RecordingConfig) {
+	f.recordDir = config.Dir
+	f.preEntry.SetText(strconv.Itoa(config.PreEvent))
+	f.postEntry.SetText(strconv.Itoa(config.PostEvent))
+	f.recordCheck.SetChecked(config.OnMotion)
+}
+
+// SetOnRecordChanged вызывается при переключении записи по движению.
+func (f *ConnectionForm) SetOnRecordChanged(handler func(onMotion bool)) {
+	f.onRecordChanged = handler
+}
+
+func (f *ConnectionForm) notifyMotion() {
+	if f.onMotionChanged == nil {
+		return
 	}
+
+	config := f.motionConfig()
+	if config.MinArea < 0 || config.MinArea > 100 {
+		return
+	}
+	f.onMotionChanged(config)
+}
+
+// SetOnMotionChanged вызывается при каждом изменении настроек детектора
+// движения, чтобы применять их без переподключения.
+func (f *ConnectionForm) SetOnMotionChanged(handler func(model.MotionConfig)) {
+	f.onMotionChanged = handler
+}
+
+func (f *ConnectionForm) SetMotionZones(zones []model.Zone) {
+	f.motionZones = append([]model.Zone(nil), zones...)
+	f.notifyMotion()
 }
 
 func (f *ConnectionForm) SetOnCheck(handler func(*model.ConnectionConfig)) {
 	f.onCheck = handler
 }
 
+func (f *ConnectionForm) SetOnPresetSelected(handler func(vendor string)) {
+	f.onPresetSelected = handler
+}
+
+func (f *ConnectionForm) SetOnAutoDetect(handler func(*model.ConnectionConfig)) {
+	f.onAutoDetect = handler
+}
+
+func (f *ConnectionForm) SetPresets(vendors []string) {
+	f.presetSelect.SetOptions(vendors)
+}
+
+func (f *ConnectionForm) SelectPreset(vendor string) {
+	f.presetSelect.SetSelected(vendor)
+}
+
+func (f *ConnectionForm) SetStreamTemplates(main, sub string) {
+	f.rtspURI1Entry.SetText(main)
+	f.rtspURI2Entry.SetText(sub)
+}
+
+func (f *ConnectionForm) SetAutoDetecting(running bool) {
+	if running {
+		f.autoButton.Disable()
+	} else {
+		f.autoButton.Enable()
+	}
+}
+
 func (f *ConnectionForm) SetOnConnect(handler func(*model.ConnectionConfig)) {
 	f.onConnect = handler
 }
@@ -182,19 +506,66 @@ func (f *ConnectionForm) SetOnDisconnect(handler func()) {
 }
 
 func (f *ConnectionForm) SetConnected(connected bool) {
+	f.connected = connected
 	if connected {
-		f.connectButton.Disable()
 		f.disconnectButton.Enable()
 		f.checkButton.Disable()
 	} else {
-		f.connectButton.Enable()
 		f.disconnectButton.Disable()
 		f.checkButton.Enable()
 	}
+	f.updateConnectButton()
+}
+
+// SetValidator задаёт проверку всей формы. Она запускается при каждом
+// изменении поля, а ошибки раскладываются по полям через ValidationError.Field.
+func (f *ConnectionForm) SetValidator(validator func(*model.ConnectionConfig) *model.ValidationResult) {
+	f.validator = validator
+	f.revalidate()
+}
+
+// ShowValidation показывает ошибки у всех полей, в том числе ещё не
+// редактированных.
+func (f *ConnectionForm) ShowValidation(result *model.ValidationResult) {
+	for field := range f.fieldEntries {
+		f.touched[field] = true
+	}
+	f.applyValidation(result)
+}
+
+func (f *ConnectionForm) revalidate() {
+	if f.validator == nil {
+		return
+	}
+	f.applyValidation(f.validator(f.GetConfig()))
+}
+
+func (f *ConnectionForm) applyValidation(result *model.ValidationResult) {
+	for field, entry := range f.fieldEntries {
+		messages := result.FieldErrors(field)
+		if field == model.FieldRTSPURI1 || field == model.FieldRTSPURI2 {
+			messages = append(messages, result.FieldErrors(model.FieldRTSPURIs)...)
+		}
+
+		label := f.fieldErrors[field]
+		if len(messages) == 0 || !f.touched[field] {
+			entry.SetValidationError(nil)
+			label.Hide()
+			continue
+		}
+
+		text := strings.Join(messages, "\n")
+		entry.SetValidationError(errors.New(text))
+		label.SetText(text)
+		label.Show()
+	}
+
+	f.valid = result.Valid
+	f.updateConnectButton()
 }
 
-func (f *ConnectionForm) ConnectionPermission(permission bool) {
-	if !permission {
+func (f *ConnectionForm) updateConnectButton() {
+	if f.valid && !f.connected {
 		f.connectButton.Enable()
 	} else {
 		f.connectButton.Disable()
@@ -205,6 +576,66 @@ func (f *ConnectionForm) LoadConfig(config *model.ConnectionConfig) {
 	f.ipEntry.SetText(config.IP)
 	f.portEntry.SetText(strconv.Itoa(config.Port))
 	f.loginEntry.SetText(config.Login)
+	f.passwordEntry.SetText(config.Password)
+	f.rememberCheck.SetChecked(config.RememberPassword)
 	f.rtspURI1Entry.SetText(config.RTSPURI1)
 	f.rtspURI2Entry.SetText(config.RTSPURI2)
+	f.audioCheck.SetChecked(config.Audio)
+	f.talkCheck.SetChecked(config.Backchannel)
+	f.caFileEntry.SetText(config.CAFile)
+	f.pinEntry.SetText(config.CertFingerprint)
+	f.varsEntry.SetText(formatVariables(config.Variables))
+	f.setMotionConfig(config.Motion)
+	f.setRecordingConfig(config.Recording)
+	f.healthConfig = model.DefaultHealthConfig()
+	if config.Health != nil {
+		f.healthConfig = *config.Health
+	}
+	watchdog := model.DefaultWatchdogConfig()
+	if config.Watchdog != nil {
+		watchdog = *config.Watchdog
+	}
+	f.setWatchdogConfig(watchdog)
+}
+
+// watchdogConfig — таймаут берётся из сохранённой конфигурации, в форме
+// меняется только переподключение.
+func (f *ConnectionForm) watchdogConfig() *model.WatchdogConfig {
+	config := f.watchdog
+	config.Reconnect = f.reconnectChk.Checked
+	return &config
+}
+
+func (f *ConnectionForm) setWatchdogConfig(config model.WatchdogConfig) {
+	f.watchdog = config
+	f.reconnectChk.SetChecked(config.Reconnect)
+}
+
+func parseVariables(text string) map[string]string {
+	variables := make(map[string]string)
+	for _, pair := range strings.FieldsFunc(text, func(r rune) bool { return r == ';' || r == '\n' }) {
+		name, value, found := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			continue
+		}
+		variables[name] = strings.TrimSpace(value)
+	}
+	if len(variables) == 0 {
+		return nil
+	}
+	return variables
+}
+
+func formatVariables(variables map[string]string) string {
+	pairs := make([]string, 0, len(variables))
+	for name, value := range variables {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "; ")
+}
+
+func (f *ConnectionForm) SetCertFingerprint(fingerprint string) {
+	f.pinEntry.SetText(fingerprint)
 }
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"ip-camera-viewer/internal/domain/model"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+const maxEvents = 200
+
+// EventList показывает события детектора движения, новые сверху.
+// Методы вызываются из главного потока.
+type EventList struct {
+	events    []*model.MotionEvent
+	list      *widget.List
+	container *fyne.Container
+}
+
+func NewEventList() *EventList {
+	el := &EventList{}
+
+	el.list = widget.NewList(
+		func() int {
+			return len(el.events)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			label := item.(*widget.Label)
+			event := el.events[len(el.events)-1-id]
+
+			label.SetText(event.String())
+			if event.Type == model.MotionStarted {
+				label.Importance = widget.DangerImportance
+			} else {
+				label.Importance = widget.MediumImportance
+			}
+			label.Refresh()
+		},
+	)
+
+	clearButton := widget.NewButton("Очистить", func() {
+		el.events = nil
+		el.list.Refresh()
+	})
+
+	el.container = container.NewBorder(
+		container.NewBorder(nil, nil, widget.NewLabel("События движения:"), clearButton),
+		nil, nil, nil,
+		el.list,
+	)
+
+	return el
+}
+
+func (el *EventList) Add(event *model.MotionEvent) {
+	el.events = append(el.events, event)
+	if len(el.events) > maxEvents {
+		el.events = el.events[len(el.events)-maxEvents:]
+	}
+	el.list.Refresh()
+}
+
+func (el *EventList) GetWidget() fyne.CanvasObject {
+	return el.container
+}
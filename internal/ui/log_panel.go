@@ -1,53 +1,252 @@
 package ui
 
 import (
+	"ip-camera-viewer/internal/domain/model"
+	"log/slog"
 	"strings"
+	"sync"
+	"time"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 )
 
+const logPanelMinHeight = 160
+
+var logLevelOptions = []string{"DEBUG", "INFO", "WARN", "ERROR"}
+
 type LogPanel struct {
 	widget.BaseWidget
-	entry    *widget.Entry
-	maxLines int
-	logs     []string
+
+	list          *widget.List
+	levelSelect   *widget.Select
+	searchEntry   *widget.Entry
+	pauseCheck    *widget.Check
+	scrollCheck   *widget.Check
+	copyButton    *widget.Button
+	saveButton    *widget.Button
+	window        fyne.Window
+	maxLines      int
+	minLevel      slog.Level
+	query         string
+	paused        bool
+	autoScroll    bool
+	mu            sync.Mutex
+	logs          []*model.LogEntry
+	visible       []*model.LogEntry
+	refreshQueued bool
+
+	content *fyne.Container
 }
 
 func NewLogPanel() *LogPanel {
 	p := &LogPanel{
-		entry:    widget.NewMultiLineEntry(),
-		maxLines: 100,
-		logs:     make([]string, 0),
+		maxLines:   1000,
+		minLevel:   slog.LevelInfo,
+		autoScroll: true,
+		logs:       make([]*model.LogEntry, 0),
 	}
 
-	p.entry.SetPlaceHolder("Логи и сообщения валидации...")
+	p.list = widget.NewList(
+		func() int {
+			return len(p.visible)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			label.TextStyle.Monospace = true
+			return label
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			if id >= len(p.visible) {
+				return
+			}
+			entry := p.visible[id]
+			label := item.(*widget.Label)
+			label.Importance = levelImportance(entry.Level)
+			label.SetText(entry.String())
+		},
+	)
+
+	p.levelSelect = widget.NewSelect(logLevelOptions, func(selected string) {
+		var level slog.Level
+		if level.UnmarshalText([]byte(selected)) == nil {
+			p.minLevel = level
+			p.refresh()
+		}
+	})
+	p.levelSelect.SetSelected(p.minLevel.String())
+
+	p.searchEntry = widget.NewEntry()
+	p.searchEntry.SetPlaceHolder("Поиск...")
+	p.searchEntry.OnChanged = func(text string) {
+		p.query = strings.ToLower(strings.TrimSpace(text))
+		p.refresh()
+	}
+
+	p.pauseCheck = widget.NewCheck("Пауза", func(paused bool) {
+		p.paused = paused
+		if !paused {
+			p.refresh()
+		}
+	})
+
+	p.scrollCheck = widget.NewCheck("Автопрокрутка", func(enabled bool) {
+		p.autoScroll = enabled
+		if enabled {
+			p.list.ScrollToBottom()
+		}
+	})
+	p.scrollCheck.SetChecked(true)
+
+	p.copyButton = widget.NewButton("Копировать", func() {
+		fyne.CurrentApp().Clipboard().SetContent(p.Text())
+	})
+
+	p.saveButton = widget.NewButton("Сохранить...", p.saveToFile)
+
+	p.content = container.NewBorder(
+		container.NewBorder(
+			nil, nil,
+			container.NewHBox(widget.NewLabel("Уровень:"), p.levelSelect),
+			container.NewHBox(p.pauseCheck, p.scrollCheck, p.copyButton, p.saveButton),
+			p.searchEntry,
+		),
+		nil, nil, nil,
+		p.list,
+	)
 
 	p.ExtendBaseWidget(p)
 	return p
 }
 
 func (p *LogPanel) CreateRenderer() fyne.WidgetRenderer {
-	return widget.NewSimpleRenderer(p.entry)
+	return widget.NewSimpleRenderer(p.content)
 }
 
-func (p *LogPanel) AddLog(message string) {
-	p.logs = append(p.logs, message)
+func (p *LogPanel) MinSize() fyne.Size {
+	size := p.content.MinSize()
+	if size.Height < logPanelMinHeight {
+		size.Height = logPanelMinHeight
+	}
+	return size
+}
+
+// SetWindow задаёт окно для диалога сохранения журнала.
+func (p *LogPanel) SetWindow(window fyne.Window) {
+	p.window = window
+}
 
+// AddEntry можно вызывать из любой горутины: отрисовка выполняется в
+// главном потоке и объединяет частые записи в одно обновление.
+func (p *LogPanel) AddEntry(entry *model.LogEntry) {
+	p.mu.Lock()
+	p.logs = append(p.logs, entry)
 	if len(p.logs) > p.maxLines {
 		p.logs = p.logs[len(p.logs)-p.maxLines:]
 	}
 
-	p.entry.SetText(strings.Join(p.logs, "\n"))
+	queued := p.refreshQueued
+	p.refreshQueued = true
+	p.mu.Unlock()
 
-	p.entry.CursorRow = len(p.logs)
+	if !queued {
+		fyne.Do(func() {
+			p.mu.Lock()
+			p.refreshQueued = false
+			p.mu.Unlock()
+
+			if !p.paused {
+				p.refresh()
+			}
+		})
+	}
 }
 
 func (p *LogPanel) Clear() {
-	p.logs = make([]string, 0)
-	p.entry.SetText("")
+	p.mu.Lock()
+	p.logs = make([]*model.LogEntry, 0)
+	p.mu.Unlock()
+
+	p.refresh()
 }
 
-func (p *LogPanel) GetWidget() *widget.Entry {
-	return p.entry
+// Text возвращает отфильтрованные записи в том виде, в каком они показаны.
+func (p *LogPanel) Text() string {
+	lines := make([]string, 0, len(p.visible))
+	for _, entry := range p.visible {
+		lines = append(lines, entry.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (p *LogPanel) GetWidget() fyne.CanvasObject {
+	return p
+}
+
+func (p *LogPanel) refresh() {
+	p.mu.Lock()
+	visible := make([]*model.LogEntry, 0, len(p.logs))
+	for _, entry := range p.logs {
+		if p.matches(entry) {
+			visible = append(visible, entry)
+		}
+	}
+	p.mu.Unlock()
+
+	p.visible = visible
+	p.list.Refresh()
+
+	if p.autoScroll {
+		p.list.ScrollToBottom()
+	}
+}
+
+func (p *LogPanel) matches(entry *model.LogEntry) bool {
+	if entry.Level < p.minLevel {
+		return false
+	}
+	if p.query == "" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(entry.String()), p.query)
+}
+
+func (p *LogPanel) saveToFile() {
+	if p.window == nil {
+		return
+	}
+
+	text := p.Text()
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, p.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+
+		if _, err := writer.Write([]byte(text + "\n")); err != nil {
+			dialog.ShowError(err, p.window)
+		}
+	}, p.window)
+	save.SetFileName("ip-camera-viewer-" + time.Now().Format("20060102-150405") + ".log")
+	save.Show()
+}
+
+func levelImportance(level slog.Level) widget.Importance {
+	switch {
+	case level >= slog.LevelError:
+		return widget.DangerImportance
+	case level >= slog.LevelWarn:
+		return widget.WarningImportance
+	case level < slog.LevelInfo:
+		return widget.LowImportance
+	default:
+		return widget.MediumImportance
+	}
 }
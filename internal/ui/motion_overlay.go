@@ -0,0 +1,226 @@
+package ui
+
+import (
+	"image/color"
+	"ip-camera-viewer/internal/domain/model"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/widget"
+)
+
+// minZoneSize — зоны меньше этой доли кадра считаются случайным щелчком.
+const minZoneSize = 0.01
+
+var (
+	zoneFillColor   = color.NRGBA{R: 0x30, G: 0x60, B: 0xff, A: 0x50}
+	zoneStrokeColor = color.NRGBA{R: 0x30, G: 0x60, B: 0xff, A: 0xc0}
+	motionColor     = color.NRGBA{R: 0xff, G: 0x30, B: 0x30, A: 0xff}
+)
+
+// motionOverlay рисуется поверх кадра: зоны маски и рамку текущего
+// движения. В режиме редактирования зоны рисуются перетаскиванием мыши,
+// а правый щелчок по зоне удаляет её. Координаты зон — в долях всего
+// кадра, при цифровом увеличении видна только часть visible.
+type motionOverlay struct {
+	widget.BaseWidget
+
+	aspect         func() float32
+	visible        func() model.Zone
+	onZonesChanged func([]model.Zone)
+
+	editing   bool
+	zones     []model.Zone
+	motion    *model.Zone
+	drawing   bool
+	dragStart fyne.Position
+	dragEnd   fyne.Position
+}
+
+func newMotionOverlay(aspect func() float32, visible func() model.Zone) *motionOverlay {
+	o := &motionOverlay{aspect: aspect, visible: visible}
+	o.ExtendBaseWidget(o)
+	return o
+}
+
+func (o *motionOverlay) CreateRenderer() fyne.WidgetRenderer {
+	return &motionOverlayRenderer{overlay: o}
+}
+
+func (o *motionOverlay) setZones(zones []model.Zone) {
+	o.zones = append([]model.Zone(nil), zones...)
+	o.Refresh()
+}
+
+func (o *motionOverlay) setEditing(editing bool) {
+	o.editing = editing
+	o.drawing = false
+	o.Refresh()
+}
+
+func (o *motionOverlay) setMotion(box *model.Zone) {
+	o.motion = box
+	o.Refresh()
+}
+
+func (o *motionOverlay) Dragged(e *fyne.DragEvent) {
+	if !o.editing {
+		return
+	}
+
+	if !o.drawing {
+		o.drawing = true
+		o.dragStart = e.Position.Subtract(e.Dragged)
+	}
+	o.dragEnd = e.Position
+	o.Refresh()
+}
+
+func (o *motionOverlay) DragEnd() {
+	if !o.drawing {
+		return
+	}
+	o.drawing = false
+
+	zone := o.zoneBetween(o.dragStart, o.dragEnd)
+	if zone.W >= minZoneSize && zone.H >= minZoneSize {
+		o.zones = append(o.zones, zone)
+		o.notify()
+	}
+	o.Refresh()
+}
+
+func (o *motionOverlay) TappedSecondary(e *fyne.PointEvent) {
+	if !o.editing {
+		return
+	}
+
+	x, y := o.toFrame(e.Position)
+	for i := len(o.zones) - 1; i >= 0; i-- {
+		if o.zones[i].Contains(x, y) {
+			o.zones = append(o.zones[:i], o.zones[i+1:]...)
+			o.notify()
+			o.Refresh()
+			return
+		}
+	}
+}
+
+func (o *motionOverlay) notify() {
+	if o.onZonesChanged != nil {
+		o.onZonesChanged(append([]model.Zone(nil), o.zones...))
+	}
+}
+
+// frame возвращает прямоугольник, который занимает кадр при вписывании
+// с сохранением пропорций (canvas.ImageFillContain).
+func (o *motionOverlay) frame() (fyne.Position, fyne.Size) {
+	size := o.Size()
+	aspect := o.aspect()
+	if aspect <= 0 || size.Width <= 0 || size.Height <= 0 {
+		return fyne.NewPos(0, 0), size
+	}
+
+	frame := fyne.NewSize(size.Width, size.Width/aspect)
+	if size.Width/size.Height > aspect {
+		frame = fyne.NewSize(size.Height*aspect, size.Height)
+	}
+	return fyne.NewPos((size.Width-frame.Width)/2, (size.Height-frame.Height)/2), frame
+}
+
+func (o *motionOverlay) toFrame(p fyne.Position) (float64, float64) {
+	pos, size := o.frame()
+	if size.Width <= 0 || size.Height <= 0 {
+		return 0, 0
+	}
+
+	visible := o.visible()
+	x := visible.X + float64((p.X-pos.X)/size.Width)*visible.W
+	y := visible.Y + float64((p.Y-pos.Y)/size.Height)*visible.H
+	return min(max(x, 0), 1), min(max(y, 0), 1)
+}
+
+func (o *motionOverlay) zoneBetween(a, b fyne.Position) model.Zone {
+	x0, y0 := o.toFrame(a)
+	x1, y1 := o.toFrame(b)
+	return model.Zone{
+		X: min(x0, x1),
+		Y: min(y0, y1),
+		W: max(x0, x1) - min(x0, x1),
+		H: max(y0, y1) - min(y0, y1),
+	}
+}
+
+func (o *motionOverlay) toCanvas(zone model.Zone) (fyne.Position, fyne.Size) {
+	pos, size := o.frame()
+	visible := o.visible()
+	scaleX := size.Width / float32(visible.W)
+	scaleY := size.Height / float32(visible.H)
+	return fyne.NewPos(pos.X+float32(zone.X-visible.X)*scaleX, pos.Y+float32(zone.Y-visible.Y)*scaleY),
+		fyne.NewSize(float32(zone.W)*scaleX, float32(zone.H)*scaleY)
+}
+
+// clip обрезает зону по видимой части кадра: контейнер превью не обрезает
+// то, что выходит за его границы.
+func (o *motionOverlay) clip(zone model.Zone) model.Zone {
+	visible := o.visible()
+	x0, y0 := max(zone.X, visible.X), max(zone.Y, visible.Y)
+	x1 := min(zone.X+zone.W, visible.X+visible.W)
+	y1 := min(zone.Y+zone.H, visible.Y+visible.H)
+	return model.Zone{X: x0, Y: y0, W: x1 - x0, H: y1 - y0}
+}
+
+type motionOverlayRenderer struct {
+	overlay *motionOverlay
+	objects []fyne.CanvasObject
+}
+
+func (r *motionOverlayRenderer) Layout(fyne.Size) {
+	r.rebuild()
+}
+
+func (r *motionOverlayRenderer) MinSize() fyne.Size {
+	return fyne.NewSize(0, 0)
+}
+
+func (r *motionOverlayRenderer) Refresh() {
+	r.rebuild()
+	canvas.Refresh(r.overlay)
+}
+
+func (r *motionOverlayRenderer) Objects() []fyne.CanvasObject {
+	return r.objects
+}
+
+func (r *motionOverlayRenderer) Destroy() {}
+
+func (r *motionOverlayRenderer) rebuild() {
+	o := r.overlay
+	r.objects = r.objects[:0]
+
+	for _, zone := range o.zones {
+		r.addRect(zone, zoneFillColor, zoneStrokeColor)
+	}
+	if o.drawing {
+		r.addRect(o.zoneBetween(o.dragStart, o.dragEnd), zoneFillColor, zoneStrokeColor)
+	}
+	if o.motion != nil {
+		r.addRect(*o.motion, color.Transparent, motionColor)
+	}
+}
+
+func (r *motionOverlayRenderer) addRect(zone model.Zone, fill, stroke color.Color) {
+	zone = r.overlay.clip(zone)
+	if zone.Empty() {
+		return
+	}
+
+	rect := canvas.NewRectangle(fill)
+	rect.StrokeColor = stroke
+	rect.StrokeWidth = 2
+
+	pos, size := r.overlay.toCanvas(zone)
+	rect.Move(pos)
+	rect.Resize(size)
+	r.objects = append(r.objects, rect)
+}
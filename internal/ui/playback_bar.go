@@ -0,0 +1,192 @@
+package ui
+
+import (
+	"errors"
+	"ip-camera-viewer/internal/domain/model"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+)
+
+const playbackTimeLayout = "2006-01-02 15:04:05"
+
+// playbackBar — панель архива NVR под превью: выбор времени, шкала суток,
+// пауза и скорость. Положение на шкале обновляется из StreamInfo.
+type playbackBar struct {
+	timeEntry     *widget.Entry
+	timeline      *widget.Slider
+	pauseButton   *widget.Button
+	liveButton    *widget.Button
+	scaleSelect   *widget.Select
+	positionLabel *widget.Label
+	container     *fyne.Container
+
+	// day — начало суток, которые показывает шкала.
+	day    time.Time
+	info   *model.PlaybackInfo
+	shown  bool
+	silent bool
+
+	onSeek  func(start time.Time)
+	onPause func(paused bool)
+	onScale func(scale float64)
+	onLive  func()
+}
+
+func newPlaybackBar() *playbackBar {
+	b := &playbackBar{}
+
+	now := time.Now()
+	b.day = startOfDay(now)
+
+	b.timeEntry = widget.NewEntry()
+	b.timeEntry.SetText(now.Add(-time.Hour).Format(playbackTimeLayout))
+	b.timeEntry.Validator = func(text string) error {
+		_, err := parsePlaybackTime(text)
+		return err
+	}
+	b.timeEntry.OnSubmitted = func(string) {
+		b.seekToEntry()
+	}
+
+	goButton := widget.NewButton("Архив", b.seekToEntry)
+
+	b.timeline = widget.NewSlider(0, float64(24*time.Hour/time.Second-1))
+	b.timeline.OnChangeEnded = func(value float64) {
+		start := b.day.Add(time.Duration(value) * time.Second)
+		b.timeEntry.SetText(start.Format(playbackTimeLayout))
+		if b.onSeek != nil {
+			b.onSeek(start)
+		}
+	}
+
+	b.pauseButton = widget.NewButton("Пауза", func() {
+		if b.info == nil || b.onPause == nil {
+			return
+		}
+		b.onPause(!b.info.Paused)
+	})
+
+	scales := make([]string, len(model.PlaybackScales))
+	for i, scale := range model.PlaybackScales {
+		scales[i] = formatScale(scale)
+	}
+	b.scaleSelect = widget.NewSelect(scales, func(selected string) {
+		if b.silent || b.onScale == nil {
+			return
+		}
+		for _, scale := range model.PlaybackScales {
+			if formatScale(scale) == selected {
+				b.onScale(scale)
+				return
+			}
+		}
+	})
+
+	b.liveButton = widget.NewButton("Эфир", func() {
+		if b.onLive != nil {
+			b.onLive()
+		}
+	})
+
+	b.positionLabel = widget.NewLabel("")
+
+	b.container = container.NewVBox(
+		container.NewBorder(nil, nil,
+			container.NewHBox(goButton, b.pauseButton, b.scaleSelect, b.liveButton),
+			b.positionLabel,
+			b.timeEntry,
+		),
+		b.timeline,
+	)
+	b.container.Hide()
+	b.update(nil)
+
+	return b
+}
+
+func (b *playbackBar) seekToEntry() {
+	start, err := parsePlaybackTime(b.timeEntry.Text)
+	if err != nil {
+		return
+	}
+
+	b.day = startOfDay(start)
+	b.timeline.SetValue(start.Sub(b.day).Seconds())
+	if b.onSeek != nil {
+		b.onSeek(start)
+	}
+}
+
+// update показывает положение архива. nil — поток в эфире.
+func (b *playbackBar) update(info *model.PlaybackInfo) {
+	if b.shown && samePlayback(b.info, info) {
+		return
+	}
+	b.info = info
+	b.shown = true
+
+	if info == nil {
+		b.positionLabel.SetText("Эфир")
+		b.pauseButton.Disable()
+		b.scaleSelect.Disable()
+		b.liveButton.Disable()
+		b.silent = true
+		b.scaleSelect.SetSelected(formatScale(1))
+		b.silent = false
+		return
+	}
+
+	b.pauseButton.Enable()
+	b.scaleSelect.Enable()
+	b.liveButton.Enable()
+
+	if info.Paused {
+		b.pauseButton.SetText("Продолжить")
+	} else {
+		b.pauseButton.SetText("Пауза")
+	}
+
+	b.silent = true
+	b.scaleSelect.SetSelected(formatScale(info.Scale))
+	b.silent = false
+
+	text := info.Position.Local().Format(playbackTimeLayout)
+	if info.Paused {
+		text += " ⏸"
+	}
+	b.positionLabel.SetText(text)
+
+	b.day = startOfDay(info.Position.Local())
+	b.timeline.SetValue(info.Position.Local().Sub(b.day).Seconds())
+}
+
+func samePlayback(a, b *model.PlaybackInfo) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Position.Equal(b.Position) && a.Scale == b.Scale && a.Paused == b.Paused
+}
+
+func parsePlaybackTime(text string) (time.Time, error) {
+	t, err := time.ParseInLocation(playbackTimeLayout, text, time.Local)
+	if err != nil {
+		return time.Time{}, errors.New("ожидается время в формате ГГГГ-ММ-ДД чч:мм:сс")
+	}
+	if t.After(time.Now()) {
+		return time.Time{}, errors.New("время записи не может быть в будущем")
+	}
+	return t, nil
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+func formatScale(scale float64) string {
+	return "×" + strconv.FormatFloat(scale, 'f', -1, 64)
+}
@@ -0,0 +1,69 @@
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/driver/desktop"
+	"fyne.io/fyne/v2/widget"
+)
+
+// PushToTalkButton активен, пока зажата кнопка мыши.
+type PushToTalkButton struct {
+	widget.Button
+	onPress   func()
+	onRelease func()
+	pressed   bool
+}
+
+func NewPushToTalkButton(label string, onPress, onRelease func()) *PushToTalkButton {
+	b := &PushToTalkButton{
+		onPress:   onPress,
+		onRelease: onRelease,
+	}
+	b.Text = label
+	b.ExtendBaseWidget(b)
+	return b
+}
+
+func (b *PushToTalkButton) Tapped(*fyne.PointEvent) {}
+
+func (b *PushToTalkButton) MouseDown(*desktop.MouseEvent) {
+	if b.Disabled() || b.pressed {
+		return
+	}
+
+	b.pressed = true
+	b.Importance = widget.DangerImportance
+	b.Refresh()
+
+	if b.onPress != nil {
+		b.onPress()
+	}
+}
+
+func (b *PushToTalkButton) MouseUp(*desktop.MouseEvent) {
+	b.release()
+}
+
+func (b *PushToTalkButton) MouseOut() {
+	b.Button.MouseOut()
+	b.release()
+}
+
+func (b *PushToTalkButton) Hide() {
+	b.release()
+	b.Button.Hide()
+}
+
+func (b *PushToTalkButton) release() {
+	if !b.pressed {
+		return
+	}
+
+	b.pressed = false
+	b.Importance = widget.MediumImportance
+	b.Refresh()
+
+	if b.onRelease != nil {
+		b.onRelease()
+	}
+}
@@ -2,29 +2,63 @@ package ui
 
 import (
 	"context"
+	"fmt"
 	"image"
+	"image/color"
+	"image/draw"
 	"ip-camera-viewer/internal/domain/model"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
+const meterFloorDB = -60.0
+
 type VideoPreviewWidget struct {
 	widget.BaseWidget
 	streamName   string
 	image        *canvas.Image
+	frame        image.Image
+	view         viewport
+	visible      model.Zone
+	surface      *zoomSurface
+	minimap      *minimap
+	fullscreen   bool
+	fullButton   *widget.Button
 	statusLabel  *widget.Label
+	muteCheck    *widget.Check
+	levelBar     *widget.ProgressBar
+	talkButton   *PushToTalkButton
+	recordButton *widget.Button
+	staleText    *canvas.Text
+	staleBadge   *fyne.Container
+	overlay      *motionOverlay
+	zonesCheck   *widget.Check
+	motionLabel  *widget.Label
+	motionBar    *fyne.Container
+	playback     *playbackBar
 	frameChannel <-chan *model.FrameData
 	cancelFunc   context.CancelFunc
 	container    *fyne.Container
+	onResize     func(width, height int)
+	onMute       func(muted bool)
+	onTalk       func(talking bool)
+	onRecord     func()
+	onFullscreen func(fullscreen bool)
+	warnings     []model.VideoWarning
 }
 
 func NewVideoPreviewWidget(streamName string) *VideoPreviewWidget {
 	w := &VideoPreviewWidget{
 		streamName:  streamName,
 		statusLabel: widget.NewLabel("Статус: Отключено"),
+		view:        newViewport(),
+		visible:     model.Zone{W: 1, H: 1},
 	}
 
 	placeholder := image.NewRGBA(image.Rect(0, 0, 640, 360))
@@ -32,12 +66,91 @@ func NewVideoPreviewWidget(streamName string) *VideoPreviewWidget {
 	w.image.FillMode = canvas.ImageFillContain
 	w.image.SetMinSize(fyne.NewSize(640, 360))
 
+	w.muteCheck = widget.NewCheck("Без звука", func(muted bool) {
+		if w.onMute != nil {
+			w.onMute(muted)
+		}
+	})
+
+	w.levelBar = widget.NewProgressBar()
+	w.levelBar.TextFormatter = func() string {
+		if w.levelBar.Value <= 0 {
+			return "—"
+		}
+		return fmt.Sprintf("%.0f dB", w.levelBar.Value*-meterFloorDB+meterFloorDB)
+	}
+
+	w.talkButton = NewPushToTalkButton("Говорить", func() {
+		if w.onTalk != nil {
+			w.onTalk(true)
+		}
+	}, func() {
+		if w.onTalk != nil {
+			w.onTalk(false)
+		}
+	})
+	w.talkButton.Hide()
+
+	w.recordButton = widget.NewButton("Записать клип", func() {
+		if w.onRecord != nil {
+			w.onRecord()
+		}
+	})
+	w.recordButton.Hide()
+
+	w.fullButton = widget.NewButtonWithIcon("", theme.ViewFullScreenIcon(), w.ToggleFullscreen)
+	w.fullButton.Hide()
+
+	w.overlay = newMotionOverlay(func() float32 {
+		return aspectRatio(w.image.Image)
+	}, func() model.Zone {
+		return w.visible
+	})
+	w.surface = newZoomSurface(w)
+	w.minimap = newMinimap(func() float32 {
+		return aspectRatio(w.frame)
+	})
+	w.minimap.Hide()
+
+	w.staleText = canvas.NewText("", color.NRGBA{R: 0xff, G: 0x50, B: 0x50, A: 0xff})
+	w.staleText.TextStyle.Bold = true
+	w.staleBadge = container.NewStack(
+		canvas.NewRectangle(color.NRGBA{A: 0xb0}),
+		container.NewPadded(w.staleText),
+	)
+	w.staleBadge.Hide()
+
+	w.zonesCheck = widget.NewCheck("Зоны маски", func(editing bool) {
+		w.overlay.setEditing(editing)
+	})
+	clearZones := widget.NewButton("Очистить зоны", func() {
+		w.overlay.setZones(nil)
+		w.overlay.notify()
+	})
+	w.motionLabel = widget.NewLabel("● Движение")
+	w.motionLabel.Importance = widget.DangerImportance
+	w.motionLabel.Hide()
+	w.motionBar = container.NewHBox(w.zonesCheck, clearZones, w.motionLabel)
+	w.motionBar.Hide()
+
+	w.playback = newPlaybackBar()
+
 	w.container = container.NewBorder(
 		nil,
-		w.statusLabel,
+		container.NewVBox(
+			w.statusLabel,
+			container.NewBorder(nil, nil, w.muteCheck, container.NewHBox(w.recordButton, w.talkButton, w.fullButton), w.levelBar),
+			w.motionBar,
+			w.playback.container,
+		),
 		nil,
 		nil,
-		w.image,
+		container.NewStack(
+			w.image,
+			w.overlay,
+			w.surface,
+			container.NewVBox(container.NewHBox(w.staleBadge, layout.NewSpacer(), w.minimap)),
+		),
 	)
 
 	w.ExtendBaseWidget(w)
@@ -48,6 +161,35 @@ func (w *VideoPreviewWidget) CreateRenderer() fyne.WidgetRenderer {
 	return widget.NewSimpleRenderer(w.container)
 }
 
+func (w *VideoPreviewWidget) Resize(size fyne.Size) {
+	w.BaseWidget.Resize(size)
+
+	if w.onResize != nil {
+		w.onResize(w.PixelSize())
+	}
+}
+
+func (w *VideoPreviewWidget) SetOnResize(handler func(width, height int)) {
+	w.onResize = handler
+}
+
+// PixelSize возвращает размер кадра, которого достаточно для превью. При
+// цифровом увеличении нужен кадр в полном разрешении, тогда размер нулевой.
+func (w *VideoPreviewWidget) PixelSize() (int, int) {
+	if w.view.zoomed() {
+		return 0, 0
+	}
+
+	size := w.image.Size()
+	scale := float32(1)
+	if app := fyne.CurrentApp(); app != nil {
+		if c := app.Driver().CanvasForObject(w); c != nil {
+			scale = c.Scale()
+		}
+	}
+	return int(size.Width * scale), int(size.Height * scale)
+}
+
 func (w *VideoPreviewWidget) StartStreaming(ctx context.Context, frameChannel <-chan *model.FrameData) {
 	streamCtx, cancel := context.WithCancel(ctx)
 	w.cancelFunc = cancel
@@ -64,6 +206,8 @@ func (w *VideoPreviewWidget) StopStreaming() {
 }
 
 func (w *VideoPreviewWidget) updateLoop(ctx context.Context) {
+	var shown *model.FrameData
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -72,20 +216,287 @@ func (w *VideoPreviewWidget) updateLoop(ctx context.Context) {
 			if !ok {
 				return
 			}
-			if frame != nil && frame.Image != nil {
-				w.image.Image = frame.Image
-				w.image.Refresh()
+			if frame == nil || frame.Image == nil {
+				frame.Release()
+				continue
 			}
+
+			fyne.DoAndWait(func() {
+				w.frame = frame.Image
+				w.showFrame()
+			})
+
+			shown.Release()
+			shown = frame
 		}
 	}
 }
 
+// Snapshot копирует показанный сейчас кадр целиком, без учёта увеличения.
+// Вызывается из главного потока.
+func (w *VideoPreviewWidget) Snapshot() image.Image {
+	if w.cancelFunc == nil || w.frame == nil {
+		return nil
+	}
+
+	src := w.frame
+	dst := image.NewRGBA(src.Bounds())
+	draw.Draw(dst, dst.Bounds(), src, src.Bounds().Min, draw.Src)
+	return dst
+}
+
 func (w *VideoPreviewWidget) UpdateStatus(status model.StreamStatus, info *model.StreamInfo) {
 	statusText := status.String()
 	if info != nil {
 		if status == model.StatusError && info.ErrorMessage != "" {
 			statusText = status.String() + ": " + info.ErrorMessage
 		}
+		if info.Warnings != nil {
+			w.warnings = info.Warnings
+		}
+	}
+	if status != model.StatusPlaying {
+		w.warnings = nil
+	}
+
+	w.updateStale(status, info)
+
+	for _, warning := range w.warnings {
+		statusText += " ⚠ " + warning.String()
 	}
 	w.statusLabel.SetText("Статус: " + statusText)
+	if len(w.warnings) > 0 {
+		w.statusLabel.Importance = widget.WarningImportance
+	} else {
+		w.statusLabel.Importance = widget.MediumImportance
+	}
+	w.statusLabel.Refresh()
+}
+
+func (w *VideoPreviewWidget) SetOnMuteChanged(handler func(muted bool)) {
+	w.onMute = handler
+}
+
+func (w *VideoPreviewWidget) SetMuted(muted bool) {
+	w.muteCheck.SetChecked(muted)
+}
+
+func (w *VideoPreviewWidget) IsMuted() bool {
+	return w.muteCheck.Checked
+}
+
+func (w *VideoPreviewWidget) SetOnTalk(handler func(talking bool)) {
+	w.onTalk = handler
+}
+
+func (w *VideoPreviewWidget) SetTalkAvailable(available bool) {
+	if available {
+		w.talkButton.Show()
+	} else {
+		w.talkButton.Hide()
+	}
+}
+
+// SetOnRecord показывает кнопку ручной записи клипа из буфера потока.
+func (w *VideoPreviewWidget) SetOnRecord(handler func()) {
+	w.onRecord = handler
+	w.recordButton.Show()
+}
+
+func (w *VideoPreviewWidget) UpdateAudioLevel(level *model.AudioLevel) {
+	value := 0.0
+	if level != nil && level.RMS > meterFloorDB {
+		value = (level.RMS - meterFloorDB) / -meterFloorDB
+	}
+
+	fyne.Do(func() {
+		w.levelBar.SetValue(value)
+	})
+}
+
+// updateStale показывает поверх последнего кадра, что он устарел: поток
+// завис или переподключается.
+func (w *VideoPreviewWidget) updateStale(status model.StreamStatus, info *model.StreamInfo) {
+	stalled := info != nil && info.Stalled
+	if !stalled && status != model.StatusReconnecting {
+		w.staleBadge.Hide()
+		return
+	}
+
+	text := "УСТАРЕЛО"
+	if info != nil && !info.LastFrameTime.IsZero() {
+		text += " · последний кадр " + info.LastFrameTime.Format("15:04:05")
+	}
+	w.staleText.Text = text
+	w.staleText.Refresh()
+	w.staleBadge.Show()
+}
+
+// EnableZoneEditing показывает под кадром переключатель рисования зон маски
+// детектора движения. Зона рисуется перетаскиванием, удаляется правым щелчком.
+func (w *VideoPreviewWidget) EnableZoneEditing() {
+	w.motionBar.Show()
+}
+
+func (w *VideoPreviewWidget) SetMotionZones(zones []model.Zone) {
+	w.overlay.setZones(zones)
+}
+
+func (w *VideoPreviewWidget) SetOnMotionZonesChanged(handler func(zones []model.Zone)) {
+	w.overlay.onZonesChanged = handler
+}
+
+// ShowMotion рисует рамку движения до события MotionEnded. nil сбрасывает
+// индикатор. Вызывается из главного потока.
+func (w *VideoPreviewWidget) ShowMotion(event *model.MotionEvent) {
+	if event == nil || event.Type == model.MotionEnded {
+		w.overlay.setMotion(nil)
+		w.motionLabel.Hide()
+		return
+	}
+
+	box := event.Box
+	w.overlay.setMotion(&box)
+	w.motionLabel.Show()
+}
+
+// EnablePlayback показывает под кадром панель архива NVR: выбор времени,
+// шкалу суток, паузу, скорость и возврат в эфир.
+func (w *VideoPreviewWidget) EnablePlayback() {
+	w.playback.container.Show()
+}
+
+func (w *VideoPreviewWidget) SetOnSeek(handler func(start time.Time)) {
+	w.playback.onSeek = handler
+}
+
+func (w *VideoPreviewWidget) SetOnPause(handler func(paused bool)) {
+	w.playback.onPause = handler
+}
+
+func (w *VideoPreviewWidget) SetOnScale(handler func(scale float64)) {
+	w.playback.onScale = handler
+}
+
+func (w *VideoPreviewWidget) SetOnLive(handler func()) {
+	w.playback.onLive = handler
+}
+
+// UpdatePlayback показывает положение архива, nil — поток в эфире.
+// Вызывается из главного потока.
+func (w *VideoPreviewWidget) UpdatePlayback(info *model.PlaybackInfo) {
+	w.playback.update(info)
+}
+
+// SetOnFullscreen показывает кнопку полноэкранного режима. Само окно
+// переключает обработчик.
+func (w *VideoPreviewWidget) SetOnFullscreen(handler func(fullscreen bool)) {
+	w.onFullscreen = handler
+	w.fullButton.Show()
+}
+
+// ToggleFullscreen переключает полноэкранный режим превью: кнопкой, двойным
+// щелчком по кадру или клавишами F и F11.
+func (w *VideoPreviewWidget) ToggleFullscreen() {
+	if w.onFullscreen == nil {
+		return
+	}
+
+	w.fullscreen = !w.fullscreen
+	if w.fullscreen {
+		w.fullButton.SetIcon(theme.ViewRestoreIcon())
+	} else {
+		w.fullButton.SetIcon(theme.ViewFullScreenIcon())
+	}
+	w.onFullscreen(w.fullscreen)
+}
+
+// FocusFrame передаёт кадру фокус клавиатуры для управления увеличением.
+func (w *VideoPreviewWidget) FocusFrame() {
+	if c := fyne.CurrentApp().Driver().CanvasForObject(w.surface); c != nil {
+		c.Focus(w.surface)
+	}
+}
+
+// showFrame показывает видимую при увеличении часть последнего кадра.
+func (w *VideoPreviewWidget) showFrame() {
+	w.visible = w.view.rect()
+	if w.frame != nil {
+		w.image.Image, w.visible = w.view.crop(w.frame)
+		w.image.Refresh()
+	}
+	if w.view.zoomed() {
+		w.minimap.update(w.visible, w.view.zoom)
+	}
+}
+
+func (w *VideoPreviewWidget) zoomAt(x, y, factor float64) {
+	w.changeView(func(v *viewport) { v.zoomAt(x, y, factor) })
+}
+
+func (w *VideoPreviewWidget) pan(dx, dy float64) {
+	w.changeView(func(v *viewport) { v.pan(dx, dy) })
+}
+
+// changeView применяет изменение увеличения. При входе в увеличение и
+// выходе из него декодер переключается между полным разрешением и
+// размером превью.
+func (w *VideoPreviewWidget) changeView(change func(*viewport)) {
+	wasZoomed := w.view.zoomed()
+	change(&w.view)
+	zoomed := w.view.zoomed()
+
+	if zoomed {
+		w.minimap.Show()
+	} else {
+		w.minimap.Hide()
+	}
+	w.showFrame()
+	w.overlay.Refresh()
+
+	if zoomed != wasZoomed && w.onResize != nil {
+		w.onResize(w.PixelSize())
+	}
+}
+
+// handleKey — клавиши кадра в фокусе: +/- увеличение, 0 сброс, стрелки
+// сдвиг, F и F11 полный экран, Esc выход из полного экрана или сброс.
+func (w *VideoPreviewWidget) handleKey(name fyne.KeyName) {
+	step := panStep / w.view.zoom
+
+	switch name {
+	case fyne.KeyPlus, fyne.KeyEqual:
+		w.zoomAt(w.view.cx, w.view.cy, zoomStep)
+	case fyne.KeyMinus:
+		w.zoomAt(w.view.cx, w.view.cy, 1/zoomStep)
+	case fyne.Key0:
+		w.changeView(func(v *viewport) { *v = newViewport() })
+	case fyne.KeyLeft:
+		w.pan(-step, 0)
+	case fyne.KeyRight:
+		w.pan(step, 0)
+	case fyne.KeyUp:
+		w.pan(0, -step)
+	case fyne.KeyDown:
+		w.pan(0, step)
+	case fyne.KeyF, fyne.KeyF11:
+		w.ToggleFullscreen()
+	case fyne.KeyEscape:
+		if w.fullscreen {
+			w.ToggleFullscreen()
+		} else {
+			w.changeView(func(v *viewport) { *v = newViewport() })
+		}
+	}
+}
+
+func aspectRatio(img image.Image) float32 {
+	if img == nil {
+		return 0
+	}
+	bounds := img.Bounds()
+	if bounds.Dy() == 0 {
+		return 0
+	}
+	return float32(bounds.Dx()) / float32(bounds.Dy())
 }
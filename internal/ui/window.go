@@ -2,13 +2,19 @@ package ui
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"image"
 	"ip-camera-viewer/internal/app/service"
 	"ip-camera-viewer/internal/domain/model"
+	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 )
 
@@ -17,22 +23,39 @@ type MainWindow struct {
 	window            fyne.Window
 	connectionService *service.ConnectionService
 	configService     *service.ConfigurationService
+	presetService     *service.PresetService
+	diagnostics       *service.DiagnosticsService
 	logger            *service.LoggerService
 
 	connectionForm *ConnectionForm
 	highPreview    *VideoPreviewWidget
 	lowPreview     *VideoPreviewWidget
 	logPanel       *LogPanel
+	eventList      *EventList
+
+	// content — основная раскладка окна, previewSlots — места превью в
+	// ней, откуда превью забирается на время полноэкранного режима.
+	content      fyne.CanvasObject
+	previewSlots map[*VideoPreviewWidget]*fyne.Container
 
 	ctx        context.Context
 	cancelFunc context.CancelFunc
+
+	certPromptShown bool
 }
 
 func NewMainWindow() *MainWindow {
-	logger := service.NewLoggerService()
+	logger, err := service.NewFileLoggerService(service.DefaultLogOptions())
+	if err != nil {
+		logger = service.NewLoggerService()
+		logger.Error("Не удалось открыть файл журнала", err)
+	}
+
 	streamManager := service.NewStreamManager(logger)
 	connectionService := service.NewConnectionService(logger, streamManager)
 	configService := service.NewConfigurationService(logger)
+	presetService := service.NewPresetService(logger)
+	diagnostics := service.NewDiagnosticsService(logger, streamManager, configService)
 
 	myApp := app.New()
 	win := myApp.NewWindow("IP Camera Viewer")
@@ -44,19 +67,28 @@ func NewMainWindow() *MainWindow {
 		window:            win,
 		connectionService: connectionService,
 		configService:     configService,
-		logger:            logger,
+		presetService:     presetService,
+		diagnostics:       diagnostics,
+		logger:            logger.Component("ui"),
 		connectionForm:    NewConnectionForm(),
 		highPreview:       NewVideoPreviewWidget("High"),
 		lowPreview:        NewVideoPreviewWidget("Low"),
 		logPanel:          NewLogPanel(),
+		eventList:         NewEventList(),
 		ctx:               ctx,
 		cancelFunc:        cancel,
 	}
 
+	logger.AddSink(mw.logPanel.AddEntry)
+	logger.CaptureStandardLog()
+	mw.logPanel.SetWindow(win)
+
 	mw.setupUI()
 	mw.setupHandlers()
 	mw.loadSavedConfig()
 	mw.startStatusMonitoring()
+	mw.startAudioLevelMonitoring()
+	mw.startPlaybackMonitoring()
 
 	return mw
 }
@@ -64,29 +96,37 @@ func NewMainWindow() *MainWindow {
 func (mw *MainWindow) setupUI() {
 	topSection := mw.connectionForm
 
+	mw.previewSlots = map[*VideoPreviewWidget]*fyne.Container{
+		mw.highPreview: container.NewStack(mw.highPreview),
+		mw.lowPreview:  container.NewStack(mw.lowPreview),
+	}
+
 	videoSection := container.NewGridWithColumns(2,
 		container.NewBorder(
 			widget.NewLabel("Preview High"),
 			nil, nil, nil,
-			mw.highPreview,
+			mw.previewSlots[mw.highPreview],
 		),
 		container.NewBorder(
 			widget.NewLabel("Preview Low"),
 			nil, nil, nil,
-			mw.lowPreview,
+			mw.previewSlots[mw.lowPreview],
 		),
 	)
 
+	logSplit := container.NewHSplit(mw.logPanel.GetWidget(), mw.eventList.GetWidget())
+	logSplit.Offset = 0.7
+
 	logSection := container.NewBorder(
 		widget.NewLabel("Логи и сообщения:"),
 		nil,
-		nil, container.NewBorder(
+		nil, container.NewVBox(
 			mw.connectionForm.connectButton,
 			mw.connectionForm.disconnectButton,
-			nil, nil,
-			nil,
+			layout.NewSpacer(),
+			widget.NewButton("Экспорт диагностики", mw.handleExportDiagnostics),
 		),
-		mw.logPanel.GetWidget(),
+		logSplit,
 	)
 
 	mainContainer := container.NewBorder(
@@ -97,15 +137,37 @@ func (mw *MainWindow) setupUI() {
 		videoSection,
 	)
 
+	mw.content = mainContainer
 	mw.window.SetContent(mainContainer)
 	mw.window.Resize(fyne.NewSize(1280, 800))
 }
 
 func (mw *MainWindow) setupHandlers() {
+	mw.connectionForm.SetValidator(func(config *model.ConnectionConfig) *model.ValidationResult {
+		_, result := mw.connectionService.ValidateConfig(config)
+		return result
+	})
+
 	mw.connectionForm.SetOnCheck(func(config *model.ConnectionConfig) {
 		mw.handleCheck(config)
 	})
 
+	vendors := make([]string, 0, len(mw.presetService.Presets()))
+	for _, preset := range mw.presetService.Presets() {
+		vendors = append(vendors, preset.Vendor)
+	}
+	mw.connectionForm.SetPresets(vendors)
+
+	mw.connectionForm.SetOnPresetSelected(func(vendor string) {
+		if preset, ok := mw.presetService.Find(vendor); ok {
+			mw.connectionForm.SetStreamTemplates(preset.Main, preset.Sub)
+		}
+	})
+
+	mw.connectionForm.SetOnAutoDetect(func(config *model.ConnectionConfig) {
+		mw.handleAutoDetect(config)
+	})
+
 	mw.connectionForm.SetOnConnect(func(config *model.ConnectionConfig) {
 		mw.handleConnect(config)
 	})
@@ -114,79 +176,280 @@ func (mw *MainWindow) setupHandlers() {
 		mw.handleDisconnect()
 	})
 
+	mw.highPreview.SetOnResize(func(width, height int) {
+		mw.connectionService.SetPreviewSize("High", width, height)
+	})
+
+	mw.lowPreview.SetOnResize(func(width, height int) {
+		mw.connectionService.SetPreviewSize("Low", width, height)
+	})
+
+	mw.highPreview.SetOnMuteChanged(func(muted bool) {
+		mw.connectionService.SetMuted("High", muted)
+	})
+
+	mw.lowPreview.SetOnMuteChanged(func(muted bool) {
+		mw.connectionService.SetMuted("Low", muted)
+	})
+
+	mw.highPreview.SetOnTalk(func(talking bool) {
+		mw.handleTalk("High", talking)
+	})
+
+	mw.lowPreview.EnableZoneEditing()
+	mw.lowPreview.SetOnMotionZonesChanged(func(zones []model.Zone) {
+		mw.connectionForm.SetMotionZones(zones)
+	})
+
+	mw.connectionForm.SetOnMotionChanged(func(config model.MotionConfig) {
+		mw.connectionService.SetMotionConfig("Low", config)
+	})
+
+	mw.connectionForm.SetOnRecordChanged(func(onMotion bool) {
+		mw.connectionService.SetRecordOnMotion(onMotion)
+	})
+
+	mw.highPreview.SetOnRecord(func() {
+		if err := mw.connectionService.TriggerRecording("High", "manual"); err != nil {
+			mw.logger.Error("Ошибка записи клипа", err, "stream", "High")
+		}
+	})
+
+	for name, preview := range map[string]*VideoPreviewWidget{"High": mw.highPreview, "Low": mw.lowPreview} {
+		mw.setupPlayback(name, preview)
+
+		preview.SetOnFullscreen(func(fullscreen bool) {
+			mw.setPreviewFullscreen(preview, fullscreen)
+		})
+	}
+
+	// оба потока обычно несут один и тот же звук
+	mw.lowPreview.SetMuted(true)
+
 	mw.window.SetOnClosed(func() {
 		mw.cancelFunc()
 		mw.connectionService.Disconnect()
+		mw.logger.Close()
+	})
+}
+
+// setPreviewFullscreen разворачивает превью на весь экран вместо основной
+// раскладки окна и возвращает его на место.
+func (mw *MainWindow) setPreviewFullscreen(preview *VideoPreviewWidget, fullscreen bool) {
+	slot := mw.previewSlots[preview]
+	if fullscreen {
+		slot.Remove(preview)
+		mw.window.SetContent(preview)
+	} else {
+		mw.window.SetContent(mw.content)
+		slot.Add(preview)
+	}
+
+	mw.window.SetFullScreen(fullscreen)
+	preview.FocusFrame()
+}
+
+// setupPlayback связывает панель архива превью с командами NVR. Команды
+// ждут ответа сервера, поэтому выполняются вне главного потока.
+func (mw *MainWindow) setupPlayback(streamName string, preview *VideoPreviewWidget) {
+	preview.EnablePlayback()
+
+	run := func(action string, command func() error) {
+		go func() {
+			if err := command(); err != nil {
+				mw.logger.Error("Ошибка команды архива", err, "stream", streamName, "action", action)
+			}
+		}()
+	}
+
+	preview.SetOnSeek(func(start time.Time) {
+		run("архив", func() error {
+			return mw.connectionService.SeekPlayback(streamName, start)
+		})
+	})
+	preview.SetOnPause(func(paused bool) {
+		run("пауза", func() error {
+			if paused {
+				return mw.connectionService.PausePlayback(streamName)
+			}
+			return mw.connectionService.ResumePlayback(streamName)
+		})
+	})
+	preview.SetOnScale(func(scale float64) {
+		run("скорость", func() error {
+			return mw.connectionService.SetPlaybackScale(streamName, scale)
+		})
+	})
+	preview.SetOnLive(func() {
+		run("эфир", func() error {
+			return mw.connectionService.GoLive(streamName)
+		})
 	})
 }
 
 func (mw *MainWindow) handleCheck(config *model.ConnectionConfig) {
-	mw.logPanel.AddLog("Проверка конфигурации...")
+	mw.logger.Info("Проверка конфигурации")
 
 	resolved, result := mw.connectionService.ValidateConfig(config)
 
 	if !result.Valid {
-		mw.logPanel.AddLog("Ошибки валидации:")
 		for _, err := range result.Errors {
-			mw.logPanel.AddLog("  - " + err.Field + ": " + err.Message)
+			mw.logger.Warn("Ошибка валидации", "field", err.Field, "message", err.Message)
 		}
-		dialog.ShowError(&validationError{result.GetErrorMessage()}, mw.window)
+		mw.connectionForm.ShowValidation(result)
 		return
 	}
 
-	mw.logPanel.AddLog("Валидация пройдена")
-	mw.logPanel.AddLog("RTSP URI #1: " + resolved.URI1Masked)
-	mw.logPanel.AddLog("RTSP URI #2: " + resolved.URI2Masked)
+	mw.logger.Info("Валидация пройдена", "uri1", resolved.URI1Masked, "uri2", resolved.URI2Masked)
 	mw.configService.SaveConfig(config)
-	mw.connectionForm.rtspURI1Entry.SetText(resolved.URI1Masked)
-	mw.connectionForm.rtspURI2Entry.SetText(resolved.URI2Masked)
-
-	mw.connectionForm.ConnectionPermission(resolved.AreIdentical)
 
 	if resolved.AreIdentical {
-		mw.logPanel.AddLog("Оба RTSP-URI идентичны")
+		mw.logger.Warn("Оба RTSP-URI идентичны")
 	}
 
 	for _, warn := range result.Warnings {
-		mw.logPanel.AddLog(warn)
+		mw.logger.Warn(warn)
 	}
+
+	go func() {
+		for dnsResult := range mw.connectionService.ResolveHost(mw.ctx, config.IP) {
+			for _, warn := range dnsResult.Warnings {
+				mw.logger.Warn(warn)
+			}
+		}
+	}()
+}
+
+func (mw *MainWindow) handleAutoDetect(config *model.ConnectionConfig) {
+	if !service.IsValidHost(config.IP) {
+		mw.logger.Warn("Автоподбор: укажите корректный адрес камеры")
+		return
+	}
+
+	mw.logger.Info("Автоподбор: проверка путей известных производителей")
+	mw.connectionForm.SetAutoDetecting(true)
+
+	go func() {
+		matches := mw.presetService.AutoDetect(mw.ctx, config)
+
+		fyne.Do(func() {
+			mw.connectionForm.SetAutoDetecting(false)
+
+			if len(matches) == 0 {
+				mw.logger.Warn("Автоподбор: ни один путь не ответил 200 OK")
+				return
+			}
+
+			for _, match := range matches {
+				sub := "нет"
+				if match.SubOK {
+					sub = "да"
+				}
+				mw.logger.Info("Автоподбор: найден производитель", "vendor", match.Preset.Vendor, "sub_stream", sub)
+			}
+
+			mw.connectionForm.SelectPreset(matches[0].Preset.Vendor)
+		})
+	}()
 }
 
 func (mw *MainWindow) handleConnect(config *model.ConnectionConfig) {
-	mw.logPanel.AddLog("Подключение к камере")
+	mw.logger.Info("Подключение к камере")
 
 	_, result := mw.connectionService.ValidateConfig(config)
 	if !result.Valid {
-		mw.logPanel.AddLog("Ошибка валидации")
-		dialog.ShowError(&validationError{result.GetErrorMessage()}, mw.window)
+		for _, err := range result.Errors {
+			mw.logger.Warn("Ошибка валидации", "field", err.Field, "message", err.Message)
+		}
+		mw.connectionForm.ShowValidation(result)
 		return
 	}
 
 	highChan, lowChan, err := mw.connectionService.Connect(mw.ctx, config)
 	if err != nil {
-		mw.logPanel.AddLog("Ошибка подключения: " + err.Error())
+		mw.logger.Error("Ошибка подключения", err)
 		dialog.ShowError(err, mw.window)
 		return
 	}
 
+	width, height := mw.highPreview.PixelSize()
+	mw.connectionService.SetPreviewSize("High", width, height)
+	width, height = mw.lowPreview.PixelSize()
+	mw.connectionService.SetPreviewSize("Low", width, height)
+	mw.connectionService.SetMuted("High", mw.highPreview.IsMuted())
+	mw.connectionService.SetMuted("Low", mw.lowPreview.IsMuted())
+
 	mw.highPreview.StartStreaming(mw.ctx, highChan)
 	mw.lowPreview.StartStreaming(mw.ctx, lowChan)
 
+	mw.highPreview.SetTalkAvailable(config.Backchannel)
+
 	mw.connectionForm.SetConnected(true)
-	mw.logPanel.AddLog("Подключение установлено")
+	mw.logger.Info("Подключение установлено")
 
 	mw.configService.SaveConfig(config)
 }
 
 func (mw *MainWindow) handleDisconnect() {
-	mw.logPanel.AddLog("Отключение")
+	mw.logger.Info("Отключение")
 
 	mw.highPreview.StopStreaming()
 	mw.lowPreview.StopStreaming()
 	mw.connectionService.Disconnect()
+	mw.highPreview.UpdateAudioLevel(nil)
+	mw.lowPreview.UpdateAudioLevel(nil)
+	mw.highPreview.SetTalkAvailable(false)
+	mw.lowPreview.ShowMotion(nil)
 
 	mw.connectionForm.SetConnected(false)
-	mw.logPanel.AddLog("Отключено")
+	mw.logger.Info("Отключено")
+}
+
+func (mw *MainWindow) handleExportDiagnostics() {
+	input := service.DiagnosticsInput{
+		Snapshots: map[string]image.Image{
+			"High": mw.highPreview.Snapshot(),
+			"Low":  mw.lowPreview.Snapshot(),
+		},
+	}
+
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, mw.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		path := writer.URI().Path()
+		writer.Close()
+
+		mw.logger.Info("Сбор диагностики")
+		go func() {
+			err := mw.diagnostics.Export(mw.ctx, path, input)
+			fyne.Do(func() {
+				if err != nil {
+					mw.logger.Error("Ошибка экспорта диагностики", err)
+					dialog.ShowError(err, mw.window)
+					return
+				}
+				mw.logger.Info("Диагностика сохранена", "path", path)
+			})
+		}()
+	}, mw.window)
+	save.SetFileName("ip-camera-viewer-diagnostics-" + time.Now().Format("20060102-150405") + ".zip")
+	save.Show()
+}
+
+func (mw *MainWindow) handleTalk(streamName string, talking bool) {
+	if !talking {
+		mw.connectionService.StopTalk(streamName)
+		return
+	}
+
+	if err := mw.connectionService.StartTalk(mw.ctx, streamName); err != nil {
+		mw.logger.Error("Ошибка передачи звука", err, "stream", streamName)
+	}
 }
 
 func (mw *MainWindow) startStatusMonitoring() {
@@ -203,19 +466,131 @@ func (mw *MainWindow) startStatusMonitoring() {
 	}()
 }
 
+func (mw *MainWindow) startAudioLevelMonitoring() {
+	go func() {
+		levelChan := mw.connectionService.GetAudioLevelChannel()
+		for {
+			select {
+			case <-mw.ctx.Done():
+				return
+			case level := <-levelChan:
+				switch level.StreamName {
+				case "High":
+					mw.highPreview.UpdateAudioLevel(level)
+				case "Low":
+					mw.lowPreview.UpdateAudioLevel(level)
+				}
+			}
+		}
+	}()
+}
+
+// startPlaybackMonitoring дважды в секунду переносит положение архива из
+// статистики потоков на шкалы под превью.
+func (mw *MainWindow) startPlaybackMonitoring() {
+	go func() {
+		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-mw.ctx.Done():
+				return
+			case <-ticker.C:
+				var high, low *model.PlaybackInfo
+				if info, ok := mw.connectionService.StreamInfo("High"); ok {
+					high = info.Playback
+				}
+				if info, ok := mw.connectionService.StreamInfo("Low"); ok {
+					low = info.Playback
+				}
+
+				fyne.Do(func() {
+					mw.highPreview.UpdatePlayback(high)
+					mw.lowPreview.UpdatePlayback(low)
+				})
+			}
+		}
+	}()
+}
+
 func (mw *MainWindow) handleStatusUpdate(update *model.StreamStatusUpdate) {
-	msg := update.StreamName + ": " + update.Status.String()
-	if update.Error != nil {
-		msg += " - " + update.Error.Error()
+	if update.Motion != nil {
+		fyne.Do(func() {
+			mw.eventList.Add(update.Motion)
+			if update.StreamName == "Low" {
+				mw.lowPreview.ShowMotion(update.Motion)
+			}
+		})
+		return
 	}
-	mw.logPanel.AddLog(msg)
 
-	switch update.StreamName {
-	case "High":
-		mw.highPreview.UpdateStatus(update.Status, update.Info)
-	case "Low":
-		mw.lowPreview.UpdateStatus(update.Status, update.Info)
+	// подключение, ошибки, переподключение и зависание потока уже записал
+	// StreamManager, здесь только отладочная запись о смене статуса
+	if update.Info == nil || update.Info.Warnings == nil {
+		args := []any{"stream", update.StreamName, "status", update.Status.String()}
+		if update.Error != nil {
+			args = append(args, "error", update.Error)
+		}
+		mw.logger.Debug("Статус потока", args...)
 	}
+
+	var certErr *model.UntrustedCertificateError
+	if errors.As(update.Error, &certErr) {
+		fyne.Do(func() {
+			mw.promptTrustCertificate(certErr)
+		})
+	}
+
+	fyne.Do(func() {
+		switch update.StreamName {
+		case "High":
+			mw.highPreview.UpdateStatus(update.Status, update.Info)
+		case "Low":
+			mw.lowPreview.UpdateStatus(update.Status, update.Info)
+		}
+	})
+}
+
+func (mw *MainWindow) promptTrustCertificate(certErr *model.UntrustedCertificateError) {
+	if mw.certPromptShown {
+		return
+	}
+	mw.certPromptShown = true
+
+	info := certErr.Info
+	title := "Недоверенный сертификат"
+	question := "Доверять этому сертификату и сохранить его отпечаток?"
+	if certErr.IsPinMismatch() {
+		title = "Сертификат камеры изменился"
+		question = "Отпечаток не совпадает с сохранённым:\n" + certErr.Pinned +
+			"\n\nЗаменить сохранённый отпечаток? Делайте это, только если сертификат был заменён намеренно."
+	}
+
+	details := fmt.Sprintf("Субъект: %s\nИздатель: %s\nИмена: %s\nДействителен: %s — %s\nSHA-256: %s\nПричина: %s\n\n%s",
+		info.Subject,
+		info.Issuer,
+		strings.Join(info.DNSNames, ", "),
+		info.NotBefore.Format("2006-01-02"),
+		info.NotAfter.Format("2006-01-02"),
+		info.Fingerprint,
+		certErr.Reason,
+		question,
+	)
+
+	dialog.ShowConfirm(title, details, func(trust bool) {
+		mw.certPromptShown = false
+		if !trust {
+			mw.logger.Warn("Сертификат отклонён", "fingerprint", info.Fingerprint)
+			return
+		}
+
+		mw.connectionForm.SetCertFingerprint(info.Fingerprint)
+		mw.logger.Info("Сертификат закреплён", "fingerprint", info.Fingerprint)
+
+		mw.handleDisconnect()
+		mw.handleConnect(mw.connectionForm.GetConfig())
+	}, mw.window)
 }
 
 func (mw *MainWindow) loadSavedConfig() {
@@ -226,26 +601,43 @@ func (mw *MainWindow) loadSavedConfig() {
 	}
 
 	if saved != nil {
+		motion := model.DefaultMotionConfig()
+		if saved.Motion != nil {
+			motion = *saved.Motion
+		}
+		recording := model.DefaultRecordingConfig()
+		if saved.Recording != nil {
+			recording = *saved.Recording
+		}
+
 		config := &model.ConnectionConfig{
-			IP:       saved.IP,
-			Port:     saved.Port,
-			Login:    saved.Login,
-			RTSPURI1: saved.RTSPURI1,
-			RTSPURI2: saved.RTSPURI2,
+			IP:          saved.IP,
+			Port:        saved.Port,
+			Login:       saved.Login,
+			Password:    saved.Password,
+			RTSPURI1:    saved.RTSPURI1,
+			RTSPURI2:    saved.RTSPURI2,
+			Audio:       saved.Audio,
+			Backchannel: saved.Backchannel,
+
+			CAFile:          saved.CAFile,
+			CertFingerprint: saved.CertFingerprint,
+
+			RememberPassword: saved.RememberPassword,
+
+			Motion:    motion,
+			Recording: recording,
+			Health:    saved.Health,
+			Watchdog:  saved.Watchdog,
+
+			Variables: saved.Variables,
 		}
 		mw.connectionForm.LoadConfig(config)
-		mw.logPanel.AddLog("Конфигурация загружена")
+		mw.lowPreview.SetMotionZones(motion.Zones)
+		mw.logger.Info("Конфигурация загружена")
 	}
 }
 
 func (mw *MainWindow) ShowAndRun() {
 	mw.window.ShowAndRun()
 }
-
-type validationError struct {
-	message string
-}
-
-func (e *validationError) Error() string {
-	return e.message
-}
@@ -0,0 +1,256 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"ip-camera-viewer/internal/domain/model"
+	"math"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/widget"
+)
+
+const (
+	maxZoom = 8.0
+	// zoomStep — увеличение за щелчок колеса мыши или нажатие клавиши.
+	zoomStep = 1.25
+	// scrollNotch — смещение ScrollEvent за один щелчок колеса в драйвере GLFW.
+	scrollNotch = 25
+	// panStep — сдвиг стрелками в долях видимой области.
+	panStep = 0.1
+	// minimapWidth — ширина миникарты, высота следует пропорциям кадра.
+	minimapWidth = 120
+)
+
+var (
+	minimapFrameColor    = color.NRGBA{R: 0xff, G: 0xff, B: 0xff, A: 0x80}
+	minimapViewportColor = color.NRGBA{R: 0xff, G: 0xd0, B: 0x30, A: 0xff}
+)
+
+// viewport — видимая часть кадра при цифровом увеличении: масштаб и центр
+// в долях кадра. Не зависит от разрешения, поэтому переживает смену
+// размера декодированных кадров.
+type viewport struct {
+	zoom   float64
+	cx, cy float64
+}
+
+func newViewport() viewport {
+	return viewport{zoom: 1, cx: 0.5, cy: 0.5}
+}
+
+func (v *viewport) zoomed() bool {
+	return v.zoom > 1
+}
+
+// rect возвращает видимую часть кадра в долях его размеров.
+func (v *viewport) rect() model.Zone {
+	size := 1 / v.zoom
+	return model.Zone{X: v.cx - size/2, Y: v.cy - size/2, W: size, H: size}
+}
+
+// zoomAt меняет масштаб в factor раз так, что точка кадра (x, y) остаётся
+// на том же месте экрана.
+func (v *viewport) zoomAt(x, y, factor float64) {
+	before := v.rect()
+	fx := (x - before.X) / before.W
+	fy := (y - before.Y) / before.H
+
+	v.zoom = min(max(v.zoom*factor, 1), maxZoom)
+	size := 1 / v.zoom
+	v.cx = x - fx*size + size/2
+	v.cy = y - fy*size + size/2
+	v.clamp()
+}
+
+// pan сдвигает видимую часть на dx, dy в долях кадра.
+func (v *viewport) pan(dx, dy float64) {
+	v.cx += dx
+	v.cy += dy
+	v.clamp()
+}
+
+func (v *viewport) clamp() {
+	half := 0.5 / v.zoom
+	v.cx = min(max(v.cx, half), 1-half)
+	v.cy = min(max(v.cy, half), 1-half)
+}
+
+// crop вырезает видимую часть кадра и возвращает её долю в кадре: после
+// округления до пикселей она чуть шире rect. Для кадров с SubImage
+// копирования нет.
+func (v *viewport) crop(img image.Image) (image.Image, model.Zone) {
+	full := model.Zone{W: 1, H: 1}
+	bounds := img.Bounds()
+	if !v.zoomed() || bounds.Empty() {
+		return img, full
+	}
+
+	visible := v.rect()
+	rect := image.Rect(
+		int(math.Floor(visible.X*float64(bounds.Dx()))),
+		int(math.Floor(visible.Y*float64(bounds.Dy()))),
+		int(math.Ceil((visible.X+visible.W)*float64(bounds.Dx()))),
+		int(math.Ceil((visible.Y+visible.H)*float64(bounds.Dy()))),
+	).Add(bounds.Min).Intersect(bounds)
+	if rect.Empty() {
+		return img, full
+	}
+
+	zone := model.Zone{
+		X: float64(rect.Min.X-bounds.Min.X) / float64(bounds.Dx()),
+		Y: float64(rect.Min.Y-bounds.Min.Y) / float64(bounds.Dy()),
+		W: float64(rect.Dx()) / float64(bounds.Dx()),
+		H: float64(rect.Dy()) / float64(bounds.Dy()),
+	}
+
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(rect), zone
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, rect.Min, draw.Src)
+	return dst, zone
+}
+
+// zoomSurface лежит поверх кадра и принимает ввод для увеличения:
+// колесо мыши, перетаскивание, двойной щелчок и клавиши. Перетаскивание в
+// режиме рисования зон уходит в motionOverlay.
+type zoomSurface struct {
+	widget.BaseWidget
+	preview *VideoPreviewWidget
+}
+
+func newZoomSurface(preview *VideoPreviewWidget) *zoomSurface {
+	s := &zoomSurface{preview: preview}
+	s.ExtendBaseWidget(s)
+	return s
+}
+
+func (s *zoomSurface) CreateRenderer() fyne.WidgetRenderer {
+	return widget.NewSimpleRenderer(canvas.NewRectangle(color.Transparent))
+}
+
+func (s *zoomSurface) Scrolled(e *fyne.ScrollEvent) {
+	x, y := s.preview.overlay.toFrame(e.Position)
+	s.preview.zoomAt(x, y, math.Pow(zoomStep, float64(e.Scrolled.DY)/scrollNotch))
+}
+
+func (s *zoomSurface) Dragged(e *fyne.DragEvent) {
+	if s.preview.overlay.editing {
+		s.preview.overlay.Dragged(e)
+		return
+	}
+
+	_, size := s.preview.overlay.frame()
+	if size.Width <= 0 || size.Height <= 0 {
+		return
+	}
+	visible := s.preview.visible
+	s.preview.pan(-float64(e.Dragged.DX/size.Width)*visible.W, -float64(e.Dragged.DY/size.Height)*visible.H)
+}
+
+func (s *zoomSurface) DragEnd() {
+	s.preview.overlay.DragEnd()
+}
+
+func (s *zoomSurface) Tapped(*fyne.PointEvent) {
+	if c := fyne.CurrentApp().Driver().CanvasForObject(s); c != nil {
+		c.Focus(s)
+	}
+}
+
+func (s *zoomSurface) DoubleTapped(*fyne.PointEvent) {
+	s.preview.ToggleFullscreen()
+}
+
+func (s *zoomSurface) FocusGained() {}
+
+func (s *zoomSurface) FocusLost() {}
+
+func (s *zoomSurface) TypedRune(rune) {}
+
+func (s *zoomSurface) TypedKey(e *fyne.KeyEvent) {
+	s.preview.handleKey(e.Name)
+}
+
+// minimap показывает в углу превью весь кадр и рамку видимой при
+// увеличении части.
+type minimap struct {
+	widget.BaseWidget
+
+	aspect  func() float32
+	visible model.Zone
+	zoom    float64
+}
+
+func newMinimap(aspect func() float32) *minimap {
+	m := &minimap{aspect: aspect}
+	m.ExtendBaseWidget(m)
+	return m
+}
+
+func (m *minimap) update(visible model.Zone, zoom float64) {
+	m.visible = visible
+	m.zoom = zoom
+	m.Refresh()
+}
+
+func (m *minimap) CreateRenderer() fyne.WidgetRenderer {
+	frame := canvas.NewRectangle(color.NRGBA{A: 0xb0})
+	frame.StrokeColor = minimapFrameColor
+	frame.StrokeWidth = 1
+
+	viewport := canvas.NewRectangle(color.Transparent)
+	viewport.StrokeColor = minimapViewportColor
+	viewport.StrokeWidth = 2
+
+	label := canvas.NewText("", minimapViewportColor)
+	label.TextSize = 11
+
+	return &minimapRenderer{minimap: m, frame: frame, viewport: viewport, label: label}
+}
+
+type minimapRenderer struct {
+	minimap  *minimap
+	frame    *canvas.Rectangle
+	viewport *canvas.Rectangle
+	label    *canvas.Text
+}
+
+func (r *minimapRenderer) Layout(size fyne.Size) {
+	r.frame.Move(fyne.NewPos(0, 0))
+	r.frame.Resize(size)
+
+	visible := r.minimap.visible
+	r.viewport.Move(fyne.NewPos(float32(visible.X)*size.Width, float32(visible.Y)*size.Height))
+	r.viewport.Resize(fyne.NewSize(float32(visible.W)*size.Width, float32(visible.H)*size.Height))
+
+	labelSize := r.label.MinSize()
+	r.label.Move(fyne.NewPos(size.Width-labelSize.Width-2, size.Height-labelSize.Height))
+}
+
+func (r *minimapRenderer) MinSize() fyne.Size {
+	aspect := r.minimap.aspect()
+	if aspect <= 0 {
+		aspect = 16.0 / 9
+	}
+	return fyne.NewSize(minimapWidth, minimapWidth/aspect)
+}
+
+func (r *minimapRenderer) Refresh() {
+	r.label.Text = fmt.Sprintf("×%.1f", r.minimap.zoom)
+	r.Layout(r.minimap.Size())
+	canvas.Refresh(r.minimap)
+}
+
+func (r *minimapRenderer) Objects() []fyne.CanvasObject {
+	return []fyne.CanvasObject{r.frame, r.viewport, r.label}
+}
+
+func (r *minimapRenderer) Destroy() {}